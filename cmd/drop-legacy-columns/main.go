@@ -0,0 +1,61 @@
+// Command drop-legacy-columns drops the incident report columns that startup
+// backfills have copied elsewhere. A column is only dropped once its backfill
+// has completed and every report holding a value in it was carried over;
+// columns that fail the check are kept and listed.
+//
+// Usage:
+//
+//	drop-legacy-columns [-dry-run]
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/techagentng/citizenx/config"
+	"github.com/techagentng/citizenx/db"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "verify the columns without dropping them")
+	flag.Parse()
+
+	conf, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	gormDB := db.GetDB(conf)
+
+	columns, err := db.GetLegacyColumns(gormDB)
+	if err != nil {
+		log.Fatalf("error verifying legacy columns: %v", err)
+	}
+	if len(columns) == 0 {
+		log.Printf("no legacy columns left to drop")
+		return
+	}
+
+	var verified []db.LegacyColumn
+	for _, column := range columns {
+		log.Printf("%s (%s backfill): %d unmatched reports", column.Name, column.Backfill, column.Unmatched)
+		if column.Unmatched == 0 {
+			verified = append(verified, column)
+		}
+	}
+	if skipped := len(columns) - len(verified); skipped > 0 {
+		log.Printf("%d columns hold values that were not copied and are kept", skipped)
+	}
+	switch {
+	case len(verified) == 0:
+		log.Printf("no verified columns to drop")
+		return
+	case *dryRun:
+		log.Printf("%d columns verified; run without -dry-run to drop them", len(verified))
+		return
+	}
+
+	if err := db.DropLegacyColumns(gormDB, verified); err != nil {
+		log.Fatalf("error dropping legacy columns: %v", err)
+	}
+	log.Printf("dropped %d legacy columns", len(verified))
+}
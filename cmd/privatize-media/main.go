@@ -0,0 +1,72 @@
+// Command privatize-media withdraws public access to report media stored
+// before media became private. Objects uploaded since then are already
+// private and are left as they are.
+//
+// Usage:
+//
+//	privatize-media [-dry-run]
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"log"
+
+	"github.com/techagentng/citizenx/config"
+	"github.com/techagentng/citizenx/db"
+	"github.com/techagentng/citizenx/storage"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "list the objects without changing them")
+	flag.Parse()
+
+	conf, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	store, err := storage.New(conf)
+	if err != nil {
+		log.Fatalf("error initializing storage: %v", err)
+	}
+	mediaRepo := db.NewMediaRepo(db.GetDB(conf))
+
+	urls, err := mediaRepo.GetPrivateMediaURLs()
+	if err != nil {
+		log.Fatalf("error loading media URLs: %v", err)
+	}
+
+	ctx := context.Background()
+	var updated, missing, failed int
+	for _, url := range urls {
+		key, ok := store.KeyForURL(url)
+		if !ok {
+			continue
+		}
+		if *dryRun {
+			log.Printf("would make %s private", key)
+			updated++
+			continue
+		}
+		switch err := store.MakePrivate(ctx, key); {
+		case errors.Is(err, storage.ErrNotFound):
+			missing++
+		case err != nil:
+			log.Printf("%s: %v", key, err)
+			failed++
+		default:
+			updated++
+		}
+	}
+
+	switch {
+	case *dryRun:
+		log.Printf("%d objects would be made private; run without -dry-run to update them", updated)
+	default:
+		log.Printf("made %d objects private, %d no longer exist", updated, missing)
+	}
+	if failed > 0 {
+		log.Fatalf("%d objects could not be updated; run again to retry", failed)
+	}
+}
@@ -0,0 +1,54 @@
+// Command reconcile-rewards checks the cached points balances against the
+// points ledger and rebuilds those that drifted.
+//
+// Usage:
+//
+//	reconcile-rewards [-dry-run]
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/techagentng/citizenx/config"
+	"github.com/techagentng/citizenx/db"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "report drifted balances without rewriting them")
+	flag.Parse()
+
+	conf, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	ledgerRepo := db.NewLedgerRepo(db.GetDB(conf))
+
+	unbalanced, err := ledgerRepo.GetUnbalancedTransactions()
+	if err != nil {
+		log.Fatalf("error checking ledger: %v", err)
+	}
+	for _, id := range unbalanced {
+		log.Printf("transaction %d: entries do not sum to zero", id)
+	}
+
+	drifts, err := ledgerRepo.RebuildBalances(!*dryRun)
+	if err != nil {
+		log.Fatalf("error reconciling balances: %v", err)
+	}
+	for _, drift := range drifts {
+		log.Printf("user %d: cached %d, ledger %d", drift.UserID, drift.Cached, drift.Ledger)
+	}
+
+	switch {
+	case len(drifts) == 0:
+		log.Printf("all balances match the ledger")
+	case *dryRun:
+		log.Printf("%d balances drifted; run without -dry-run to rebuild them", len(drifts))
+	default:
+		log.Printf("rebuilt %d balances from the ledger", len(drifts))
+	}
+	if len(unbalanced) > 0 {
+		log.Fatalf("%d unbalanced transactions need attention", len(unbalanced))
+	}
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"log"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/kelseyhightower/envconfig"
@@ -38,6 +39,42 @@ type Config struct {
 	AWS_SECRET_ACCESS_KEY        string `envconfig:"aws_secret_access_key"`
 	FRONTEND_URL        string `envconfig:"frontend_url"`
 	GOOGLE_CLOUD_PROJECT string `envconfig:"google_cloud_project"`
+	UploadTempDir        string `envconfig:"upload_temp_dir"`
+	MediaWorkers         int    `envconfig:"media_workers"`
+	StorageBackend       string `envconfig:"storage_backend"`
+	LocalStorageDir      string `envconfig:"local_storage_dir"`
+	LocalStorageURL      string `envconfig:"local_storage_url"`
+	WhisperBinary        string `envconfig:"whisper_binary"`
+	WhisperModel         string `envconfig:"whisper_model"`
+	ModerationKeywords   []string `envconfig:"moderation_keywords"`
+	SLACheckInterval     time.Duration `envconfig:"sla_check_interval"`
+	// ReopenThreshold is how many "still broken" votes reopen a resolved report
+	ReopenThreshold      int     `envconfig:"reopen_threshold"`
+	// ResolutionVoteRadius is how close, in metres, a citizen other than the
+	// reporter must be to a report to vote on its resolution
+	ResolutionVoteRadius float64 `envconfig:"resolution_vote_radius"`
+	// PayoutProvider pays out redemptions and must be set; "fake" pays
+	// instantly and is meant for local use
+	PayoutProvider string `envconfig:"payout_provider"`
+	// NairaPerPoint is what a redeemed point is worth
+	NairaPerPoint float64 `envconfig:"naira_per_point"`
+	// Minimum points for each kind of redemption
+	MinAirtimeRedemption      int `envconfig:"min_airtime_redemption"`
+	MinDataRedemption         int `envconfig:"min_data_redemption"`
+	MinBankTransferRedemption int `envconfig:"min_bank_transfer_redemption"`
+	// FeedCacheTTL is how long a ranked feed is reused before being ranked
+	// again
+	FeedCacheTTL time.Duration `envconfig:"feed_cache_ttl"`
+	// TrendingWindow is how far back the trending feed counts activity
+	TrendingWindow time.Duration `envconfig:"trending_window"`
+	// NearMeRadius is how far, in metres, the near me feed looks
+	NearMeRadius float64 `envconfig:"near_me_radius"`
+	// ViewDedupWindow is how long repeat views or shares by one viewer count
+	// once
+	ViewDedupWindow time.Duration `envconfig:"view_dedup_window"`
+	// ViewFlushInterval is how often buffered views and shares are written to
+	// the database
+	ViewFlushInterval time.Duration `envconfig:"view_flush_interval"`
 }
 
 func Load() (*Config, error) {
@@ -0,0 +1,247 @@
+package db
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+	"github.com/techagentng/citizenx/models"
+	"gorm.io/gorm"
+)
+
+type AgencyRepository interface {
+	ListAgencies() ([]models.Agency, error)
+	ListActiveAgencies() ([]models.Agency, error)
+	GetAgencyByID(id uint) (*models.Agency, error)
+	CreateAgency(agency *models.Agency) error
+	UpdateAgency(agency *models.Agency) error
+	DeleteAgency(id uint) error
+	AddAgencyMember(agencyID, userID uint, roleID uuid.UUID) error
+	CreateAssignments(assignments []models.ReportAssignment) error
+	MarkAssignmentDelivered(id uint, deliveryErr string) error
+	GetAssignment(agencyID, id uint) (*models.ReportAssignment, error)
+	GetAgencyAssignments(agencyID uint, status string, page int) ([]models.ReportAssignment, error)
+	GetReportAssignments(reportID uuid.UUID) ([]models.ReportAssignment, error)
+	UpdateAssignment(assignment *models.ReportAssignment) error
+	GetOverdueAssignments(now time.Time) ([]models.ReportAssignment, error)
+	MarkAssignmentEscalated(id uint, stage string, at time.Time) (bool, error)
+	GetSLAMetrics(groupBy, state, lga string, from, to *time.Time) ([]models.SLAMetric, error)
+}
+
+type agencyRepo struct {
+	DB *gorm.DB
+}
+
+func NewAgencyRepo(db *GormDB) AgencyRepository {
+	return &agencyRepo{db.DB}
+}
+
+func (a *agencyRepo) ListAgencies() ([]models.Agency, error) {
+	var agencies []models.Agency
+	if err := a.DB.Order("name ASC").Find(&agencies).Error; err != nil {
+		return nil, err
+	}
+	return agencies, nil
+}
+
+func (a *agencyRepo) ListActiveAgencies() ([]models.Agency, error) {
+	var agencies []models.Agency
+	if err := a.DB.Where("active = ?", true).Find(&agencies).Error; err != nil {
+		return nil, err
+	}
+	return agencies, nil
+}
+
+// GetAgencyByID returns the agency, or nil when there is none.
+func (a *agencyRepo) GetAgencyByID(id uint) (*models.Agency, error) {
+	var agency models.Agency
+	if err := a.DB.First(&agency, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &agency, nil
+}
+
+func (a *agencyRepo) CreateAgency(agency *models.Agency) error {
+	return a.DB.Create(agency).Error
+}
+
+func (a *agencyRepo) UpdateAgency(agency *models.Agency) error {
+	return a.DB.Omit("created_at").Save(agency).Error
+}
+
+// DeleteAgency removes the agency and detaches its staff. Assignments are kept
+// for the record.
+func (a *agencyRepo) DeleteAgency(id uint) error {
+	return a.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).Where("agency_id = ?", id).Update("agency_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Agency{}, id).Error
+	})
+}
+
+// AddAgencyMember makes the user a member of the agency with the given role.
+func (a *agencyRepo) AddAgencyMember(agencyID, userID uint, roleID uuid.UUID) error {
+	result := a.DB.Model(&models.User{}).
+		Where("id = ?", userID).
+		Updates(map[string]interface{}{"agency_id": agencyID, "role_id": roleID})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (a *agencyRepo) CreateAssignments(assignments []models.ReportAssignment) error {
+	if len(assignments) == 0 {
+		return nil
+	}
+	return a.DB.Create(&assignments).Error
+}
+
+func (a *agencyRepo) MarkAssignmentDelivered(id uint, deliveryErr string) error {
+	updates := map[string]interface{}{"delivery_error": deliveryErr}
+	if deliveryErr == "" {
+		updates["delivered_at"] = time.Now()
+	}
+	return a.DB.Model(&models.ReportAssignment{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// GetAssignment returns an assignment of the agency with its report, or nil.
+func (a *agencyRepo) GetAssignment(agencyID, id uint) (*models.ReportAssignment, error) {
+	var assignment models.ReportAssignment
+	err := a.DB.Preload("IncidentReport").
+		Where("agency_id = ?", agencyID).
+		First(&assignment, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &assignment, nil
+}
+
+// GetAgencyAssignments returns a page of the agency's assignments, newest
+// first, optionally only those with the given status.
+func (a *agencyRepo) GetAgencyAssignments(agencyID uint, status string, page int) ([]models.ReportAssignment, error) {
+	var assignments []models.ReportAssignment
+	query := a.DB.Preload("IncidentReport").Where("agency_id = ?", agencyID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("created_at DESC").
+		Limit(DefaultPageSize).
+		Offset((page - 1) * DefaultPageSize).
+		Find(&assignments).Error
+	if err != nil {
+		return nil, err
+	}
+	return assignments, nil
+}
+
+// GetReportAssignments returns the agencies a report was routed to.
+func (a *agencyRepo) GetReportAssignments(reportID uuid.UUID) ([]models.ReportAssignment, error) {
+	var assignments []models.ReportAssignment
+	err := a.DB.Preload("Agency").
+		Where("incident_report_id = ?", reportID).
+		Order("created_at ASC").
+		Find(&assignments).Error
+	if err != nil {
+		return nil, err
+	}
+	return assignments, nil
+}
+
+// UpdateAssignment saves an agency's progress on an assignment and carries
+// the status over to the report's resolution status, so an agency resolving
+// the report opens it to resolution votes.
+func (a *agencyRepo) UpdateAssignment(assignment *models.ReportAssignment) error {
+	return a.DB.Transaction(func(tx *gorm.DB) error {
+		return updateAssignment(tx, assignment)
+	})
+}
+
+// updateAssignment is UpdateAssignment within the caller's transaction.
+func updateAssignment(tx *gorm.DB, assignment *models.ReportAssignment) error {
+	err := tx.Model(assignment).
+		Select("status", "note", "updated_by", "updated_at", "acknowledged_at", "resolved_at").
+		Updates(assignment).Error
+	if err != nil {
+		return err
+	}
+	return tx.Model(&models.IncidentReport{}).
+		Where("id = ?", assignment.IncidentReportID).
+		Update("resolution_status", assignment.Status).Error
+}
+
+// GetOverdueAssignments returns assignments past an SLA target that have not
+// been escalated for it yet.
+func (a *agencyRepo) GetOverdueAssignments(now time.Time) ([]models.ReportAssignment, error) {
+	var assignments []models.ReportAssignment
+	err := a.DB.Preload("Agency").Preload("IncidentReport").
+		Where("(acknowledge_due_at < ? AND acknowledged_at IS NULL AND acknowledge_escalated_at IS NULL) OR "+
+			"(resolve_due_at < ? AND resolved_at IS NULL AND resolve_escalated_at IS NULL)", now, now).
+		Find(&assignments).Error
+	if err != nil {
+		return nil, err
+	}
+	return assignments, nil
+}
+
+// MarkAssignmentEscalated records the escalation of a missed SLA stage. It
+// returns false when another instance already escalated it.
+func (a *agencyRepo) MarkAssignmentEscalated(id uint, stage string, at time.Time) (bool, error) {
+	column := "acknowledge_escalated_at"
+	if stage == models.SLAStageResolve {
+		column = "resolve_escalated_at"
+	}
+	result := a.DB.Model(&models.ReportAssignment{}).
+		Where("id = ? AND "+column+" IS NULL", id).
+		Update(column, at)
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetSLAMetrics summarises assignment response times grouped by agency or by
+// the state of the report. Assignments still open past their due time count
+// as breaches. Empty filters are ignored.
+func (a *agencyRepo) GetSLAMetrics(groupBy, state, lga string, from, to *time.Time) ([]models.SLAMetric, error) {
+	group := "agencies.name"
+	if groupBy == "state" {
+		group = "incident_reports.state_name"
+	}
+
+	var metrics []models.SLAMetric
+	query := a.DB.Table("report_assignments").
+		Select(group + ` AS "group",
+			COUNT(*) AS assigned,
+			COUNT(report_assignments.acknowledged_at) AS acknowledged,
+			COUNT(report_assignments.resolved_at) AS resolved,
+			COALESCE(AVG(EXTRACT(EPOCH FROM report_assignments.acknowledged_at) - report_assignments.created_at) / 3600, 0) AS avg_hours_to_acknowledge,
+			COALESCE(AVG(EXTRACT(EPOCH FROM report_assignments.resolved_at) - report_assignments.created_at) / 3600, 0) AS avg_hours_to_resolve,
+			COUNT(*) FILTER (WHERE report_assignments.acknowledge_due_at < COALESCE(report_assignments.acknowledged_at, NOW())) AS acknowledge_breaches,
+			COUNT(*) FILTER (WHERE report_assignments.resolve_due_at < COALESCE(report_assignments.resolved_at, NOW())) AS resolve_breaches`).
+		Joins("JOIN agencies ON agencies.id = report_assignments.agency_id").
+		Joins("JOIN incident_reports ON incident_reports.id = report_assignments.incident_report_id")
+	if state != "" {
+		query = query.Where("incident_reports.state_name = ?", state)
+	}
+	if lga != "" {
+		query = query.Where("incident_reports.lga_name = ?", lga)
+	}
+	if from != nil {
+		query = query.Where("report_assignments.created_at >= ?", from.Unix())
+	}
+	if to != nil {
+		query = query.Where("report_assignments.created_at < ?", to.Unix())
+	}
+	if err := query.Group(group).Order("assigned DESC").Scan(&metrics).Error; err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/techagentng/citizenx/models"
+	"gorm.io/gorm"
+)
+
+// legacyAttributeColumns maps the per-domain columns incident reports carried
+// before category form schemas to the attribute names they move to.
+var legacyAttributeColumns = map[string]string{
+	"product_name":           "product_name",
+	"hospital_name":          "hospital_name",
+	"hospital_address":       "hospital_address",
+	"department":             "department",
+	"department_head_name":   "department_head_name",
+	"accident_cause":         "accident_cause",
+	"road_name":              "road_name",
+	"school_name":            "school_name",
+	"vice_principal":         "vice_principal",
+	"outage_length":          "outage_length",
+	"airport_name":           "airport_name",
+	"airline_name":           "airline_name",
+	"terminal":               "terminal",
+	"queue_time":             "queue_time",
+	"country":                "country",
+	"state_embassy_location": "state_embassy_location",
+	"ambassedors_name":       "ambassador_name",
+}
+
+// backfillReportAttributes copies the legacy per-domain columns that hold a
+// value into the attributes of each report and records that it ran, so it only
+// does work once. The columns are left in place until the copy is verified and
+// they are dropped with cmd/drop-legacy-columns.
+func backfillReportAttributes(db *gorm.DB) error {
+	done, err := backfillDone(db, backfillReportAttributesName)
+	if err != nil || done {
+		return err
+	}
+	if !db.Migrator().HasColumn(&models.IncidentReport{}, "hospital_name") {
+		return markBackfillDone(db, backfillReportAttributesName)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		migrator := tx.Migrator()
+		for column, attribute := range legacyAttributeColumns {
+			if !migrator.HasColumn(&models.IncidentReport{}, column) {
+				continue
+			}
+			err := tx.Exec(fmt.Sprintf(
+				`UPDATE incident_reports SET attributes = COALESCE(attributes, '{}'::jsonb) || jsonb_build_object(?::text, %[1]s)
+				WHERE COALESCE(%[1]s, '') <> ''`, column), attribute).Error
+			if err != nil {
+				return fmt.Errorf("failed to move %s: %v", column, err)
+			}
+		}
+
+		if migrator.HasColumn(&models.IncidentReport{}, "no_water") {
+			err := tx.Exec(`UPDATE incident_reports SET attributes = COALESCE(attributes, '{}'::jsonb) || '{"no_water": true}'::jsonb
+				WHERE no_water`).Error
+			if err != nil {
+				return fmt.Errorf("failed to move no_water: %v", err)
+			}
+		}
+
+		if err := markBackfillDone(tx, backfillReportAttributesName); err != nil {
+			return fmt.Errorf("failed to record attributes backfill: %v", err)
+		}
+		return nil
+	})
+}
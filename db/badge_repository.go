@@ -0,0 +1,96 @@
+package db
+
+import (
+	"github.com/techagentng/citizenx/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BadgeRepository interface {
+	GetBadges() ([]models.Badge, error)
+	GetActiveBadges() ([]models.Badge, error)
+	GetUserBadges(userID uint) ([]models.UserBadge, error)
+	AwardBadge(userBadge *models.UserBadge) (bool, error)
+	GetBadgeProgress(userID uint) (*models.BadgeProgress, error)
+}
+
+type badgeRepo struct {
+	DB *gorm.DB
+}
+
+func NewBadgeRepo(db *GormDB) BadgeRepository {
+	return &badgeRepo{db.DB}
+}
+
+func (b *badgeRepo) GetBadges() ([]models.Badge, error) {
+	var badges []models.Badge
+	err := b.DB.Order("id").Find(&badges).Error
+	return badges, err
+}
+
+func (b *badgeRepo) GetActiveBadges() ([]models.Badge, error) {
+	var badges []models.Badge
+	err := b.DB.Where("active = ?", true).Order("id").Find(&badges).Error
+	return badges, err
+}
+
+// GetUserBadges returns the user's badges, most recently earned first.
+func (b *badgeRepo) GetUserBadges(userID uint) ([]models.UserBadge, error) {
+	var userBadges []models.UserBadge
+	err := b.DB.Preload("Badge").
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&userBadges).Error
+	return userBadges, err
+}
+
+// AwardBadge records the badge unless the user already earned it for the
+// period, returning whether it was new.
+func (b *badgeRepo) AwardBadge(userBadge *models.UserBadge) (bool, error) {
+	result := b.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(userBadge)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (b *badgeRepo) GetBadgeProgress(userID uint) (*models.BadgeProgress, error) {
+	var progress models.BadgeProgress
+	err := b.DB.Model(&models.IncidentReport{}).
+		Select(`COUNT(*) AS reports_submitted,
+			COUNT(*) FILTER (WHERE report_status = 'approved') AS reports_approved,
+			COALESCE(SUM(upvote_count), 0) AS upvotes_received`).
+		Where("user_id = ?", userID).
+		Scan(&progress).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Duplicate photos do not count as contributions
+	var media int64
+	err = b.DB.Model(&models.Media{}).
+		Where("user_id = ? AND is_duplicate = ?", userID, false).
+		Count(&media).Error
+	if err != nil {
+		return nil, err
+	}
+	progress.MediaItems = int(media)
+	return &progress, nil
+}
+
+// seedBadges creates the launch badges.
+func seedBadges(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&models.Badge{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	badges := []models.Badge{
+		{Code: "first_report", Name: "First Report", Description: "Submitted your first report", Criterion: models.BadgeCriterionReportsSubmitted, Threshold: 1, Active: true},
+		{Code: "verified_reporter", Name: "Verified Reporter", Description: "Had 10 reports approved", Criterion: models.BadgeCriterionReportsApproved, Threshold: 10, Active: true},
+		{Code: "media_contributor", Name: "Media Contributor", Description: "Added 10 photos, videos or recordings to reports", Criterion: models.BadgeCriterionMediaItems, Threshold: 10, Active: true},
+		{Code: "community_voice", Name: "Community Voice", Description: "Received 50 upvotes on your reports", Criterion: models.BadgeCriterionUpvotesReceived, Threshold: 50, Active: true},
+		{Code: "top_reporter_lga", Name: "Top Reporter", Description: "Top reporter in your LGA this month", Criterion: models.BadgeCriterionTopInLGAMonthly, Threshold: 1, Active: true},
+	}
+	return db.Create(&badges).Error
+}
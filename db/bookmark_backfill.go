@@ -0,0 +1,77 @@
+package db
+
+import (
+	"github.com/google/uuid"
+	"github.com/techagentng/citizenx/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// legacyBookmarkTables held bookmarks before the bookmarks table was the
+// only store: the incident_report_user many2many and the IncidentReportUser
+// model's table. They are left in place but no longer written.
+var legacyBookmarkTables = []string{"incident_report_user", "incident_report_users"}
+
+// backfillBookmarks brings the bookmarks table to one row per user and
+// report, creates the unique index and copies in the bookmarks from the
+// legacy tables, in one transaction that only runs while the index is
+// missing.
+func backfillBookmarks(db *gorm.DB) error {
+	var exists bool
+	if err := db.Raw("SELECT to_regclass('idx_bookmarks_user_report') IS NOT NULL").Scan(&exists).Error; err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`DELETE FROM bookmarks a USING bookmarks b
+			WHERE a.user_id = b.user_id AND a.report_id = b.report_id AND a.id > b.id`).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Exec("CREATE UNIQUE INDEX idx_bookmarks_user_report ON bookmarks (user_id, report_id)").Error; err != nil {
+			return err
+		}
+
+		for _, table := range legacyBookmarkTables {
+			var present bool
+			if err := tx.Raw("SELECT to_regclass(?) IS NOT NULL", table).Scan(&present).Error; err != nil {
+				return err
+			}
+			if !present {
+				continue
+			}
+			var rows []struct {
+				UserID           uint
+				IncidentReportID string
+			}
+			err := tx.Table(table).
+				Select("DISTINCT user_id, incident_report_id::text AS incident_report_id").
+				Scan(&rows).Error
+			if err != nil {
+				return err
+			}
+			bookmarks := make([]models.Bookmark, 0, len(rows))
+			for _, row := range rows {
+				reportID, err := uuid.Parse(row.IncidentReportID)
+				if err != nil {
+					continue
+				}
+				bookmarks = append(bookmarks, models.Bookmark{UserID: row.UserID, ReportID: reportID})
+			}
+			if len(bookmarks) == 0 {
+				continue
+			}
+			err = tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "user_id"}, {Name: "report_id"}},
+				DoNothing: true,
+			}).CreateInBatches(bookmarks, 500).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,184 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/techagentng/citizenx/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type BookmarkRepository interface {
+	ReportExists(reportID uuid.UUID) (bool, error)
+	GetBookmark(userID uint, reportID uuid.UUID) (*models.Bookmark, error)
+	SaveBookmark(bookmark *models.Bookmark) error
+	DeleteBookmark(userID uint, reportID uuid.UUID) (bool, error)
+	GetBookmarkedReports(userID uint, collectionID *uint, page int) ([]models.IncidentReport, error)
+	GetBookmarkedIDs(userID uint, reportIDs []uuid.UUID) (map[uuid.UUID]bool, error)
+	GetCollections(userID uint) ([]models.BookmarkCollection, error)
+	GetCollection(userID, id uint) (*models.BookmarkCollection, error)
+	CollectionNameTaken(userID uint, name string, excludeID uint) (bool, error)
+	SaveCollection(collection *models.BookmarkCollection) error
+	DeleteCollection(collection *models.BookmarkCollection) error
+}
+
+type bookmarkRepo struct {
+	DB *gorm.DB
+}
+
+func NewBookmarkRepo(db *GormDB) BookmarkRepository {
+	return &bookmarkRepo{db.DB}
+}
+
+func (b *bookmarkRepo) ReportExists(reportID uuid.UUID) (bool, error) {
+	var count int64
+	err := b.DB.Model(&models.IncidentReport{}).
+		Where("id = ?", reportID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetBookmark returns nil when the user has not bookmarked the report.
+func (b *bookmarkRepo) GetBookmark(userID uint, reportID uuid.UUID) (*models.Bookmark, error) {
+	var bookmark models.Bookmark
+	err := b.DB.Where("user_id = ? AND report_id = ?", userID, reportID).First(&bookmark).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &bookmark, nil
+}
+
+// SaveBookmark creates a new bookmark, or updates the collection of an
+// existing one. A bookmark created concurrently for the same user and
+// report is kept.
+func (b *bookmarkRepo) SaveBookmark(bookmark *models.Bookmark) error {
+	if bookmark.ID != 0 {
+		return b.DB.Model(bookmark).Update("collection_id", bookmark.CollectionID).Error
+	}
+	return b.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "report_id"}},
+		DoNothing: true,
+	}).Create(bookmark).Error
+}
+
+// DeleteBookmark reports whether there was a bookmark to remove.
+func (b *bookmarkRepo) DeleteBookmark(userID uint, reportID uuid.UUID) (bool, error) {
+	result := b.DB.Where("user_id = ? AND report_id = ?", userID, reportID).Delete(&models.Bookmark{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetBookmarkedReports returns a page of the user's bookmarked reports, most
+// recently bookmarked first, optionally only those in a collection.
+func (b *bookmarkRepo) GetBookmarkedReports(userID uint, collectionID *uint, page int) ([]models.IncidentReport, error) {
+	var reports []models.IncidentReport
+	// bookmarks.report_id is stored as text
+	query := b.DB.
+		Joins("JOIN bookmarks ON bookmarks.report_id = incident_reports.id::text").
+		Where("bookmarks.user_id = ?", userID)
+	if collectionID != nil {
+		query = query.Where("bookmarks.collection_id = ?", *collectionID)
+	}
+	err := query.Order("bookmarks.created_at DESC, bookmarks.id DESC").
+		Limit(DefaultPageSize).
+		Offset((page - 1) * DefaultPageSize).
+		Find(&reports).Error
+	if err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+// GetBookmarkedIDs returns which of the given reports the user bookmarked.
+func (b *bookmarkRepo) GetBookmarkedIDs(userID uint, reportIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	bookmarked := make(map[uuid.UUID]bool)
+	if len(reportIDs) == 0 {
+		return bookmarked, nil
+	}
+	var ids []string
+	err := b.DB.Model(&models.Bookmark{}).
+		Where("user_id = ? AND report_id IN ?", userID, reportIDs).
+		Pluck("report_id", &ids).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		if reportID, err := uuid.Parse(id); err == nil {
+			bookmarked[reportID] = true
+		}
+	}
+	return bookmarked, nil
+}
+
+// GetCollections returns the user's collections by name, with the number of
+// bookmarks in each.
+func (b *bookmarkRepo) GetCollections(userID uint) ([]models.BookmarkCollection, error) {
+	var collections []models.BookmarkCollection
+	if err := b.DB.Where("user_id = ?", userID).Order("name ASC").Find(&collections).Error; err != nil {
+		return nil, err
+	}
+	var counts []struct {
+		CollectionID uint
+		Count        int
+	}
+	err := b.DB.Model(&models.Bookmark{}).
+		Select("collection_id, COUNT(*) AS count").
+		Where("user_id = ? AND collection_id IS NOT NULL", userID).
+		Group("collection_id").
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uint]int, len(counts))
+	for _, count := range counts {
+		byID[count.CollectionID] = count.Count
+	}
+	for i := range collections {
+		collections[i].BookmarkCount = byID[collections[i].ID]
+	}
+	return collections, nil
+}
+
+// GetCollection returns nil when the user has no collection with the ID.
+func (b *bookmarkRepo) GetCollection(userID, id uint) (*models.BookmarkCollection, error) {
+	var collection models.BookmarkCollection
+	err := b.DB.Where("user_id = ?", userID).First(&collection, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &collection, nil
+}
+
+// CollectionNameTaken reports whether another of the user's collections
+// already has the name, ignoring case.
+func (b *bookmarkRepo) CollectionNameTaken(userID uint, name string, excludeID uint) (bool, error) {
+	var count int64
+	err := b.DB.Model(&models.BookmarkCollection{}).
+		Where("user_id = ? AND LOWER(name) = LOWER(?) AND id <> ?", userID, name, excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (b *bookmarkRepo) SaveCollection(collection *models.BookmarkCollection) error {
+	return b.DB.Save(collection).Error
+}
+
+// DeleteCollection removes a collection. Its bookmarks are kept, outside any
+// collection.
+func (b *bookmarkRepo) DeleteCollection(collection *models.BookmarkCollection) error {
+	return b.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(&models.Bookmark{}).
+			Where("collection_id = ?", collection.ID).
+			Update("collection_id", nil).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(collection).Error
+	})
+}
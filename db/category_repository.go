@@ -0,0 +1,175 @@
+package db
+
+import (
+	"github.com/pkg/errors"
+	"github.com/techagentng/citizenx/models"
+	"gorm.io/gorm"
+)
+
+type CategoryRepository interface {
+	ListCategories(includeInactive bool) ([]models.Category, error)
+	GetCategoryByID(id uint) (*models.Category, error)
+	GetCategoryBySlug(slug string) (*models.Category, error)
+	CreateCategory(category *models.Category) error
+	UpdateCategory(category *models.Category, oldSlug string) error
+	DeleteCategory(id uint) error
+	GetSubCategoryByID(id uint) (*models.SubCategory, error)
+	CreateSubCategory(sub *models.SubCategory) error
+	UpdateSubCategory(sub *models.SubCategory) error
+	DeleteSubCategory(id uint) error
+}
+
+type categoryRepo struct {
+	DB *gorm.DB
+}
+
+func NewCategoryRepo(db *GormDB) CategoryRepository {
+	return &categoryRepo{db.DB}
+}
+
+// ListCategories returns the taxonomy in display order with the sub-types of
+// each category.
+func (c *categoryRepo) ListCategories(includeInactive bool) ([]models.Category, error) {
+	var categories []models.Category
+	query := c.DB.Order("sort_order ASC, name ASC")
+	subTypes := func(db *gorm.DB) *gorm.DB {
+		if !includeInactive {
+			db = db.Where("active = ?", true)
+		}
+		return db.Order("sort_order ASC, name ASC")
+	}
+	if !includeInactive {
+		query = query.Where("active = ?", true)
+	}
+	if err := query.Preload("SubTypes", subTypes).Find(&categories).Error; err != nil {
+		return nil, err
+	}
+	return categories, nil
+}
+
+// GetCategoryByID returns the category with all its sub-types, or nil.
+func (c *categoryRepo) GetCategoryByID(id uint) (*models.Category, error) {
+	var category models.Category
+	err := c.DB.Preload("SubTypes", func(db *gorm.DB) *gorm.DB {
+		return db.Order("sort_order ASC, name ASC")
+	}).First(&category, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &category, nil
+}
+
+// GetCategoryBySlug returns the category with all its sub-types, or nil.
+func (c *categoryRepo) GetCategoryBySlug(slug string) (*models.Category, error) {
+	var category models.Category
+	err := c.DB.Preload("SubTypes").Where("slug = ?", slug).First(&category).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &category, nil
+}
+
+func (c *categoryRepo) CreateCategory(category *models.Category) error {
+	return c.DB.Omit("SubTypes").Create(category).Error
+}
+
+// UpdateCategory saves the category. Agencies cover categories by slug, so a
+// changed slug is rewritten in their coverage in the same transaction.
+func (c *categoryRepo) UpdateCategory(category *models.Category, oldSlug string) error {
+	return c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("SubTypes", "created_at").Save(category).Error; err != nil {
+			return err
+		}
+		if category.Slug == oldSlug {
+			return nil
+		}
+		return tx.Exec(`UPDATE agencies SET categories = (
+				SELECT jsonb_agg(CASE WHEN lower(trim(e.slug)) = lower(?) THEN to_jsonb(?::text) ELSE to_jsonb(e.slug) END ORDER BY e.n)
+				FROM jsonb_array_elements_text(agencies.categories) WITH ORDINALITY AS e(slug, n))
+			WHERE EXISTS (
+				SELECT 1 FROM jsonb_array_elements_text(agencies.categories) AS e(slug)
+				WHERE lower(trim(e.slug)) = lower(?))`,
+			oldSlug, category.Slug, oldSlug).Error
+	})
+}
+
+// DeleteCategory removes the category and its sub-types. Reports keep their
+// category name; admins usually deactivate a category instead.
+func (c *categoryRepo) DeleteCategory(id uint) error {
+	return c.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("category_id = ?", id).Delete(&models.SubCategory{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.Category{}, id).Error
+	})
+}
+
+func (c *categoryRepo) GetSubCategoryByID(id uint) (*models.SubCategory, error) {
+	var sub models.SubCategory
+	if err := c.DB.First(&sub, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &sub, nil
+}
+
+func (c *categoryRepo) CreateSubCategory(sub *models.SubCategory) error {
+	return c.DB.Create(sub).Error
+}
+
+func (c *categoryRepo) UpdateSubCategory(sub *models.SubCategory) error {
+	return c.DB.Omit("created_at").Save(sub).Error
+}
+
+func (c *categoryRepo) DeleteSubCategory(id uint) error {
+	return c.DB.Delete(&models.SubCategory{}, id).Error
+}
+
+// seedCategories creates a category for every category name already used by
+// reports the first time the taxonomy table is empty, so existing clients can
+// keep submitting, and links those reports to it.
+func seedCategories(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&models.Category{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	var names []string
+	err := db.Model(&models.IncidentReport{}).
+		Where("COALESCE(category, '') <> ''").
+		Distinct().
+		Pluck("category", &names).Error
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for i, name := range names {
+			category := models.Category{Slug: models.Slugify(name), Name: name, Active: true, SortOrder: i}
+			if category.Slug == "" {
+				continue
+			}
+			if err := tx.Where(models.Category{Slug: category.Slug}).FirstOrCreate(&category).Error; err != nil {
+				return err
+			}
+			err := tx.Model(&models.IncidentReport{}).
+				Where("category = ?", name).
+				Update("category_id", category.ID).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
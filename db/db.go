@@ -54,6 +54,7 @@ func SeedRoles(db *gorm.DB) error {
 	roles := []models.Role{
 		{ID: uuid.New(), Name: "Admin"},
 		{ID: uuid.New(), Name: "User"},
+		{ID: uuid.New(), Name: models.RoleAgency},
 	}
 
 	for _, role := range roles {
@@ -68,19 +69,22 @@ func SeedRoles(db *gorm.DB) error {
 func migrate(db *gorm.DB) error {
 	// AutoMigrate all the models
 	err := db.AutoMigrate(
+		&completedBackfill{},
 		&models.User{},
 		&models.Blacklist{},
 		&models.IncidentReport{},
 		&models.Media{},
 		&models.Reward{},
 		&models.Like{},
+		&models.View{},
+		&models.EngagementBatch{},
 		&models.Notification{},
 		&models.Comment{},
 		&models.ReportType{},
-		&models.IncidentReportUser{},
 		&models.LGA{},
 		&models.State{},
 		&models.Bookmark{},
+		&models.BookmarkCollection{},
 		&models.StateReportPercentage{},
 		&models.MediaCount{},
 		&models.LoginRequestMacAddress{},
@@ -97,12 +101,60 @@ func migrate(db *gorm.DB) error {
 		&models.OAuthState{},
 		&models.Conversation{},
 		&models.Message{},
+		&models.UploadSession{},
+		&models.MediaJob{},
+		&models.DeadMediaJob{},
+		&models.Category{},
+		&models.SubCategory{},
+		&models.Agency{},
+		&models.ReportAssignment{},
+		&models.OfficialResponse{},
+		&models.ResolutionVote{},
+		&models.LedgerTransaction{},
+		&models.LedgerEntry{},
+		&models.PointsBalance{},
+		&models.RewardRule{},
+		&models.Redemption{},
+		&models.Badge{},
+		&models.UserBadge{},
 	)
 	
 	if err != nil {
 		return fmt.Errorf("migrations error: %v", err)
 	}
 
+	if err := backfillReportMedia(db); err != nil {
+		return fmt.Errorf("media backfill error: %v", err)
+	}
+
+	if err := backfillReportAttributes(db); err != nil {
+		return fmt.Errorf("attributes backfill error: %v", err)
+	}
+
+	if err := backfillLedger(db); err != nil {
+		return fmt.Errorf("ledger backfill error: %v", err)
+	}
+
+	if err := backfillVotes(db); err != nil {
+		return fmt.Errorf("votes backfill error: %v", err)
+	}
+
+	if err := backfillBookmarks(db); err != nil {
+		return fmt.Errorf("bookmarks backfill error: %v", err)
+	}
+
+	if err := seedCategories(db); err != nil {
+		return fmt.Errorf("seeding categories error: %v", err)
+	}
+
+	if err := seedRewardRules(db); err != nil {
+		return fmt.Errorf("seeding reward rules error: %v", err)
+	}
+
+	if err := seedBadges(db); err != nil {
+		return fmt.Errorf("seeding badges error: %v", err)
+	}
+
 	// Seed roles
 	if err := SeedRoles(db); err != nil {
 		return fmt.Errorf("seeding roles error: %v", err)
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"github.com/techagentng/citizenx/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// engagementBatchRetention is how long applied batch IDs are remembered.
+const engagementBatchRetention = 7 * 24 * time.Hour
+
+type EngagementRepository interface {
+	ApplyEngagement(batchID string, views, shares map[string]int, viewers []models.View) error
+	GetReportMetrics(reportID string) (*models.ReportMetrics, error)
+}
+
+type engagementRepo struct {
+	DB *gorm.DB
+}
+
+func NewEngagementRepo(db *GormDB) EngagementRepository {
+	return &engagementRepo{db.DB}
+}
+
+// ApplyEngagement adds buffered view and share counts to the reports and
+// records new viewers, in one transaction. Viewers already recorded are
+// skipped, and so is a batch that was already applied.
+func (e *engagementRepo) ApplyEngagement(batchID string, views, shares map[string]int, viewers []models.View) error {
+	return e.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&models.EngagementBatch{ID: batchID, AppliedAt: now.Unix()})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		err := tx.Where("applied_at < ?", now.Add(-engagementBatchRetention).Unix()).
+			Delete(&models.EngagementBatch{}).Error
+		if err != nil {
+			return err
+		}
+
+		for reportID, count := range views {
+			err := tx.Model(&models.IncidentReport{}).
+				Where("id = ?", reportID).
+				Update("view", gorm.Expr("view + ?", count)).Error
+			if err != nil {
+				return err
+			}
+		}
+		for reportID, count := range shares {
+			err := tx.Model(&models.IncidentReport{}).
+				Where("id = ?", reportID).
+				Update("share_count", gorm.Expr("share_count + ?", count)).Error
+			if err != nil {
+				return err
+			}
+		}
+		if len(viewers) == 0 {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(viewers, 500).Error
+	})
+}
+
+// GetReportMetrics returns nil when the report does not exist.
+func (e *engagementRepo) GetReportMetrics(reportID string) (*models.ReportMetrics, error) {
+	var report models.IncidentReport
+	err := e.DB.Select("id, user_id, view, share_count, upvote_count, downvote_count").
+		Where("id = ?", reportID).
+		First(&report).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := &models.ReportMetrics{
+		ReportID:  reportID,
+		Views:     report.View,
+		Shares:    report.ShareCount,
+		Upvotes:   report.UpvoteCount,
+		Downvotes: report.DownvoteCount,
+		OwnerID:   report.UserID,
+	}
+	var counts struct {
+		UniqueViewers int
+		Bookmarks     int
+		Follows       int
+	}
+	err = e.DB.Raw(`SELECT
+			(SELECT COUNT(*) FROM views WHERE incident_report_id = ?) AS unique_viewers,
+			(SELECT COUNT(*) FROM bookmarks WHERE report_id = ?) AS bookmarks,
+			(SELECT COUNT(*) FROM follows WHERE report_id = ?) AS follows`,
+		reportID, reportID, reportID).
+		Scan(&counts).Error
+	if err != nil {
+		return nil, err
+	}
+	metrics.UniqueViewers = counts.UniqueViewers
+	metrics.Bookmarks = counts.Bookmarks
+	metrics.Follows = counts.Follows
+	return metrics, nil
+}
@@ -0,0 +1,223 @@
+package db
+
+import (
+	"math"
+
+	"github.com/techagentng/citizenx/models"
+	"gorm.io/gorm"
+)
+
+// visibleReports excludes reports hidden from public listings.
+const visibleReports = "incident_reports.block_request IS DISTINCT FROM 'true' AND incident_reports.report_status IS DISTINCT FROM 'rejected'"
+
+// hotScore ranks reports by net upvotes decaying with age in hours. It takes
+// the current unix time as its parameter.
+const hotScore = "(GREATEST(incident_reports.upvote_count - incident_reports.downvote_count, 0) + 1) / POWER((? - incident_reports.created_at) / 3600.0 + 2, 1.5)"
+
+// FeedRepository ranks reports for the feed modes. Ranking queries return
+// report IDs, best first, and the reports are loaded a page at a time.
+type FeedRepository interface {
+	GetLatestPositions(after *models.FeedPosition, limit int) ([]models.FeedPosition, error)
+	RankHot(since, now int64, limit int) ([]string, error)
+	RankTrending(since int64, limit int) ([]string, error)
+	RankNearby(lat, lng, radius float64, limit int) ([]string, error)
+	RankLocal(state, lga string, limit int) ([]string, error)
+	RankForUser(user *models.User, since, now int64, limit int) ([]string, error)
+	GetReportMaps(ids []string) ([]map[string]interface{}, error)
+}
+
+type feedRepo struct {
+	DB *gorm.DB
+}
+
+func NewFeedRepo(db *GormDB) FeedRepository {
+	return &feedRepo{db.DB}
+}
+
+// GetLatestPositions returns the newest reports after the given position.
+func (f *feedRepo) GetLatestPositions(after *models.FeedPosition, limit int) ([]models.FeedPosition, error) {
+	query := f.DB.Table("incident_reports").
+		Select("incident_reports.id, incident_reports.created_at").
+		Where(visibleReports)
+	if after != nil {
+		query = query.Where("(incident_reports.created_at, incident_reports.id) < (?, ?::uuid)", after.CreatedAt, after.ID)
+	}
+	var positions []models.FeedPosition
+	err := query.Order("incident_reports.created_at DESC, incident_reports.id DESC").
+		Limit(limit).
+		Scan(&positions).Error
+	return positions, err
+}
+
+func (f *feedRepo) RankHot(since, now int64, limit int) ([]string, error) {
+	var ids []string
+	err := f.DB.Raw(`SELECT incident_reports.id FROM incident_reports
+		WHERE `+visibleReports+` AND incident_reports.created_at >= ?
+		ORDER BY `+hotScore+` DESC, incident_reports.created_at DESC
+		LIMIT ?`, since, now, limit).
+		Scan(&ids).Error
+	return ids, err
+}
+
+// RankTrending ranks reports by how many upvotes and follows they picked up
+// since the given time.
+func (f *feedRepo) RankTrending(since int64, limit int) ([]string, error) {
+	var ids []string
+	err := f.DB.Raw(`SELECT incident_reports.id FROM incident_reports
+		JOIN (
+			SELECT report_id, COUNT(*) AS activity FROM (
+				SELECT report_id FROM votes WHERE vote_type = ? AND updated_at >= ?
+				UNION ALL
+				SELECT report_id::text FROM follows WHERE created_at >= to_timestamp(?)
+			) recent GROUP BY report_id
+		) a ON a.report_id = incident_reports.id::text
+		WHERE `+visibleReports+`
+		ORDER BY a.activity DESC, incident_reports.created_at DESC
+		LIMIT ?`, models.VoteUp, since, since, limit).
+		Scan(&ids).Error
+	return ids, err
+}
+
+// RankNearby ranks reports within radius metres of the point, nearest first.
+func (f *feedRepo) RankNearby(lat, lng, radius float64, limit int) ([]string, error) {
+	// A bounding box lets the index narrow the rows before the distance is
+	// worked out
+	latDelta := radius / 111320
+	lngDelta := radius / (111320 * math.Max(math.Cos(lat*math.Pi/180), 0.01))
+	var ids []string
+	err := f.DB.Raw(`SELECT id FROM (
+			SELECT incident_reports.id, incident_reports.created_at,
+				2 * 6371000 * ASIN(SQRT(
+					POWER(SIN(RADIANS(incident_reports.latitude - ?) / 2), 2) +
+					COS(RADIANS(?)) * COS(RADIANS(incident_reports.latitude)) *
+					POWER(SIN(RADIANS(incident_reports.longitude - ?) / 2), 2)
+				)) AS distance
+			FROM incident_reports
+			WHERE `+visibleReports+`
+				AND incident_reports.latitude BETWEEN ? AND ?
+				AND incident_reports.longitude BETWEEN ? AND ?
+		) nearby
+		WHERE distance <= ?
+		ORDER BY distance, created_at DESC
+		LIMIT ?`,
+		lat, lat, lng,
+		lat-latDelta, lat+latDelta, lng-lngDelta, lng+lngDelta,
+		radius, limit).
+		Scan(&ids).Error
+	return ids, err
+}
+
+// RankLocal ranks the reports of the state, those in the LGA first.
+func (f *feedRepo) RankLocal(state, lga string, limit int) ([]string, error) {
+	var ids []string
+	err := f.DB.Raw(`SELECT incident_reports.id FROM incident_reports
+		WHERE `+visibleReports+` AND incident_reports.state_name = ?
+		ORDER BY (incident_reports.lga_name = ?) DESC, incident_reports.created_at DESC
+		LIMIT ?`, state, lga, limit).
+		Scan(&ids).Error
+	return ids, err
+}
+
+// RankForUser ranks the hot reports by how close they are to the user:
+// reports they follow, reports in their LGA or state, and reports in the
+// categories of reports they filed, followed or upvoted count for more.
+func (f *feedRepo) RankForUser(user *models.User, since, now int64, limit int) ([]string, error) {
+	var ids []string
+	err := f.DB.Raw(`WITH followed AS (
+			SELECT report_id FROM follows WHERE user_id = @user
+		), engaged AS (
+			SELECT DISTINCT category_id FROM incident_reports
+			WHERE category_id <> 0 AND (
+				user_id = @user
+				OR id IN (SELECT report_id FROM followed)
+				OR id::text IN (SELECT report_id FROM votes WHERE user_id = @user AND vote_type = @upvote)
+			)
+		)
+		SELECT incident_reports.id FROM incident_reports
+		WHERE `+visibleReports+`
+			AND incident_reports.created_at >= @since
+			AND incident_reports.user_id <> @user
+		ORDER BY (1
+			+ CASE WHEN incident_reports.id IN (SELECT report_id FROM followed) THEN 4 ELSE 0 END
+			+ CASE WHEN @lga <> '' AND incident_reports.lga_name = @lga THEN 3
+				WHEN @state <> '' AND incident_reports.state_name = @state THEN 1
+				ELSE 0 END
+			+ CASE WHEN incident_reports.category_id IN (SELECT category_id FROM engaged) THEN 2 ELSE 0 END
+		) * (GREATEST(incident_reports.upvote_count - incident_reports.downvote_count, 0) + 1)
+			/ POWER((@now - incident_reports.created_at) / 3600.0 + 2, 1.5) DESC,
+			incident_reports.created_at DESC
+		LIMIT @limit`,
+		map[string]interface{}{
+			"user":   user.ID,
+			"upvote": models.VoteUp,
+			"since":  since,
+			"now":    now,
+			"state":  user.StateName,
+			"lga":    user.LGAName,
+			"limit":  limit,
+		}).
+		Scan(&ids).Error
+	return ids, err
+}
+
+// GetReportMaps loads the reports in the order of ids, with the reporter's
+// profile image, in the shape GetAllReports returns.
+func (f *feedRepo) GetReportMaps(ids []string) ([]map[string]interface{}, error) {
+	reports := []map[string]interface{}{}
+	if len(ids) == 0 {
+		return reports, nil
+	}
+	err := f.DB.
+		Table("incident_reports").
+		Select(`
+			incident_reports.*,
+			users.thumb_nail_url AS thumbnail_urls,
+			users.profile_image AS profile_image,
+			users.state_name AS user_state_name,
+			incident_reports.is_anonymous
+		`).
+		Joins("JOIN users ON users.id = incident_reports.user_id").
+		Where("incident_reports.id IN ?", ids).
+		Scan(&reports).Error
+	if err != nil {
+		return nil, err
+	}
+	setReportProfileImages(reports)
+
+	byID := make(map[string]map[string]interface{}, len(reports))
+	for _, report := range reports {
+		byID[reportMapKey(report["id"])] = report
+	}
+	ordered := make([]map[string]interface{}, 0, len(reports))
+	for _, id := range ids {
+		if report, ok := byID[id]; ok {
+			ordered = append(ordered, report)
+		}
+	}
+	return ordered, nil
+}
+
+// setReportProfileImages falls back to the reporter's thumbnail when they
+// have no profile image.
+func setReportProfileImages(reports []map[string]interface{}) {
+	for _, report := range reports {
+		switch {
+		case report["profile_image"] != nil && report["profile_image"] != "":
+			// ok
+		case report["thumbnail_urls"] != nil && report["thumbnail_urls"] != "":
+			report["profile_image"] = report["thumbnail_urls"]
+		default:
+			report["profile_image"] = nil
+		}
+	}
+}
+
+func reportMapKey(id interface{}) string {
+	if b, ok := id.([]byte); ok {
+		return string(b)
+	}
+	if s, ok := id.(string); ok {
+		return s
+	}
+	return ""
+}
@@ -1,21 +1,13 @@
 package db
 
 import (
-	"bytes"
 	"context"
-	"database/sql"
 	"fmt"
-	"io"
 	"log"
-	"mime/multipart"
-	"os"
+	"sort"
+	"strings"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/google/uuid"
 	"github.com/pkg/errors"
 	"github.com/techagentng/citizenx/models"
@@ -30,11 +22,12 @@ const (
 type IncidentReportRepository interface {
 	SaveIncidentReport(report *models.IncidentReport) (*models.IncidentReport, error)
 	HasPreviousReports(userID uint) (bool, error)
-	UpdateReward(userID uint, reward *models.Reward) error
 	FindUserByID(id uint) (*models.UserResponse, error)
 	GetReportByID(report_id string) (*models.IncidentReport, error)
 	GetAllReports() ([]map[string]interface{}, error)
 	GetAllReportsByState(state string, page int) ([]models.IncidentReport, error)
+	SearchIncidentReports(query string, attributes map[string]string, page int) ([]models.IncidentReport, error)
+	CountReportsByAttribute(field, category, state, lga string) ([]models.AttributeCount, error)
 	GetAllReportsByLGA(lga string, page int) ([]models.IncidentReport, error)
 	GetAllReportsByReportType(lga string, page int) ([]models.IncidentReport, error)
 	GetReportPercentageByState() ([]models.StateReportPercentage, error)
@@ -52,27 +45,22 @@ type IncidentReportRepository interface {
 	DeleteByID(id string) error
 	GetStateReportCounts() ([]models.StateReportCount, error)
 	GetVariadicStateReportCounts(reportTypes []string, states []string, startDate, endDate *time.Time) ([]models.StateReportCount, error)
-	GetAllCategories() ([]string, error)
 	GetAllStates() ([]string, error)
 	GetRatingPercentages(reportType, state string) (*models.RatingPercentage, error)
 	GetReportCountsByStateAndLGA() ([]models.ReportCount, error)
 	ListAllStatesWithReportCounts() ([]models.StateReportCount, error)
 	GetTotalReportCount() (int64, error)
 	GetNamesByCategory(stateName string, lgaID string, reportTypeCategory string) ([]string, error)
-	UploadMediaToS3(file multipart.File, fileHeader *multipart.FileHeader, bucketName, folderName string) (string, error)
 	SaveReportType(reportType *models.ReportType) (*models.ReportType, error)
 	SaveSubReport(subReport *models.SubReport) (*models.SubReport, error)
 	GetSubReportsByCategory(category string) ([]models.SubReport, error)
-	IsBookmarked(userID uint, reportID uuid.UUID, bookmark *models.Bookmark) error
-	SaveBookmark(bookmark *models.Bookmark) error
-	GetBookmarkedReports(userID uint) ([]models.IncidentReport, error)
 	GetReportsByUserID(userID uint) ([]models.ReportType, error)
 	GetReportTypeCountsByLGA(lga string) (map[string]interface{}, error)
 	GetReportCountsByState(state string) ([]string, []int, error)
 	GetTopCategories() ([]string, []int, error)
 	GetReportsByCategoryAndReportID(category string, reportID string) ([]models.ReportType, error)
 	GetReportsByCategory(category string) ([]models.ReportType, error)
-	GetFilteredIncidentReports(category, state, lga string) ([]models.IncidentReport, []string, error)
+	GetFilteredIncidentReports(category, state, lga string, attributes map[string]string) ([]models.IncidentReport, []string, error)
 	GetIncidentReportByID(reportID string) (*models.IncidentReport, error)
 	UpdateReportTypeWithIncidentReport(report *models.IncidentReport) error
 	FindReportTypeByCategory(category string, reportType *models.ReportType) error
@@ -274,57 +262,6 @@ func (i *incidentReportRepo) GetLastReportIDByUserID(userID uint) (string, error
 }
 
 
-func (i *incidentReportRepo) UpdateReward(userID uint, reward *models.Reward) error {
-	// Find the existing reward for the user
-	existingReward := &models.Reward{}
-
-	// Retrieve the existing reward from the database
-	if err := i.DB.Where("user_id = ?", userID).First(existingReward).Error; err != nil {
-		// Check if the error is due to record not found
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			// If record not found, create a new reward with the provided details
-			// and save it to the database
-			if err := i.DB.Create(reward).Error; err != nil {
-				return err
-			}
-			return nil
-		}
-		// Return other errors
-		return err
-	}
-
-	// Use COALESCE to handle NULL sums
-	var totalBalance sql.NullInt64
-	err := i.DB.Table("rewards").Select("COALESCE(SUM(balance), 0)").Where("user_id = ?", userID).Scan(&totalBalance).Error
-	if err != nil {
-		return fmt.Errorf("failed to retrieve total balance: %w", err)
-	}
-
-	// Log or use the balance
-	if totalBalance.Valid {
-		fmt.Println("Total balance:", totalBalance.Int64)
-	} else {
-		fmt.Println("Total balance is 0")
-	}
-
-	// Update the existing reward with the new values
-	existingReward.RewardType = reward.RewardType
-	existingReward.Point = reward.Point
-	existingReward.IncidentReportID = reward.IncidentReportID
-
-	// Update the balance if provided in the reward parameter
-	if reward.Balance != 0 {
-		existingReward.Balance = reward.Balance
-	}
-
-	// Save the updated reward to the database
-	if err := i.DB.Save(existingReward).Error; err != nil {
-		return fmt.Errorf("failed to update reward: %w", err)
-	}
-
-	return nil
-}
-
 func (i *incidentReportRepo) SaveIncidentReport(report *models.IncidentReport) (*models.IncidentReport, error) {
 	// Save the new report to the database
 	if err := i.DB.Create(&report).Error; err != nil {
@@ -390,7 +327,6 @@ func (repo *incidentReportRepo) GetAllReports() ([]map[string]interface{}, error
 			users.thumb_nail_url AS thumbnail_urls,
 			users.profile_image AS profile_image, 
 			users.state_name AS user_state_name,
-			incident_reports.feed_urls,
 			incident_reports.is_anonymous
 		`).
 		Joins("JOIN users ON users.id = incident_reports.user_id").
@@ -402,23 +338,9 @@ func (repo *incidentReportRepo) GetAllReports() ([]map[string]interface{}, error
 		return nil, err
 	}
 
-	// Clean up and anonymize as needed
-	for _, report := range reports {
-		if isAnonymous, ok := report["is_anonymous"].(bool); ok && isAnonymous {
-			report["user_fullname"] = "Anonymous"
-			report["user_username"] = "anonymous"
-			report["profile_image"] = nil
-		}
-
-		switch {
-		case report["profile_image"] != nil && report["profile_image"] != "":
-			// ok
-		case report["thumbnail_urls"] != nil && report["thumbnail_urls"] != "":
-			report["profile_image"] = report["thumbnail_urls"]
-		default:
-			report["profile_image"] = nil
-		}
-	}
+	// Anonymity is applied by the caller through models.RedactReportMap,
+	// which knows who the report is being shown to
+	setReportProfileImages(reports)
 
 	return reports, nil
 }
@@ -440,6 +362,71 @@ func (repo *incidentReportRepo) GetAllReportsByState(state string, page int) ([]
 	return reports, nil
 }
 
+// SearchIncidentReports matches the query against report descriptions and the
+// transcripts of their audio, and keeps reports whose attributes have the given
+// values. Blocked and rejected reports are left out.
+func (repo *incidentReportRepo) SearchIncidentReports(query string, attributes map[string]string, page int) ([]models.IncidentReport, error) {
+	var reports []models.IncidentReport
+	offset := (page - 1) * DefaultPageSize
+
+	db := repo.DB.Where("block_request IS DISTINCT FROM 'true' AND report_status IS DISTINCT FROM 'rejected'")
+	if query != "" {
+		pattern := "%" + escapeLike(query) + "%"
+		db = db.Where("description ILIKE ? OR EXISTS (SELECT 1 FROM media WHERE media.incident_report_id = incident_reports.id AND media.transcript ILIKE ?)", pattern, pattern)
+	}
+	err := whereAttributes(db, attributes).
+		Order("timeof_incidence DESC").
+		Limit(DefaultPageSize).
+		Offset(offset).
+		Find(&reports).Error
+	if err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+// whereAttributes keeps reports whose attributes equal the given values,
+// compared as text so numbers and booleans match their query string form.
+func whereAttributes(db *gorm.DB, attributes map[string]string) *gorm.DB {
+	names := make([]string, 0, len(attributes))
+	for name := range attributes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		db = db.Where("attributes ->> ? = ?", name, attributes[name])
+	}
+	return db
+}
+
+// CountReportsByAttribute counts reports per value of one attribute, for
+// analytics on category specific fields. Empty filters are ignored.
+func (repo *incidentReportRepo) CountReportsByAttribute(field, category, state, lga string) ([]models.AttributeCount, error) {
+	var counts []models.AttributeCount
+	query := repo.DB.Model(&models.IncidentReport{}).
+		Select("attributes ->> ? AS value, COUNT(*) AS count", field).
+		Where("jsonb_exists(attributes, ?)", field)
+	if category != "" {
+		query = query.Where("category = ?", category)
+	}
+	if state != "" {
+		query = query.Where("state_name = ?", state)
+	}
+	if lga != "" {
+		query = query.Where("lga_name = ?", lga)
+	}
+	err := query.Group("value").Order("count DESC").Scan(&counts).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to count reports by %s: %v", field, err)
+	}
+	return counts, nil
+}
+
+// escapeLike escapes the LIKE wildcards in user input.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
 // GetAllReportsByState returns incident reports filtered by state and time range, with pagination
 func (repo *incidentReportRepo) GetAllReportsByStateByTime(state string, startTime, endTime time.Time, page int) ([]models.IncidentReport, error) {
 	var reports []models.IncidentReport
@@ -819,14 +806,6 @@ func (repo *incidentReportRepo) GetVariadicStateReportCounts(reportTypes []strin
 	return stateReportCounts, nil
 }
 
-func (i *incidentReportRepo) GetAllCategories() ([]string, error) {
-	var categories []string
-	if err := i.DB.Model(&models.ReportType{}).Distinct().Pluck("category", &categories).Error; err != nil {
-		return nil, fmt.Errorf("failed to fetch categories: %v", err)
-	}
-	return categories, nil
-}
-
 func (i *incidentReportRepo) GetAllStates() ([]string, error) {
 	var states []string
 	if err := i.DB.Model(&models.ReportType{}).Distinct().Pluck("state_name", &states).Error; err != nil {
@@ -924,48 +903,6 @@ func (i *incidentReportRepo) GetTotalReportCount() (int64, error) {
 	return count, nil
 }
 
-func (i *incidentReportRepo) uploadFileToS3(file multipart.File, bucketName, key string) (string, error) {
-	defer file.Close()
-
-	// Step 1: Load the AWS config with environment credentials
-	cfg, err := config.LoadDefaultConfig(context.TODO(),
-		config.WithRegion(os.Getenv("AWS_REGION")),
-		config.WithCredentialsProvider(
-			credentials.NewStaticCredentialsProvider(os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"), ""),
-		),
-	)
-	if err != nil {
-		return "", fmt.Errorf("unable to load AWS config: %v", err)
-	}
-
-	// Step 2: Create an S3 client with the configured credentials
-	svc := s3.NewFromConfig(cfg)
-
-	// Step 3: Read the file content into memory
-	fileContent, err := io.ReadAll(file)
-	if err != nil {
-		return "", fmt.Errorf("failed to read file content: %v", err)
-	}
-
-	// Step 4: Prepare the S3 PutObjectInput
-	putObjectInput := &s3.PutObjectInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(key),
-		Body:   bytes.NewReader(fileContent),
-		ACL:    "public-read", // Set the ACL to public-read
-	}
-
-	// Step 5: Upload the file to S3
-	_, err = svc.PutObject(context.TODO(), putObjectInput)
-	if err != nil {
-		return "", fmt.Errorf("failed to upload file to S3a: %v", err)
-	}
-
-	// Step 6: Construct and return the file URL
-	fileURL := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucketName, os.Getenv("AWS_REGION"), key)
-	return fileURL, nil
-}
-
 func (i *incidentReportRepo) GetNamesByCategory(stateName string, lgaID string, reportTypeCategory string) ([]string, error) {
 	var names []string
 
@@ -980,85 +917,6 @@ func (i *incidentReportRepo) GetNamesByCategory(stateName string, lgaID string,
 	return names, nil
 }
 
-func createS3Client() (*s3.Client, error) {
-	cfg, err := config.LoadDefaultConfig(context.TODO(),
-		config.WithRegion(os.Getenv("AWS_REGION")),
-		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
-			os.Getenv("AWS_ACCESS_KEY_ID"),
-			os.Getenv("AWS_SECRET_ACCESS_KEY"),
-			"",
-		)),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("unable to load SDK config, %v", err)
-	}
-
-	return s3.NewFromConfig(cfg), nil
-}
-
-func (i *incidentReportRepo) UploadMediaToS3(file multipart.File, fileHeader *multipart.FileHeader, bucketName, folderName string) (string, error) {
-	defer file.Close()
-
-	// Create an S3 client
-	client, err := createS3Client()
-	if err != nil {
-		return "", fmt.Errorf("failed to create S3 client: %v", err)
-	}
-
-	// Generate a unique key for the file
-	key := fmt.Sprintf("%s/%s", folderName, fileHeader.Filename)
-
-	// Upload the file to S3
-	fileURL, err := uploadFileToS3(client, file, bucketName, key)
-	if err != nil {
-		return "", fmt.Errorf("failed to upload file to S3b: %v", err)
-	}
-
-	return fileURL, nil
-}
-
-// Function to upload a file to S3
-func uploadFileToS3(client *s3.Client, file multipart.File, bucketName, key string) (string, error) {
-	defer file.Close()
-
-	// Step 1: Read the file content into memory
-	fileContent, err := io.ReadAll(file)
-	if err != nil {
-		// Log and return an error if reading the file fails
-		fmt.Printf("Error reading file content: %v\n", err)
-		return "", fmt.Errorf("failed to read file content: %v", err)
-	}
-
-	// Step 2: Log information about the bucket and key
-	fmt.Printf("Uploading to bucket: %s\n", bucketName)
-	fmt.Printf("Uploading with key: %s\n", key)
-
-	// Step 3: Prepare the S3 PutObjectInput
-	putObjectInput := &s3.PutObjectInput{
-		Bucket: aws.String(bucketName),          // Specify the S3 bucket name
-		Key:    aws.String(key),                 // Specify the object key (file name)
-		Body:   bytes.NewReader(fileContent),    // Use the file content as the body
-		ACL:    types.ObjectCannedACLPublicRead, // Directly use the ObjectCannedACL enum
-	}
-
-	// Step 4: Attempt to upload the file to S3
-	_, err = client.PutObject(context.TODO(), putObjectInput)
-	if err != nil {
-		// Log and return an error if the upload fails
-		fmt.Printf("Error uploading file to S3: %v\n", err)
-		return "", fmt.Errorf("failed to upload file to S3c: %v", err)
-	}
-
-	// Step 5: Construct the public URL for the uploaded file
-	fileURL := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucketName, os.Getenv("AWS_REGION"), key)
-
-	// Step 6: Log the successful upload and the URL
-	fmt.Printf("File uploaded successfully, URL: %s\n", fileURL)
-
-	// Step 7: Return the file URL
-	return fileURL, nil
-}
-
 func (repo *incidentReportRepo) SaveReportType(reportType *models.ReportType) (*models.ReportType, error) {
 	if err := repo.DB.Create(reportType).Error; err != nil {
 		return nil, err // Return nil and the error
@@ -1132,7 +990,7 @@ func (repo *incidentReportRepo) GetAllIncidentReportsByUser(userID uint, page in
 		return []map[string]interface{}{}, nil
 	}
 
-	// Process the reports to set profile_image and handle feed_urls
+	// Process the reports to set profile_image
 	for _, report := range reports {
 		if profileImage, exists := report["profile_image"]; exists && profileImage != "" {
 			report["profile_image"] = profileImage
@@ -1141,11 +999,6 @@ func (repo *incidentReportRepo) GetAllIncidentReportsByUser(userID uint, page in
 		} else {
 			report["profile_image"] = nil
 		}
-
-		// Ensure feed_urls is properly handled
-		if feedUrls, exists := report["feed_urls"]; exists {
-			report["feed_urls"] = feedUrls
-		}
 	}
 
 	return reports, nil
@@ -1153,37 +1006,6 @@ func (repo *incidentReportRepo) GetAllIncidentReportsByUser(userID uint, page in
 
 
 
-func (repo *incidentReportRepo) IsBookmarked(userID uint, reportID uuid.UUID, bookmark *models.Bookmark) error {
-	return repo.DB.Where("user_id = ? AND report_id = ?", userID, reportID).
-		First(bookmark).Error
-}
-
-func (repo *incidentReportRepo) SaveBookmark(bookmark *models.Bookmark) error {
-	return repo.DB.Create(bookmark).Error
-}
-
-func (repo *incidentReportRepo) GetBookmarkedReports(userID uint) ([]models.IncidentReport, error) {
-	var reports []models.IncidentReport
-
-	log.Printf("Retrieving bookmarked reports for userID: %d", userID)
-
-	// Cast incident_reports.id (uuid) to text to match bookmarks.report_id
-	err := repo.DB.
-		Joins("JOIN bookmarks ON bookmarks.report_id = incident_reports.id::text").
-		Where("bookmarks.user_id = ?", userID).
-		Find(&reports).Error
-
-	if err != nil {
-		log.Printf("Error retrieving reports: %v", err)
-		return nil, err
-	}
-
-	log.Printf("Found reports: %v", reports)
-
-	return reports, nil
-}
-
-
 func (repo *incidentReportRepo) GetReportsByUserID(userID uint) ([]models.ReportType, error) {
 	var reports []models.ReportType
 
@@ -1353,7 +1175,7 @@ func (repo *incidentReportRepo) GetReportsByCategory(category string) ([]models.
 	return reports, nil
 }
 
-func (i *incidentReportRepo) GetFilteredIncidentReports(category, state, lga string) ([]models.IncidentReport, []string, error) {
+func (i *incidentReportRepo) GetFilteredIncidentReports(category, state, lga string, attributes map[string]string) ([]models.IncidentReport, []string, error) {
 	var reports []models.IncidentReport
 	var filters []string
 
@@ -1373,6 +1195,10 @@ func (i *incidentReportRepo) GetFilteredIncidentReports(category, state, lga str
 		query = query.Where("lga_name = ?", lga)
 		filters = append(filters, lga) // Append the LGA value
 	}
+	for name, value := range attributes {
+		filters = append(filters, name+"="+value)
+	}
+	query = whereAttributes(query, attributes)
 
 	// Execute the query and get the results
 	if err := query.Find(&reports).Error; err != nil {
@@ -1422,9 +1248,6 @@ func (i *incidentReportRepo) UpdateIncidentReport(report *models.IncidentReport)
 
 		// Update the existing report's fields with the new data.
 		existingReport.Description = report.Description
-		existingReport.FeedURLs = report.FeedURLs
-		existingReport.ThumbnailURLs = report.ThumbnailURLs
-		existingReport.FullSizeURLs = report.FullSizeURLs
 		existingReport.StateName = report.StateName
 		existingReport.LGAName = report.LGAName
 		existingReport.Latitude = report.Latitude
@@ -1442,29 +1265,14 @@ func (i *incidentReportRepo) UpdateIncidentReport(report *models.IncidentReport)
 		existingReport.IsResponse = report.IsResponse
 		existingReport.TimeofIncidence = report.TimeofIncidence
 		existingReport.ReportStatus = report.ReportStatus
+		existingReport.ApprovalRound = report.ApprovalRound
 		existingReport.RewardPoint = report.RewardPoint
 		existingReport.RewardAccountNumber = report.RewardAccountNumber
 		existingReport.ActionTypeName = report.ActionTypeName
 		existingReport.IsState = report.IsState
 		existingReport.Rating = report.Rating
-		existingReport.HospitalName = report.HospitalName
-		existingReport.Department = report.Department
-		existingReport.DepartmentHeadName = report.DepartmentHeadName
-		existingReport.AccidentCause = report.AccidentCause
-		existingReport.SchoolName = report.SchoolName
-		existingReport.VicePrincipal = report.VicePrincipal
-		existingReport.OutageLength = report.OutageLength
-		existingReport.AirportName = report.AirportName
-		existingReport.Country = report.Country
-		existingReport.StateEmbassyLocation = report.StateEmbassyLocation
-		existingReport.NoWater = report.NoWater
-		existingReport.AmbassedorsName = report.AmbassedorsName
-		existingReport.HospitalAddress = report.HospitalAddress
-		existingReport.RoadName = report.RoadName
-		existingReport.AirlineName = report.AirlineName
 		existingReport.Category = report.Category
-		existingReport.Terminal = report.Terminal
-		existingReport.QueueTime = report.QueueTime
+		existingReport.Attributes = report.Attributes
 		existingReport.SubReportType = report.SubReportType
 		existingReport.UpvoteCount = report.UpvoteCount
 		existingReport.DownvoteCount = report.DownvoteCount
@@ -1514,9 +1322,8 @@ func updateRelatedReportType(tx *gorm.DB, report *models.IncidentReport, newRepo
 
 // Example validation function for IncidentReport.
 func validateIncidentReport(report *models.IncidentReport) error {
-	// Validate FeedURLs
-	if len(report.FeedURLs) == 0 {
-		return fmt.Errorf("feed URLs cannot be empty")
+	if report.ID == uuid.Nil {
+		return fmt.Errorf("report ID cannot be empty")
 	}
 
 	// Add more validation logic as needed.
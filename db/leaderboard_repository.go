@@ -0,0 +1,100 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/techagentng/citizenx/models"
+	"gorm.io/gorm"
+)
+
+type LeaderboardRepository interface {
+	GetUserByID(id uint) (*models.User, error)
+	GetUsersByIDs(ids []uint) ([]models.User, error)
+	EachEarnedPoints(afterID uint, fn func(models.EarnedPoints) error) error
+	GetReputationStats(userID uint) (*models.ReputationStats, error)
+}
+
+type leaderboardRepo struct {
+	DB *gorm.DB
+}
+
+func NewLeaderboardRepo(db *GormDB) LeaderboardRepository {
+	return &leaderboardRepo{db.DB}
+}
+
+func (l *leaderboardRepo) GetUserByID(id uint) (*models.User, error) {
+	var user models.User
+	err := l.DB.Select("id, state_name, lga_name").Where("id = ?", id).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func (l *leaderboardRepo) GetUsersByIDs(ids []uint) ([]models.User, error) {
+	var users []models.User
+	if len(ids) == 0 {
+		return users, nil
+	}
+	err := l.DB.Select("id, username, fullname, thumb_nail_url, is_anonymous").
+		Where("id IN ?", ids).
+		Find(&users).Error
+	return users, err
+}
+
+// EachEarnedPoints calls fn for every ledger transaction after afterID that
+// counts towards the leaderboards, oldest first. The filter mirrors
+// LedgerTransaction.EarnsPoints.
+func (l *leaderboardRepo) EachEarnedPoints(afterID uint, fn func(models.EarnedPoints) error) error {
+	rows, err := l.DB.Table("ledger_transactions t").
+		Select("t.id, t.user_id, u.state_name, u.lga_name, t.amount, t.idempotency_key, t.created_at").
+		Joins("JOIN users u ON u.id = t.user_id").
+		Where("t.id > ? AND t.type <> ? AND t.idempotency_key NOT LIKE ?", afterID, models.LedgerRedemption, "reversal:redemption:%").
+		Order("t.id").
+		Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var earned models.EarnedPoints
+		if err := l.DB.ScanRows(rows, &earned); err != nil {
+			return err
+		}
+		if err := fn(earned); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// GetReputationStats returns nil when the user does not exist.
+func (l *leaderboardRepo) GetReputationStats(userID uint) (*models.ReputationStats, error) {
+	var user models.User
+	err := l.DB.Select("id, is_queried, is_verified").Where("id = ?", userID).First(&user).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var stats models.ReputationStats
+	err = l.DB.Model(&models.IncidentReport{}).
+		Select(`COUNT(*) FILTER (WHERE report_status = 'approved') AS approved_reports,
+			COUNT(*) FILTER (WHERE report_status = 'rejected') AS rejected_reports,
+			COALESCE(SUM(upvote_count), 0) AS upvotes_received,
+			COALESCE(SUM(downvote_count), 0) AS downvotes_received`).
+		Where("user_id = ?", userID).
+		Scan(&stats).Error
+	if err != nil {
+		return nil, err
+	}
+	stats.IsQueried = user.IsQueried
+	stats.IsVerified = user.IsVerified
+	return &stats, nil
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/techagentng/citizenx/models"
+	"gorm.io/gorm"
+)
+
+// backfillLedger opens the points ledger with each user's balance from the
+// legacy rewards table. It runs in one transaction and only while the ledger
+// has no opening balances, so it does work once.
+func backfillLedger(db *gorm.DB) error {
+	var opened int64
+	err := db.Model(&models.LedgerTransaction{}).
+		Where("idempotency_key LIKE ?", "opening:%").
+		Count(&opened).Error
+	if err != nil || opened > 0 {
+		return err
+	}
+
+	var balances []struct {
+		UserID uint
+		Points int
+	}
+	err = db.Table("rewards").
+		Select("user_id, SUM(point) AS points").
+		Where("user_id <> 0").
+		Group("user_id").
+		Having("SUM(point) <> 0").
+		Scan(&balances).Error
+	if err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, b := range balances {
+			txn := &models.LedgerTransaction{
+				IdempotencyKey: fmt.Sprintf("opening:%d", b.UserID),
+				Type:           models.LedgerAdjustment,
+				UserID:         b.UserID,
+				Amount:         b.Points,
+				Description:    "Opening balance from rewards",
+			}
+			if _, err := postLedgerTransaction(tx, txn); err != nil {
+				return fmt.Errorf("opening balance for user %d: %v", b.UserID, err)
+			}
+		}
+		return nil
+	})
+}
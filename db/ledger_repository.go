@@ -0,0 +1,272 @@
+package db
+
+import (
+	"errors"
+	"time"
+
+	"github.com/techagentng/citizenx/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInsufficientPoints is returned when a redemption would take a user's
+// balance below zero.
+var ErrInsufficientPoints = errors.New("insufficient points")
+
+type LedgerRepository interface {
+	Post(txn *models.LedgerTransaction) (bool, error)
+	GetTransactionByID(id uint) (*models.LedgerTransaction, error)
+	GetTransactionByKey(key string) (*models.LedgerTransaction, error)
+	GetEventTransactions(key string) ([]models.LedgerTransaction, error)
+	SumRuleCredits(userID, ruleID uint, since time.Time) (int, error)
+	GetUserTransactions(userID uint, page int) ([]models.LedgerTransaction, error)
+	GetTransactions(page int) ([]models.LedgerTransaction, error)
+	GetBalance(userID uint) (int, error)
+	SumBalances() (int, error)
+	RebuildBalances(apply bool) ([]models.BalanceDrift, error)
+	GetUnbalancedTransactions() ([]uint, error)
+}
+
+type ledgerRepo struct {
+	DB *gorm.DB
+}
+
+func NewLedgerRepo(db *GormDB) LedgerRepository {
+	return &ledgerRepo{db.DB}
+}
+
+// Post appends the transaction and its entries and moves the user's cached
+// balance. It returns false, with txn loaded from the ledger, when a
+// transaction with the same idempotency key was already posted.
+func (l *ledgerRepo) Post(txn *models.LedgerTransaction) (bool, error) {
+	posted := false
+	err := l.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		posted, err = postLedgerTransaction(tx, txn)
+		return err
+	})
+	return posted, err
+}
+
+// postLedgerTransaction does the work of Post inside tx. Entries left empty
+// default to the user's account against the system account for the type.
+func postLedgerTransaction(tx *gorm.DB, txn *models.LedgerTransaction) (bool, error) {
+	entries := txn.Entries
+	if len(entries) == 0 {
+		entries = defaultLedgerEntries(txn)
+	}
+
+	if txn.Type == models.LedgerRedemption {
+		var balance models.PointsBalance
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ?", txn.UserID).
+			Take(&balance).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, err
+		}
+		if balance.Balance+txn.Amount < 0 {
+			return false, ErrInsufficientPoints
+		}
+	}
+
+	txn.Entries = nil
+	result := tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "idempotency_key"}},
+		DoNothing: true,
+	}).Create(txn)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	if result.RowsAffected == 0 {
+		err := tx.Preload("Entries").Where("idempotency_key = ?", txn.IdempotencyKey).First(txn).Error
+		return false, err
+	}
+
+	for i := range entries {
+		entries[i].TransactionID = txn.ID
+	}
+	if err := tx.Create(&entries).Error; err != nil {
+		return false, err
+	}
+	txn.Entries = entries
+
+	err := tx.Exec(`INSERT INTO points_balances (user_id, balance, last_transaction_id, updated_at)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT (user_id) DO UPDATE SET
+			balance = points_balances.balance + EXCLUDED.balance,
+			last_transaction_id = EXCLUDED.last_transaction_id,
+			updated_at = EXCLUDED.updated_at`,
+		txn.UserID, txn.Amount, txn.ID, time.Now().Unix()).Error
+	return err == nil, err
+}
+
+func defaultLedgerEntries(txn *models.LedgerTransaction) []models.LedgerEntry {
+	counter := models.AccountRewards
+	if txn.Type == models.LedgerRedemption {
+		counter = models.AccountRedemptions
+	}
+	userID := txn.UserID
+	return []models.LedgerEntry{
+		{Account: models.UserAccount(txn.UserID), UserID: &userID, Amount: txn.Amount},
+		{Account: counter, Amount: -txn.Amount},
+	}
+}
+
+// GetTransactionByID returns the transaction with its entries, or nil.
+func (l *ledgerRepo) GetTransactionByID(id uint) (*models.LedgerTransaction, error) {
+	var txn models.LedgerTransaction
+	if err := l.DB.Preload("Entries").First(&txn, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &txn, nil
+}
+
+// GetTransactionByKey returns the transaction posted for the key, or nil.
+func (l *ledgerRepo) GetTransactionByKey(key string) (*models.LedgerTransaction, error) {
+	var txn models.LedgerTransaction
+	if err := l.DB.Preload("Entries").Where("idempotency_key = ?", key).First(&txn).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &txn, nil
+}
+
+// GetEventTransactions returns the transactions posted for an event: the one
+// keyed by key and those keyed per rule under it.
+func (l *ledgerRepo) GetEventTransactions(key string) ([]models.LedgerTransaction, error) {
+	var txns []models.LedgerTransaction
+	err := l.DB.Preload("Entries").
+		Where("idempotency_key = ? OR idempotency_key LIKE ?", key, key+":%").
+		Order("id ASC").
+		Find(&txns).Error
+	if err != nil {
+		return nil, err
+	}
+	return txns, nil
+}
+
+// SumRuleCredits returns the net points a rule paid the user since the given
+// time, net of reversals.
+func (l *ledgerRepo) SumRuleCredits(userID, ruleID uint, since time.Time) (int, error) {
+	var sum int
+	err := l.DB.Model(&models.LedgerTransaction{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("user_id = ? AND rule_id = ? AND created_at >= ?", userID, ruleID, since.Unix()).
+		Scan(&sum).Error
+	return sum, err
+}
+
+// GetUserTransactions returns a page of the user's transactions, newest first.
+func (l *ledgerRepo) GetUserTransactions(userID uint, page int) ([]models.LedgerTransaction, error) {
+	var txns []models.LedgerTransaction
+	err := l.DB.Where("user_id = ?", userID).
+		Order("id DESC").
+		Limit(DefaultPageSize).
+		Offset((page - 1) * DefaultPageSize).
+		Find(&txns).Error
+	if err != nil {
+		return nil, err
+	}
+	return txns, nil
+}
+
+// GetTransactions returns a page of all transactions, newest first.
+func (l *ledgerRepo) GetTransactions(page int) ([]models.LedgerTransaction, error) {
+	var txns []models.LedgerTransaction
+	err := l.DB.Order("id DESC").
+		Limit(DefaultPageSize).
+		Offset((page - 1) * DefaultPageSize).
+		Find(&txns).Error
+	if err != nil {
+		return nil, err
+	}
+	return txns, nil
+}
+
+// GetBalance returns the user's cached balance. Users without a snapshot have
+// their balance summed from the ledger.
+func (l *ledgerRepo) GetBalance(userID uint) (int, error) {
+	var balance models.PointsBalance
+	err := l.DB.Where("user_id = ?", userID).Take(&balance).Error
+	if err == nil {
+		return balance.Balance, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, err
+	}
+
+	var sum int
+	err = l.DB.Model(&models.LedgerEntry{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("user_id = ?", userID).
+		Scan(&sum).Error
+	return sum, err
+}
+
+// SumBalances returns the points held by all users.
+func (l *ledgerRepo) SumBalances() (int, error) {
+	var total int
+	err := l.DB.Model(&models.PointsBalance{}).Select("COALESCE(SUM(balance), 0)").Scan(&total).Error
+	return total, err
+}
+
+// RebuildBalances compares every cached balance with the sum of the user's
+// ledger entries and returns those that differ. With apply set the snapshots
+// are rewritten from the ledger. The snapshot table is locked meanwhile so
+// transactions posted concurrently are counted exactly once.
+func (l *ledgerRepo) RebuildBalances(apply bool) ([]models.BalanceDrift, error) {
+	var drifts []models.BalanceDrift
+	err := l.DB.Transaction(func(tx *gorm.DB) error {
+		if apply {
+			if err := tx.Exec("LOCK TABLE points_balances IN SHARE ROW EXCLUSIVE MODE").Error; err != nil {
+				return err
+			}
+		}
+		err := tx.Raw(`SELECT COALESCE(l.user_id, b.user_id) AS user_id,
+				COALESCE(b.balance, 0) AS cached,
+				COALESCE(l.total, 0) AS ledger
+			FROM (SELECT user_id, SUM(amount) AS total FROM ledger_entries
+				WHERE user_id IS NOT NULL GROUP BY user_id) l
+			FULL OUTER JOIN points_balances b ON b.user_id = l.user_id
+			WHERE COALESCE(b.balance, 0) <> COALESCE(l.total, 0)
+			ORDER BY 1`).Scan(&drifts).Error
+		if err != nil || !apply {
+			return err
+		}
+
+		now := time.Now().Unix()
+		for _, drift := range drifts {
+			err := tx.Exec(`INSERT INTO points_balances (user_id, balance, last_transaction_id, updated_at)
+				VALUES (?, ?, COALESCE((SELECT MAX(transaction_id) FROM ledger_entries WHERE user_id = ?), 0), ?)
+				ON CONFLICT (user_id) DO UPDATE SET
+					balance = EXCLUDED.balance,
+					last_transaction_id = EXCLUDED.last_transaction_id,
+					updated_at = EXCLUDED.updated_at`,
+				drift.UserID, drift.Ledger, drift.UserID, now).Error
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return drifts, nil
+}
+
+// GetUnbalancedTransactions returns the IDs of transactions whose entries do
+// not sum to zero. There should never be any.
+func (l *ledgerRepo) GetUnbalancedTransactions() ([]uint, error) {
+	var ids []uint
+	err := l.DB.Model(&models.LedgerEntry{}).
+		Group("transaction_id").
+		Having("SUM(amount) <> 0").
+		Pluck("transaction_id", &ids).Error
+	return ids, err
+}
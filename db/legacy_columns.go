@@ -0,0 +1,141 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/techagentng/citizenx/models"
+	"gorm.io/gorm"
+)
+
+// completedBackfill records a startup backfill that finished, so it is not
+// repeated while the legacy columns it reads from are still around.
+type completedBackfill struct {
+	Name        string `gorm:"primaryKey"`
+	CompletedAt time.Time
+}
+
+func (completedBackfill) TableName() string {
+	return "completed_backfills"
+}
+
+const (
+	backfillReportMediaName      = "report_media"
+	backfillReportAttributesName = "report_attributes"
+)
+
+func backfillDone(db *gorm.DB, name string) (bool, error) {
+	var count int64
+	if err := db.Model(&completedBackfill{}).Where("name = ?", name).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func markBackfillDone(tx *gorm.DB, name string) error {
+	return tx.Create(&completedBackfill{Name: name, CompletedAt: time.Now()}).Error
+}
+
+// LegacyColumn is an incident report column a startup backfill copied from.
+// Unmatched counts the reports holding a value in it that the copy did not
+// carry over; it should be zero before the column is dropped.
+type LegacyColumn struct {
+	Name      string
+	Backfill  string
+	Unmatched int64
+}
+
+type legacyColumnCheck struct {
+	backfill string
+	// unmatched selects the reports whose value was not copied; %[1]s is
+	// the column name.
+	unmatched string
+}
+
+func legacyColumnChecks() map[string]legacyColumnCheck {
+	checks := make(map[string]legacyColumnCheck)
+	for _, column := range legacyMediaColumns {
+		checks[column] = legacyColumnCheck{
+			backfill: backfillReportMediaName,
+			unmatched: `COALESCE(%[1]s, '') <> '' AND NOT EXISTS (
+				SELECT 1 FROM media WHERE media.incident_report_id = incident_reports.id)`,
+		}
+	}
+	// Reports used to keep the reporter's profile image in thumbnail_urls; the
+	// backfill moved it to reporter_image without creating media
+	checks["thumbnail_urls"] = legacyColumnCheck{
+		backfill: backfillReportMediaName,
+		unmatched: `COALESCE(%[1]s, '') <> '' AND TRIM(%[1]s) IS DISTINCT FROM reporter_image AND NOT EXISTS (
+			SELECT 1 FROM media WHERE media.incident_report_id = incident_reports.id)`,
+	}
+	for column, attribute := range legacyAttributeColumns {
+		checks[column] = legacyColumnCheck{
+			backfill:  backfillReportAttributesName,
+			unmatched: `COALESCE(%[1]s, '') <> '' AND attributes->>'` + attribute + `' IS NULL`,
+		}
+	}
+	checks["no_water"] = legacyColumnCheck{
+		backfill:  backfillReportAttributesName,
+		unmatched: `%[1]s AND attributes->>'no_water' IS NULL`,
+	}
+	return checks
+}
+
+// GetLegacyColumns lists the legacy columns still present on incident
+// reports whose backfill has completed, with the reports each did not match.
+func GetLegacyColumns(g *GormDB) ([]LegacyColumn, error) {
+	migrator := g.DB.Migrator()
+	checks := legacyColumnChecks()
+	names := make([]string, 0, len(checks))
+	for name := range checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var columns []LegacyColumn
+	for _, name := range names {
+		check := checks[name]
+		if !migrator.HasColumn(&models.IncidentReport{}, name) {
+			continue
+		}
+		done, err := backfillDone(g.DB, check.backfill)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check backfill %s: %v", check.backfill, err)
+		}
+		if !done {
+			continue
+		}
+		column := LegacyColumn{Name: name, Backfill: check.backfill}
+		err = g.DB.Table("incident_reports").
+			Where(fmt.Sprintf(check.unmatched, name)).
+			Count(&column.Unmatched).Error
+		if err != nil {
+			return nil, fmt.Errorf("failed to verify %s: %v", name, err)
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+// DropLegacyColumns drops the given legacy columns in one transaction. It
+// refuses columns that still have unmatched reports.
+func DropLegacyColumns(g *GormDB, columns []LegacyColumn) error {
+	for _, column := range columns {
+		if column.Unmatched > 0 {
+			return fmt.Errorf("%s has %d unmatched reports", column.Name, column.Unmatched)
+		}
+	}
+	if len(columns) == 0 {
+		return errors.New("no legacy columns to drop")
+	}
+	return g.DB.Transaction(func(tx *gorm.DB) error {
+		for _, column := range columns {
+			if err := tx.Migrator().DropColumn(&models.IncidentReport{}, column.Name); err != nil {
+				return fmt.Errorf("failed to drop %s: %v", column.Name, err)
+			}
+		}
+		return nil
+	})
+}
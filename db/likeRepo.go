@@ -1,23 +1,20 @@
 package db
 
 import (
-	"fmt"
-	"log"
+	"errors"
 
-	"github.com/pkg/errors"
 	"github.com/techagentng/citizenx/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // LikeRepository interface
 type LikeRepository interface {
 	GetUserPoints(userID uint) (int, error)
 	UpdateUserPoints(userID uint, points int) error
-	RecordVote(userID uint, reportID string, voteType string) error
 	BeginTransaction() *gorm.DB
-	DownVoteReport(userID uint, reportID string) error
-	UpvoteReport(userID uint, reportID string) error
-	GetUpvoteAndDownvoteCounts(reportID string) (int, int, error)
+	SetVote(userID uint, reportID string, vote string, toggle bool, weight float64) (string, error)
+	GetVoteCounts(reportID string, userID uint) (*models.VoteCounts, error)
 }
 
 // likeRepo struct
@@ -30,49 +27,6 @@ func NewLikeRepo(db *GormDB) LikeRepository {
 	return &likeRepo{db.DB}
 }
 
-func (lk *likeRepo) UpvoteReport(userID uint, reportID string) error {
-	// Start a transaction to ensure consistency
-	tx := lk.DB.Begin()
-
-	// Check if the user has already upvoted this report
-	var existingVote models.Votes
-	if err := tx.Where("user_id = ? AND report_id = ? AND vote_type = ?", userID, reportID, "upvote").First(&existingVote).Error; err == nil {
-		// User has already upvoted, rollback and return
-		log.Println("User has already upvoted, rolling back transaction")
-		tx.Rollback()
-		return nil
-	}
-
-	// Record the upvote in the votes table
-	vote := models.Votes{
-		UserID:   userID,
-		ReportID: reportID,
-		VoteType: "upvote",
-	}
-	if err := tx.Create(&vote).Error; err != nil {
-		tx.Rollback() // Rollback transaction on error
-		return err
-	}
-
-	// Update the upvote count by querying the Votes table
-	var upvoteCount int64
-	if err := tx.Model(&models.Votes{}).Where("report_id = ? AND vote_type = ?", reportID, "upvote").Count(&upvoteCount).Error; err != nil {
-		log.Println("Failed to count upvotes, rolling back")
-		tx.Rollback()
-		return fmt.Errorf("failed to count upvotes: %w", err)
-	}
-
-	if err := tx.Model(&models.IncidentReport{}).Where("id = ?", reportID).Update("upvote_count", upvoteCount).Error; err != nil {
-		log.Println("Failed to update upvote count, rolling back")
-		tx.Rollback()
-		return fmt.Errorf("failed to update upvote count: %w", err)
-	}
-
-	log.Println("Committing transaction")
-	// Commit the transaction
-	return tx.Commit().Error
-}
-
 func (r *likeRepo) GetUserPoints(userID uint) (int, error) {
 	var userPoints models.UserPoints
 	if err := r.DB.Where("user_id = ?", userID).First(&userPoints).Error; err != nil {
@@ -85,66 +39,109 @@ func (r *likeRepo) UpdateUserPoints(userID uint, points int) error {
 	return r.DB.Model(&models.UserPoints{}).Where("user_id = ?", userID).Update("points", points).Error
 }
 
-func (r *likeRepo) RecordVote(userID uint, reportID string, voteType string) error {
-	vote := models.Votes{UserID: userID, ReportID: reportID, VoteType: voteType}
-	return r.DB.Create(&vote).Error
-}
-
 func (r *likeRepo) BeginTransaction() *gorm.DB {
 	return r.DB.Begin()
 }
 
-// DownVoteReport handles the logic for disliking a report with transaction management
-func (r *likeRepo) DownVoteReport(userID uint, reportID string) error {
-	log.Printf("DownVoteReport called: userID = %d, reportID = %s", userID, reportID)
-
-	// Start a transaction to ensure consistency
-	tx := r.DB.Begin()
-
-	// Check if the user has already downvoted this report
-	var existingVote models.Votes
-	if err := tx.Where("user_id = ? AND report_id = ? AND vote_type = ?", userID, reportID, "downvote").First(&existingVote).Error; err == nil {
-		log.Println("User has already downvoted, rolling back transaction")
-		tx.Rollback()
-		return errors.New("user has already downvoted")
+// SetVote moves the user's vote on the report to vote, which may be
+// models.VoteNone to retract it. With toggle, voting the way the user already
+// voted retracts the vote instead. The report's counters move by the
+// difference in the same transaction. It returns the user's vote afterwards,
+// and gorm.ErrRecordNotFound when the report does not exist.
+func (r *likeRepo) SetVote(userID uint, reportID string, vote string, toggle bool, weight float64) (string, error) {
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		var existing models.Votes
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND report_id = ?", userID, reportID).
+			Take(&existing).Error
+		found := err == nil
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		current := models.VoteNone
+		if found {
+			current = existing.VoteType
+		}
+		if toggle && current == vote {
+			vote = models.VoteNone
+		}
+		if current == vote {
+			return nil
+		}
+
+		counts := map[string]interface{}{}
+		if found {
+			moveVoteCounters(counts, existing.VoteType, -1, -existing.Weight)
+		}
+		moveVoteCounters(counts, vote, 1, weight)
+
+		switch {
+		case vote == models.VoteNone:
+			err = tx.Delete(&existing).Error
+		case found:
+			err = tx.Model(&existing).Updates(map[string]interface{}{"vote_type": vote, "weight": weight}).Error
+		default:
+			err = tx.Create(&models.Votes{UserID: userID, ReportID: reportID, VoteType: vote, Weight: weight}).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		result := tx.Model(&models.IncidentReport{}).Where("id = ?", reportID).Updates(counts)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
 	}
+	return vote, nil
+}
 
-	// Record the downvote in the votes table
-	vote := models.Votes{
-		UserID:   userID,
-		ReportID: reportID,
-		VoteType: "downvote",
-	}
-	if err := tx.Create(&vote).Error; err != nil {
-		log.Println("Failed to record downvote, rolling back")
-		tx.Rollback() // Rollback transaction on error
-		return err
+// moveVoteCounters adds the change for one vote to the counter updates.
+func moveVoteCounters(counts map[string]interface{}, vote string, count int, weight float64) {
+	switch vote {
+	case models.VoteUp:
+		counts["upvote_count"] = gorm.Expr("upvote_count + ?", count)
+		counts["weighted_upvotes"] = gorm.Expr("weighted_upvotes + ?", weight)
+	case models.VoteDown:
+		counts["downvote_count"] = gorm.Expr("downvote_count + ?", count)
+		counts["weighted_downvotes"] = gorm.Expr("weighted_downvotes + ?", weight)
 	}
+}
 
-	// Update the downvote count by querying the Votes table
-	var downvoteCount int64
-	if err := tx.Model(&models.Votes{}).Where("report_id = ? AND vote_type = ?", reportID, "downvote").Count(&downvoteCount).Error; err != nil {
-		log.Println("Failed to count downvotes, rolling back")
-		tx.Rollback()
-		return fmt.Errorf("failed to count downvotes: %w", err)
+// GetVoteCounts retrieves the report's vote counters and the user's own vote.
+// It returns nil when the report does not exist.
+func (r *likeRepo) GetVoteCounts(reportID string, userID uint) (*models.VoteCounts, error) {
+	var report models.IncidentReport
+	err := r.DB.Select("id, upvote_count, downvote_count, weighted_upvotes, weighted_downvotes").
+		Where("id = ?", reportID).
+		First(&report).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
 	}
-
-	if err := tx.Model(&models.IncidentReport{}).Where("id = ?", reportID).Update("downvote_count", downvoteCount).Error; err != nil {
-		log.Println("Failed to update downvote count, rolling back")
-		tx.Rollback()
-		return fmt.Errorf("failed to update downvote count: %w", err)
+	if err != nil {
+		return nil, err
 	}
 
-	log.Println("Committing transaction")
-	// Commit the transaction
-	return tx.Commit().Error
-}
-
-// GetUpvoteAndDownvoteCounts retrieves the upvote and downvote counts for a report.
-func (r *likeRepo) GetUpvoteAndDownvoteCounts(reportID string) (int, int, error) {
-	var report models.IncidentReport
-	if err := r.DB.Select("upvote_count, downvote_count").Where("id = ?", reportID).First(&report).Error; err != nil {
-		return 0, 0, err
+	counts := &models.VoteCounts{
+		Upvotes:           report.UpvoteCount,
+		Downvotes:         report.DownvoteCount,
+		WeightedUpvotes:   report.WeightedUpvotes,
+		WeightedDownvotes: report.WeightedDownvotes,
+		UserVote:          models.VoteNone,
+	}
+	var vote models.Votes
+	err = r.DB.Select("vote_type").Where("user_id = ? AND report_id = ?", userID, reportID).Take(&vote).Error
+	if err == nil {
+		counts.UserVote = vote.VoteType
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
 	}
-	return report.UpvoteCount, report.DownvoteCount, nil
+	return counts, nil
 }
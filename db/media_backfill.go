@@ -0,0 +1,205 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/techagentng/citizenx/models"
+	"gorm.io/gorm"
+)
+
+// legacyMediaColumns are the comma joined URL columns incident reports carried
+// before report media moved to the media table.
+var legacyMediaColumns = []string{"feed_urls", "thumbnail_urls", "full_size_urls", "video_url", "audio_url"}
+
+type legacyReportMedia struct {
+	ID            uuid.UUID
+	UserID        uint
+	FeedURLs      string
+	ThumbnailURLs string
+	FullSizeURLs  string
+	VideoURL      string
+	AudioURL      string
+}
+
+// backfillReportMedia turns the legacy URL columns of every report into media
+// rows and records that it ran, so it only does work once. URLs that already
+// have a media row for the report are skipped. The columns are left in place
+// until the copy is verified and they are dropped with cmd/drop-legacy-columns.
+func backfillReportMedia(db *gorm.DB) error {
+	done, err := backfillDone(db, backfillReportMediaName)
+	if err != nil || done {
+		return err
+	}
+	if !db.Migrator().HasColumn(&models.IncidentReport{}, "feed_urls") {
+		return markBackfillDone(db, backfillReportMediaName)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		var reports []legacyReportMedia
+		err := tx.Table("incident_reports").
+			Select(`id, user_id,
+				COALESCE(feed_urls, '') AS feed_urls,
+				COALESCE(thumbnail_urls, '') AS thumbnail_urls,
+				COALESCE(full_size_urls, '') AS full_size_urls,
+				COALESCE(video_url, '') AS video_url,
+				COALESCE(audio_url, '') AS audio_url`).
+			Scan(&reports).Error
+		if err != nil {
+			return fmt.Errorf("failed to read legacy media: %v", err)
+		}
+
+		created := 0
+		for _, report := range reports {
+			var existing []models.Media
+			if err := tx.Where("incident_report_id = ?", report.ID).Find(&existing).Error; err != nil {
+				return fmt.Errorf("failed to read media of report %s: %v", report.ID, err)
+			}
+			known := make(map[string]bool, len(existing))
+			for _, media := range existing {
+				known[media.FeedURL] = true
+			}
+
+			media, reporterImage := legacyMediaRows(report)
+			var rows []models.Media
+			for _, row := range media {
+				if known[row.FeedURL] {
+					continue
+				}
+				row.Position = len(rows)
+				rows = append(rows, row)
+			}
+			if len(rows) > 0 {
+				// Legacy media comes first, in the order it was stored
+				if len(existing) > 0 {
+					err := tx.Model(&models.Media{}).
+						Where("incident_report_id = ?", report.ID).
+						Update("position", gorm.Expr("position + ?", len(rows))).Error
+					if err != nil {
+						return fmt.Errorf("failed to reorder media of report %s: %v", report.ID, err)
+					}
+				}
+				if err := tx.Create(&rows).Error; err != nil {
+					return fmt.Errorf("failed to create media of report %s: %v", report.ID, err)
+				}
+				created += len(rows)
+			}
+			if reporterImage != "" {
+				err := tx.Model(&models.IncidentReport{}).
+					Where("id = ? AND COALESCE(reporter_image, '') = ''", report.ID).
+					Update("reporter_image", reporterImage).Error
+				if err != nil {
+					return fmt.Errorf("failed to set reporter image of report %s: %v", report.ID, err)
+				}
+			}
+		}
+
+		if err := markBackfillDone(tx, backfillReportMediaName); err != nil {
+			return fmt.Errorf("failed to record media backfill: %v", err)
+		}
+		log.Printf("Moved %d legacy report media URLs to the media table", created)
+		return nil
+	})
+}
+
+// legacyMediaRows builds media rows from a report's URL strings. Thumbnails
+// and full size images were appended per upload, so they are matched to the
+// last feed URLs that would have produced them; a leftover thumbnail in front
+// is the reporter's profile image, which reports used to store there.
+func legacyMediaRows(report legacyReportMedia) ([]models.Media, string) {
+	feeds := splitURLs(report.FeedURLs)
+	for _, extra := range []string{report.VideoURL, report.AudioURL} {
+		if extra != "" && !containsURL(feeds, extra) {
+			feeds = append(feeds, extra)
+		}
+	}
+
+	rows := make([]models.Media, 0, len(feeds))
+	var visual, images []int
+	for i, feed := range feeds {
+		fileType := legacyFileType(feed)
+		rows = append(rows, models.Media{
+			ID:               uuid.New().String(),
+			FileType:         fileType,
+			Filename:         path.Base(feed),
+			FeedURL:          feed,
+			UserID:           report.UserID,
+			IncidentReportID: report.ID,
+			ProcessingStatus: models.MediaStatusReady,
+		})
+		if fileType != "audio" {
+			visual = append(visual, i)
+		}
+		if fileType == "image" {
+			images = append(images, i)
+		}
+	}
+
+	var reporterImage string
+	thumbnails := splitURLs(report.ThumbnailURLs)
+	switch {
+	case len(thumbnails) >= len(visual):
+		if len(thumbnails) > len(visual) {
+			reporterImage = thumbnails[0]
+		}
+		thumbnails = thumbnails[len(thumbnails)-len(visual):]
+		for i, row := range visual {
+			rows[row].ThumbnailURL = thumbnails[i]
+		}
+	case len(thumbnails) == len(images):
+		for i, row := range images {
+			rows[row].ThumbnailURL = thumbnails[i]
+		}
+	}
+
+	if fullSize := splitURLs(report.FullSizeURLs); len(fullSize) >= len(images) {
+		fullSize = fullSize[len(fullSize)-len(images):]
+		for i, row := range images {
+			rows[row].FullSizeURL = fullSize[i]
+		}
+	}
+	return rows, reporterImage
+}
+
+// splitURLs reads a comma joined list; a few old rows hold a JSON array.
+func splitURLs(urls string) []string {
+	var out []string
+	if strings.HasPrefix(strings.TrimSpace(urls), "[") {
+		var list []string
+		if err := json.Unmarshal([]byte(urls), &list); err == nil {
+			urls = strings.Join(list, ",")
+		}
+	}
+	for _, u := range strings.Split(urls, ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			out = append(out, u)
+		}
+	}
+	return out
+}
+
+func containsURL(urls []string, u string) bool {
+	for _, existing := range urls {
+		if existing == u {
+			return true
+		}
+	}
+	return false
+}
+
+func legacyFileType(u string) string {
+	if i := strings.IndexByte(u, '?'); i >= 0 {
+		u = u[:i]
+	}
+	switch strings.ToLower(path.Ext(u)) {
+	case ".m3u8", ".mp4", ".mov", ".webm", ".3gp", ".mkv":
+		return "video"
+	case ".mp3", ".wav", ".ogg", ".m4a", ".aac", ".amr":
+		return "audio"
+	}
+	return "image"
+}
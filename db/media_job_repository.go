@@ -0,0 +1,156 @@
+package db
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/techagentng/citizenx/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrMediaJobLost is returned when a worker updates a job it no longer holds
+// because its lease ran out and the job was requeued.
+var ErrMediaJobLost = errors.New("media job claim lost")
+
+type MediaJobRepository interface {
+	EnqueueMediaJob(job *models.MediaJob) error
+	ClaimMediaJob() (*models.MediaJob, error)
+	CompleteMediaJob(job *models.MediaJob) error
+	RetryMediaJob(job *models.MediaJob, runAt time.Time, lastError string) error
+	DeadLetterMediaJob(job *models.MediaJob, lastError string) error
+	HeartbeatMediaJob(job *models.MediaJob) error
+	RequeueStaleMediaJobs(lockedBefore time.Time) (int64, error)
+	CountOpenMediaJobs(reportID string) (int64, error)
+}
+
+type mediaJobRepo struct {
+	DB *gorm.DB
+}
+
+func NewMediaJobRepo(db *GormDB) MediaJobRepository {
+	return &mediaJobRepo{db.DB}
+}
+
+func (m *mediaJobRepo) EnqueueMediaJob(job *models.MediaJob) error {
+	job.Status = models.MediaJobQueued
+	if job.RunAt.IsZero() {
+		job.RunAt = time.Now()
+	}
+	return m.DB.Create(job).Error
+}
+
+// ClaimMediaJob locks the next due job and marks it running. SKIP LOCKED lets
+// any number of workers, across instances, poll the table without handing the
+// same job out twice. It returns nil when nothing is due. Each claim counts an
+// attempt, so the attempt number identifies the claim in later updates.
+func (m *mediaJobRepo) ClaimMediaJob() (*models.MediaJob, error) {
+	var job models.MediaJob
+	err := m.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND run_at <= ?", models.MediaJobQueued, time.Now()).
+			Order("run_at ASC").
+			First(&job).Error
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		job.Status = models.MediaJobRunning
+		job.Attempts++
+		job.LockedAt = &now
+		return tx.Model(&job).Updates(map[string]interface{}{
+			"status":    job.Status,
+			"attempts":  job.Attempts,
+			"locked_at": now,
+		}).Error
+	})
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+// claimed scopes an update to the job while the caller's claim on it holds.
+func (m *mediaJobRepo) claimed(tx *gorm.DB, job *models.MediaJob) *gorm.DB {
+	return tx.Model(&models.MediaJob{}).
+		Where("id = ? AND status = ? AND attempts = ?", job.ID, models.MediaJobRunning, job.Attempts)
+}
+
+func claimHeld(result *gorm.DB) error {
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMediaJobLost
+	}
+	return nil
+}
+
+func (m *mediaJobRepo) CompleteMediaJob(job *models.MediaJob) error {
+	return claimHeld(m.claimed(m.DB, job).
+		Updates(map[string]interface{}{"status": models.MediaJobDone, "locked_at": nil, "last_error": ""}))
+}
+
+func (m *mediaJobRepo) RetryMediaJob(job *models.MediaJob, runAt time.Time, lastError string) error {
+	return claimHeld(m.claimed(m.DB, job).
+		Updates(map[string]interface{}{
+			"status":     models.MediaJobQueued,
+			"run_at":     runAt,
+			"locked_at":  nil,
+			"last_error": lastError,
+		}))
+}
+
+// DeadLetterMediaJob moves a job that has run out of attempts to the
+// dead_media_jobs table.
+func (m *mediaJobRepo) DeadLetterMediaJob(job *models.MediaJob, lastError string) error {
+	return m.DB.Transaction(func(tx *gorm.DB) error {
+		if err := claimHeld(m.claimed(tx, job).Update("status", models.MediaJobDone)); err != nil {
+			return err
+		}
+		dead := models.DeadMediaJob{
+			JobID:            job.ID,
+			MediaID:          job.MediaID,
+			IncidentReportID: job.IncidentReportID,
+			UserID:           job.UserID,
+			SourcePath:       job.SourcePath,
+			SourceKey:        job.SourceKey,
+			Filename:         job.Filename,
+			Attempts:         job.Attempts,
+			LastError:        lastError,
+			FailedAt:         time.Now(),
+		}
+		if err := tx.Create(&dead).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&models.MediaJob{}, job.ID).Error
+	})
+}
+
+// HeartbeatMediaJob refreshes the lock of a running job so it is not
+// requeued while its worker is still busy.
+func (m *mediaJobRepo) HeartbeatMediaJob(job *models.MediaJob) error {
+	return claimHeld(m.claimed(m.DB, job).Update("locked_at", time.Now()))
+}
+
+// RequeueStaleMediaJobs puts back jobs whose worker died while running them:
+// running jobs whose lock was not refreshed since lockedBefore.
+func (m *mediaJobRepo) RequeueStaleMediaJobs(lockedBefore time.Time) (int64, error) {
+	result := m.DB.Model(&models.MediaJob{}).
+		Where("status = ? AND locked_at < ?", models.MediaJobRunning, lockedBefore).
+		Updates(map[string]interface{}{"status": models.MediaJobQueued, "locked_at": nil})
+	return result.RowsAffected, result.Error
+}
+
+// CountOpenMediaJobs counts queued or running jobs for a report.
+func (m *mediaJobRepo) CountOpenMediaJobs(reportID string) (int64, error) {
+	var count int64
+	err := m.DB.Model(&models.MediaJob{}).
+		Where("incident_report_id = ? AND status IN ?", reportID, []string{models.MediaJobQueued, models.MediaJobRunning}).
+		Count(&count).Error
+	return count, err
+}
@@ -1,28 +1,25 @@
 package db
 
 import (
-	"fmt"
-	"image"
-	"image/jpeg"
-	"mime/multipart"
-	"os"
-	"strings"
-	"github.com/disintegration/imaging"
 	"github.com/google/uuid"
-	"github.com/nfnt/resize"
 	"github.com/pkg/errors"
 	"github.com/techagentng/citizenx/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type MediaRepository interface {
 	SaveMedia(media models.Media, reportID string, userID uint) error
+	FindNearDuplicateMedia(hash int64, maxDistance int) (*models.Media, error)
+	UpdateMedia(media *models.Media) error
+	UpdateMediaStatus(mediaID, status string) error
+	GetMediaByReportIDs(reportIDs []uuid.UUID) (map[uuid.UUID][]models.Media, error)
+	CountDuplicateMediaByFeedURLs(feedURLs []string) (int64, error)
+	GetFlaggedMedia(page int) ([]models.Media, error)
+	GetPrivateMediaURLs() ([]string, error)
 	RewardAndSavePoints(mediaCount int, report *models.IncidentReport) error
 	GetMediaCountByByUserID(userID uint) (int, error)
 	CreateMediaCount(mediaCount *models.MediaCount) error
-	UploadMediaToS3(file multipart.File, fileHeader *multipart.FileHeader, bucketName, folderName string) (string, error)
-	ProcessImageFile(mediaFile *os.File, userIDUint uint, reportIDStr string) (string, string, string, error)
-	saveImageToStorage(img image.Image, userIDUint uint, reportIDStr string, sizeType string) (string, error)
 }
 
 type mediaRepo struct {
@@ -39,20 +36,121 @@ func NewMediaRepo(db *GormDB) MediaRepository {
 }
 
 func (m *mediaRepo) SaveMedia(media models.Media, reportID string, userID uint) error {
-	ID := uuid.New()
-	media.ID = ID.String()
+	// Queued media gets its ID up front so the job can refer to it
+	if media.ID == "" {
+		media.ID = uuid.New().String()
+	}
 	media.UserID = userID
 
-	// Call the reward calculation and saving function
-	// err := m.RewardAndSavePoints(mediaCount, report)
-	// if err != nil {
-	// 	return err
-	// }
+	if media.IncidentReportID == uuid.Nil {
+		return m.DB.Create(&media).Error
+	}
 
-	if err := m.DB.Create(media).Error; err != nil {
-		return err
+	// New media goes after whatever the report already has. The report row is
+	// locked so concurrent uploads to it don't read the same position.
+	return m.DB.Transaction(func(tx *gorm.DB) error {
+		var report models.IncidentReport
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("id").
+			Where("id = ?", media.IncidentReportID).
+			Take(&report).Error
+		if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		var next int
+		err = tx.Model(&models.Media{}).
+			Where("incident_report_id = ?", media.IncidentReportID).
+			Select("COALESCE(MAX(position) + 1, 0)").
+			Scan(&next).Error
+		if err != nil {
+			return err
+		}
+		media.Position = next
+
+		return tx.Create(&media).Error
+	})
+}
+
+// UpdateMedia saves a processed media record, keeping its place in the report.
+func (m *mediaRepo) UpdateMedia(media *models.Media) error {
+	return m.DB.Omit("position", "created_at").Save(media).Error
+}
+
+func (m *mediaRepo) UpdateMediaStatus(mediaID, status string) error {
+	return m.DB.Model(&models.Media{}).Where("id = ?", mediaID).Update("processing_status", status).Error
+}
+
+// GetMediaByReportIDs returns the media of the given reports keyed by report
+// ID, each list in display order.
+func (m *mediaRepo) GetMediaByReportIDs(reportIDs []uuid.UUID) (map[uuid.UUID][]models.Media, error) {
+	byReport := make(map[uuid.UUID][]models.Media, len(reportIDs))
+	if len(reportIDs) == 0 {
+		return byReport, nil
 	}
-	return nil
+	var media []models.Media
+	err := m.DB.
+		Where("incident_report_id IN ?", reportIDs).
+		Where("processing_status <> ?", models.MediaStatusFailed).
+		Order("position ASC, created_at ASC").
+		Find(&media).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range media {
+		byReport[item.IncidentReportID] = append(byReport[item.IncidentReportID], item)
+	}
+	return byReport, nil
+}
+
+// GetFlaggedMedia returns media whose transcript matched a moderation keyword,
+// newest first.
+func (m *mediaRepo) GetFlaggedMedia(page int) ([]models.Media, error) {
+	var media []models.Media
+	err := m.DB.
+		Where("moderation_flags <> ''").
+		Order("created_at DESC").
+		Limit(DefaultPageSize).
+		Offset((page - 1) * DefaultPageSize).
+		Find(&media).Error
+	if err != nil {
+		return nil, err
+	}
+	return media, nil
+}
+
+// FindNearDuplicateMedia returns the earliest original image whose
+// perceptual hash is within maxDistance bits of hash, or nil when there is
+// none. The distance is computed for every hashed image, so the check grows
+// with the media table; at DuplicateHashDistance a band index would need 11
+// bands of under 6 bits, which narrows too little to be worth it.
+func (m *mediaRepo) FindNearDuplicateMedia(hash int64, maxDistance int) (*models.Media, error) {
+	var media models.Media
+	err := m.DB.
+		Where("perceptual_hash IS NOT NULL").
+		Where("length(replace(((perceptual_hash # ?)::bit(64))::text, '0', '')) <= ?", hash, maxDistance).
+		Order("is_duplicate ASC, created_at ASC").
+		First(&media).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &media, nil
+}
+
+// CountDuplicateMediaByFeedURLs counts how many of the given uploads were
+// flagged as near duplicates.
+func (m *mediaRepo) CountDuplicateMediaByFeedURLs(feedURLs []string) (int64, error) {
+	var count int64
+	if len(feedURLs) == 0 {
+		return 0, nil
+	}
+	err := m.DB.Model(&models.Media{}).
+		Where("feed_url IN ? AND is_duplicate = ?", feedURLs, true).
+		Count(&count).Error
+	return count, err
 }
 
 // func (m *mediaRepo) RewardAndSavePoints(mediaCount int, report *models.IncidentReport) error {
@@ -103,119 +201,22 @@ func (repo *mediaRepo) CreateMediaCount(mediaCount *models.MediaCount) error {
 	return nil
 }
 
-//	func (repo *mediaRepo) GetMediaCount() (models.MediaCount, error) {
-//	    // Fetch media count from the database
-//	    count, err := repo.mediaRepo.GetMediaCount()
-//	    if err != nil {
-//	        return MediaCount{}, err
-//	    }
-//	    return count, nil
-//	}
-func (repo *mediaRepo) UploadMediaToS3(file multipart.File, fileHeader *multipart.FileHeader, bucketName, folderName string) (string, error) {
-	defer file.Close()
-
-	// Sanitize the filename by replacing spaces with underscores
-	sanitizedFilename := strings.ReplaceAll(fileHeader.Filename, " ", "_")
-
-	// Generate a unique key for the file
-	key := fmt.Sprintf("%s/%s", folderName, sanitizedFilename)
-
-	// Create an S3 client
-	client, err := createS3Client()
-	if err != nil {
-		return "", fmt.Errorf("failed to create S3 client: %v", err)
-	}
-
-	// Upload the file to S3
-	fileURL, err := uploadFileToS3(client, file, bucketName, key)
-	if err != nil {
-		return "", fmt.Errorf("failed to upload file to S3: %v", err)
-	}
-
-	return fileURL, nil
-}
-
-func (repo *mediaRepo) ProcessImageFile(mediaFile *os.File, userIDUint uint, reportIDStr string) (string, string, string, error) {
-	// Step 1: Open the image file
-	img, _, err := image.Decode(mediaFile)
-	if err != nil {
-		return "", "", "", fmt.Errorf("failed to decode image: %v", err)
-	}
-
-	// Step 2: Resize the image for different versions
-	// Generate thumbnail (smaller version)
-	thumbnail := resize.Resize(150, 0, img, resize.Lanczos3) // Resize to 150px width, maintaining aspect ratio
-	// Generate feed-sized image (medium version)
-	feedImage := resize.Resize(600, 0, img, resize.Lanczos3) // Resize to 600px width, maintaining aspect ratio
-	// Generate full-size image (no resize for full-size)
-	fullsizeImage := img // Keep original size for full-size
-
-	// Step 3: Apply filters to enhance the image
-	feedImage = repo.applyImageEnhancements(feedImage)
-	thumbnail = repo.applyImageEnhancements(thumbnail)
-	fullsizeImage = repo.applyImageEnhancements(fullsizeImage)
-
-	// Step 4: Save the images to storage (local or cloud)
-	thumbnailURL, err := repo.saveImageToStorage(thumbnail, userIDUint, reportIDStr, "thumbnail")
-	if err != nil {
-		return "", "", "", fmt.Errorf("failed to save thumbnail: %v", err)
-	}
-
-	feedURL, err := repo.saveImageToStorage(feedImage, userIDUint, reportIDStr, "feed")
-	if err != nil {
-		return "", "", "", fmt.Errorf("failed to save feed image: %v", err)
-	}
-
-	fullsizeURL, err := repo.saveImageToStorage(fullsizeImage, userIDUint, reportIDStr, "fullsize")
-	if err != nil {
-		return "", "", "", fmt.Errorf("failed to save full-size image: %v", err)
-	}
-
-	// Step 5: Return the URLs for the different image versions
-	return feedURL, thumbnailURL, fullsizeURL, nil
-}
-
-// saveImageToStorage saves an image to storage and returns the URL for the saved image
-func (repo *mediaRepo) saveImageToStorage(img image.Image, userIDUint uint, reportIDStr string, sizeType string) (string, error) {
-	// Generate a filename for the image based on the user ID and report ID
-	filename := fmt.Sprintf("%d_%s_%s.jpg", userIDUint, reportIDStr, sizeType)
-
-	// Save the image to a local file or storage system (e.g., S3, GCP, etc.)
-	// Here we are saving it locally for simplicity
-	filePath := fmt.Sprintf("./uploads/%s", filename)
-
-	// Create the file on the filesystem
-	file, err := os.Create(filePath)
-	if err != nil {
-		return "", fmt.Errorf("failed to create file: %v", err)
-	}
-	defer file.Close()
-
-	// Save the image in JPEG format (you can change this to PNG or other formats if needed)
-	err = jpeg.Encode(file, img, nil)
+// GetPrivateMediaURLs returns the URL of every stored object that must not be
+// publicly readable: report media, follow-up media and official response
+// attachments.
+func (m *mediaRepo) GetPrivateMediaURLs() ([]string, error) {
+	var urls []string
+	err := m.DB.Raw(`SELECT url FROM (
+			SELECT feed_url AS url FROM media
+			UNION SELECT thumbnail_url FROM media
+			UNION SELECT full_size_url FROM media
+			UNION SELECT follow_media FROM follows
+			UNION SELECT jsonb_array_elements_text(media_urls) FROM official_responses
+				WHERE jsonb_typeof(media_urls) = 'array'
+		) u WHERE url IS NOT NULL AND url <> ''`).
+		Scan(&urls).Error
 	if err != nil {
-		return "", fmt.Errorf("failed to encode image: %v", err)
+		return nil, err
 	}
-
-	// Generate the URL (this would be different if you're using cloud storage)
-	// For example, if you're using local storage, you might serve the images via an HTTP server
-	url := fmt.Sprintf("https://yourcdn.com/uploads/%s", filename)
-
-	return url, nil
+	return urls, nil
 }
-// applyImageEnhancements applies blur, sharpness, brightness, and other effects to the image
-func (repo *mediaRepo) applyImageEnhancements(img image.Image) image.Image {
-	// Apply a slight blur to enhance the image quality and make it look softer
-	img = imaging.Blur(img, 2.0) // Apply a slight blur (the higher the number, the more blur)
-
-	// Apply brightness adjustment
-	img = imaging.AdjustBrightness(img, 10) // Increase brightness
-
-	// Apply contrast adjustment
-	img = imaging.AdjustContrast(img, 10) // Increase contrast
-
-	// Apply saturation adjustment
-	img = imaging.AdjustSaturation(img, 0.2) // Slightly increase saturation for a richer look
-
-	return img
-}
\ No newline at end of file
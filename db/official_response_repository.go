@@ -0,0 +1,56 @@
+package db
+
+import (
+	"github.com/google/uuid"
+	"github.com/techagentng/citizenx/models"
+	"gorm.io/gorm"
+)
+
+type OfficialResponseRepository interface {
+	CreateOfficialResponse(response *models.OfficialResponse, assignment *models.ReportAssignment) error
+	GetOfficialResponses(reportID uuid.UUID) ([]models.OfficialResponse, error)
+}
+
+type officialResponseRepo struct {
+	DB *gorm.DB
+}
+
+func NewOfficialResponseRepo(db *GormDB) OfficialResponseRepository {
+	return &officialResponseRepo{db.DB}
+}
+
+// CreateOfficialResponse stores the response and marks the report as
+// responded to, with the new resolution status when the response sets one.
+// The agency assignment the response moved, if any, is saved in the same
+// transaction.
+func (o *officialResponseRepo) CreateOfficialResponse(response *models.OfficialResponse, assignment *models.ReportAssignment) error {
+	return o.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(response).Error; err != nil {
+			return err
+		}
+		if assignment != nil {
+			if err := updateAssignment(tx, assignment); err != nil {
+				return err
+			}
+		}
+		updates := map[string]interface{}{"is_response": true, "admin_id": response.ResponderID}
+		if response.Status != "" {
+			updates["resolution_status"] = response.Status
+		}
+		return tx.Model(&models.IncidentReport{}).
+			Where("id = ?", response.IncidentReportID).
+			Updates(updates).Error
+	})
+}
+
+// GetOfficialResponses returns the responses on a report, oldest first.
+func (o *officialResponseRepo) GetOfficialResponses(reportID uuid.UUID) ([]models.OfficialResponse, error) {
+	var responses []models.OfficialResponse
+	err := o.DB.Where("incident_report_id = ?", reportID).
+		Order("created_at ASC, id ASC").
+		Find(&responses).Error
+	if err != nil {
+		return nil, err
+	}
+	return responses, nil
+}
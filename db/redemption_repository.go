@@ -0,0 +1,87 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/techagentng/citizenx/models"
+	"gorm.io/gorm"
+)
+
+type RedemptionRepository interface {
+	CreateRedemption(redemption *models.Redemption) error
+	GetRedemptionByID(id uint) (*models.Redemption, error)
+	GetUserRedemptions(userID uint, page int) ([]models.Redemption, error)
+	ListRedemptions(status string, page int) ([]models.Redemption, error)
+	UpdateRedemption(redemption *models.Redemption) error
+	TransitionRedemption(id uint, from, to string) (bool, error)
+}
+
+type redemptionRepo struct {
+	DB *gorm.DB
+}
+
+func NewRedemptionRepo(db *GormDB) RedemptionRepository {
+	return &redemptionRepo{db.DB}
+}
+
+func (r *redemptionRepo) CreateRedemption(redemption *models.Redemption) error {
+	return r.DB.Create(redemption).Error
+}
+
+// GetRedemptionByID returns the redemption, or nil when there is none.
+func (r *redemptionRepo) GetRedemptionByID(id uint) (*models.Redemption, error) {
+	var redemption models.Redemption
+	if err := r.DB.First(&redemption, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &redemption, nil
+}
+
+// GetUserRedemptions returns a page of the user's redemptions, newest first.
+func (r *redemptionRepo) GetUserRedemptions(userID uint, page int) ([]models.Redemption, error) {
+	var redemptions []models.Redemption
+	err := r.DB.Where("user_id = ?", userID).
+		Order("id DESC").
+		Limit(DefaultPageSize).
+		Offset((page - 1) * DefaultPageSize).
+		Find(&redemptions).Error
+	if err != nil {
+		return nil, err
+	}
+	return redemptions, nil
+}
+
+// ListRedemptions returns a page of redemptions, oldest first so the queue is
+// worked in order, optionally only those with the given status.
+func (r *redemptionRepo) ListRedemptions(status string, page int) ([]models.Redemption, error) {
+	var redemptions []models.Redemption
+	query := r.DB.Model(&models.Redemption{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Order("id ASC").
+		Limit(DefaultPageSize).
+		Offset((page - 1) * DefaultPageSize).
+		Find(&redemptions).Error
+	if err != nil {
+		return nil, err
+	}
+	return redemptions, nil
+}
+
+func (r *redemptionRepo) UpdateRedemption(redemption *models.Redemption) error {
+	return r.DB.Omit("created_at").Save(redemption).Error
+}
+
+// TransitionRedemption moves the redemption between statuses. It returns
+// false when the redemption was no longer in the from status, so two admins
+// cannot act on the same request.
+func (r *redemptionRepo) TransitionRedemption(id uint, from, to string) (bool, error) {
+	result := r.DB.Model(&models.Redemption{}).
+		Where("id = ? AND status = ?", id, from).
+		Update("status", to)
+	return result.RowsAffected > 0, result.Error
+}
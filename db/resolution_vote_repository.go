@@ -0,0 +1,109 @@
+package db
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/techagentng/citizenx/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type ResolutionVoteRepository interface {
+	SaveVote(vote *models.ResolutionVote) error
+	GetTally(reportID uuid.UUID, round int) (*models.ResolutionTally, error)
+	ReopenReport(reportID uuid.UUID, round int, resolveDue *time.Time, note string) (bool, error)
+	GetConfirmationMetrics(groupBy, category, state string) ([]models.ConfirmationMetric, error)
+}
+
+type resolutionVoteRepo struct {
+	DB *gorm.DB
+}
+
+func NewResolutionVoteRepo(db *GormDB) ResolutionVoteRepository {
+	return &resolutionVoteRepo{db.DB}
+}
+
+// SaveVote records the vote, replacing the user's earlier vote in the same
+// round.
+func (r *resolutionVoteRepo) SaveVote(vote *models.ResolutionVote) error {
+	return r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "incident_report_id"}, {Name: "user_id"}, {Name: "round"}},
+		DoUpdates: clause.AssignmentColumns([]string{"fixed", "updated_at"}),
+	}).Create(vote).Error
+}
+
+func (r *resolutionVoteRepo) GetTally(reportID uuid.UUID, round int) (*models.ResolutionTally, error) {
+	tally := models.ResolutionTally{Round: round}
+	err := r.DB.Model(&models.ResolutionVote{}).
+		Select("COUNT(*) FILTER (WHERE fixed) AS fixed, COUNT(*) FILTER (WHERE NOT fixed) AS still_broken").
+		Where("incident_report_id = ? AND round = ?", reportID, round).
+		Scan(&tally).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tally, nil
+}
+
+// ReopenReport moves a resolved report into a new round and puts its resolved
+// assignments back in progress with a fresh resolve target. It returns false
+// when the report was already reopened or is no longer resolved.
+func (r *resolutionVoteRepo) ReopenReport(reportID uuid.UUID, round int, resolveDue *time.Time, note string) (bool, error) {
+	reopened := false
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.IncidentReport{}).
+			Where("id = ? AND resolution_round = ? AND resolution_status = ?", reportID, round, models.AssignmentStatusResolved).
+			Updates(map[string]interface{}{
+				"resolution_status": models.ResolutionStatusReopened,
+				"resolution_round":  round + 1,
+			})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		reopened = true
+		return tx.Model(&models.ReportAssignment{}).
+			Where("incident_report_id = ? AND status = ?", reportID, models.AssignmentStatusResolved).
+			Updates(map[string]interface{}{
+				"status":               models.AssignmentStatusInProgress,
+				"note":                 note,
+				"resolved_at":          nil,
+				"resolve_due_at":       resolveDue,
+				"resolve_escalated_at": nil,
+			}).Error
+	})
+	return reopened, err
+}
+
+// GetConfirmationMetrics summarises resolution votes grouped by the agencies
+// the reports were assigned to, or by state. Empty filters are ignored.
+func (r *resolutionVoteRepo) GetConfirmationMetrics(groupBy, category, state string) ([]models.ConfirmationMetric, error) {
+	group := "incident_reports.state_name"
+	query := r.DB.Table("resolution_votes").
+		Joins("JOIN incident_reports ON incident_reports.id = resolution_votes.incident_report_id")
+	if groupBy == "agency" {
+		group = "agencies.name"
+		query = query.
+			Joins("JOIN report_assignments ON report_assignments.incident_report_id = resolution_votes.incident_report_id").
+			Joins("JOIN agencies ON agencies.id = report_assignments.agency_id")
+	}
+	if category != "" {
+		query = query.Where("incident_reports.category = ?", category)
+	}
+	if state != "" {
+		query = query.Where("incident_reports.state_name = ?", state)
+	}
+
+	var metrics []models.ConfirmationMetric
+	err := query.Select(group + ` AS "group",
+			COUNT(*) AS votes,
+			COUNT(*) FILTER (WHERE resolution_votes.fixed) AS fixed,
+			COUNT(*) FILTER (WHERE NOT resolution_votes.fixed) AS still_broken,
+			COUNT(*) FILTER (WHERE resolution_votes.fixed) * 100.0 / COUNT(*) AS confirmation_rate`).
+		Group(group).
+		Order("votes DESC").
+		Scan(&metrics).Error
+	if err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
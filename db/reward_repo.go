@@ -13,7 +13,6 @@ type RewardRepository interface {
 	// BuyPoints(userID uint, amount float64) error
 	// RewardForReport(report models.IncidentReport) error
 	GetRewardsByUserID(userID uint) (*models.Reward, error)
-	SaveReward(reward *models.Reward) error
 	GetReportByID(reportID string) (*models.IncidentReport, error)
 	GetCurrentRewardByUserID(userID uint) (int, error)
 	GetRewardPointByReportID(reportID string) (int, error)
@@ -40,23 +39,6 @@ func (r *rewardRepo) GetRewardsByUserID(userID uint) (*models.Reward, error) {
 	return &reward, nil
 }
 
-func (repo *rewardRepo) SaveReward(reward *models.Reward) error {
-	var existingReward models.Reward
-	err := repo.DB.Where("user_id = ? AND incident_report_id = ?", reward.UserID, reward.IncidentReportID).First(&existingReward).Error
-	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			// Create new reward entry
-			return repo.DB.Create(reward).Error
-		}
-		return err
-	}
-
-	// Update existing reward
-	existingReward.Point += reward.Point
-	existingReward.Balance += reward.Point
-	return repo.DB.Save(&existingReward).Error
-}
-
 func (r *rewardRepo) GetReportByID(reportID string) (*models.IncidentReport, error) {
 	var report models.IncidentReport
 	if err := r.DB.Where("id = ?", reportID).First(&report).Error; err != nil {
@@ -124,33 +106,6 @@ func (r *rewardRepo) GetAllRewards() ([]models.Reward, error) {
 	return rewards, nil
 }
 
-func (r *rewardRepo) GetUserReward(userID uint) (models.Reward, error) {
-	var reward models.Reward
-	err := r.DB.Where("user_id = ?", userID).First(&reward).Error
-
-	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			// Record not found, initialize a new reward record with default values
-			reward = models.Reward{
-				UserID:        userID,
-				Point:         0,         // Default points
-				Balance:       0,         // Default balance
-				RewardType:    "default", // Set a default reward type or adjust as needed
-				AccountNumber: "",        // Default or empty account number
-			}
-			// Create a new record
-			err = r.DB.Create(&reward).Error
-			if err != nil {
-				return reward, err
-			}
-		} else {
-			return reward, err
-		}
-	}
-
-	return reward, nil
-}
-
 func (r *rewardRepo) SumRewardBalanceByUserID(userID uint) (int, error) {
 	var totalBalance int
 	err := r.DB.Model(&models.Reward{}).Where("user_id = ?", userID).Select("SUM(balance)").Scan(&totalBalance).Error
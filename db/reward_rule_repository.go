@@ -0,0 +1,93 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/techagentng/citizenx/models"
+	"gorm.io/gorm"
+)
+
+type RewardRuleRepository interface {
+	ListRules() ([]models.RewardRule, error)
+	GetRulesForAction(action string, categoryID uint) ([]models.RewardRule, error)
+	GetRuleByID(id uint) (*models.RewardRule, error)
+	CreateRule(rule *models.RewardRule) error
+	UpdateRule(rule *models.RewardRule) error
+	DeleteRule(id uint) error
+}
+
+type rewardRuleRepo struct {
+	DB *gorm.DB
+}
+
+func NewRewardRuleRepo(db *GormDB) RewardRuleRepository {
+	return &rewardRuleRepo{db.DB}
+}
+
+func (r *rewardRuleRepo) ListRules() ([]models.RewardRule, error) {
+	var rules []models.RewardRule
+	if err := r.DB.Order("action ASC, id ASC").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// GetRulesForAction returns the active rules for the action that apply to all
+// categories or to the given one. Campaign windows are left to the caller.
+func (r *rewardRuleRepo) GetRulesForAction(action string, categoryID uint) ([]models.RewardRule, error) {
+	var rules []models.RewardRule
+	err := r.DB.Where("action = ? AND active = ?", action, true).
+		Where("category_id IS NULL OR category_id = ?", categoryID).
+		Order("id ASC").
+		Find(&rules).Error
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// GetRuleByID returns the rule, or nil when there is none.
+func (r *rewardRuleRepo) GetRuleByID(id uint) (*models.RewardRule, error) {
+	var rule models.RewardRule
+	if err := r.DB.First(&rule, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &rule, nil
+}
+
+func (r *rewardRuleRepo) CreateRule(rule *models.RewardRule) error {
+	return r.DB.Create(rule).Error
+}
+
+func (r *rewardRuleRepo) UpdateRule(rule *models.RewardRule) error {
+	return r.DB.Omit("created_at").Save(rule).Error
+}
+
+// DeleteRule removes the rule. Ledger transactions it paid keep its ID.
+func (r *rewardRuleRepo) DeleteRule(id uint) error {
+	return r.DB.Delete(&models.RewardRule{}, id).Error
+}
+
+// seedRewardRules creates rules paying what reports and media earned before
+// rules were configurable, plus the bonus new users got for a first report.
+func seedRewardRules(db *gorm.DB) error {
+	var count int64
+	if err := db.Model(&models.RewardRule{}).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return nil
+	}
+
+	rules := []models.RewardRule{
+		{Name: "Report description", Action: models.RewardActionDescription, Points: 10, Active: true},
+		{Name: "Report location", Action: models.RewardActionLocation, Points: 10, Active: true},
+		{Name: "Report media", Action: models.RewardActionMediaItem, Points: 10, Active: true},
+		{Name: "First report bonus", Action: models.RewardActionReportSubmitted, Points: 10, FirstReportOnly: true, Active: true},
+		{Name: "Approval bonus", Action: models.RewardActionReportApproved, Points: 1, Active: true},
+	}
+	return db.Create(&rules).Error
+}
@@ -0,0 +1,107 @@
+package db
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/techagentng/citizenx/models"
+	"gorm.io/gorm"
+)
+
+type UploadRepository interface {
+	CreateUploadSession(session *models.UploadSession) error
+	GetUploadSession(id string, userID uint) (*models.UploadSession, error)
+	AdvanceUploadSession(id string, chunk int, offset int64) (bool, error)
+	MarkUploadSessionCompleted(id string) (bool, error)
+	ReopenUploadSession(id string) error
+	CountOpenUploadSessions(userID uint, now time.Time) (int64, error)
+	GetExpiredUploadSessions(now time.Time, limit int) ([]models.UploadSession, error)
+	DeleteUploadSession(id string) error
+}
+
+type uploadRepo struct {
+	DB *gorm.DB
+}
+
+func NewUploadRepo(db *GormDB) UploadRepository {
+	return &uploadRepo{db.DB}
+}
+
+func (u *uploadRepo) CreateUploadSession(session *models.UploadSession) error {
+	return u.DB.Create(session).Error
+}
+
+// GetUploadSession returns the session only if it belongs to the user, or nil
+// when there is no such session.
+func (u *uploadRepo) GetUploadSession(id string, userID uint) (*models.UploadSession, error) {
+	var session models.UploadSession
+	err := u.DB.Where("id = ? AND user_id = ?", id, userID).First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// AdvanceUploadSession records that chunk was received and moves the offset on.
+// It only succeeds if chunk is still the one expected, so two concurrent
+// retries of the same chunk cannot both advance the session.
+func (u *uploadRepo) AdvanceUploadSession(id string, chunk int, offset int64) (bool, error) {
+	result := u.DB.Model(&models.UploadSession{}).
+		Where("id = ? AND next_chunk = ? AND status = ?", id, chunk, models.UploadStatusPending).
+		Updates(map[string]interface{}{"next_chunk": chunk + 1, "offset": offset})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// MarkUploadSessionCompleted completes a pending session. It reports false
+// when the session was no longer pending, so only one caller completes it.
+func (u *uploadRepo) MarkUploadSessionCompleted(id string) (bool, error) {
+	result := u.DB.Model(&models.UploadSession{}).
+		Where("id = ? AND status = ?", id, models.UploadStatusPending).
+		Update("status", models.UploadStatusCompleted)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+// ReopenUploadSession puts a completed session back to pending, for when
+// queueing its file failed.
+func (u *uploadRepo) ReopenUploadSession(id string) error {
+	return u.DB.Model(&models.UploadSession{}).
+		Where("id = ? AND status = ?", id, models.UploadStatusCompleted).
+		Update("status", models.UploadStatusPending).Error
+}
+
+// CountOpenUploadSessions counts the user's uploads that are still pending and
+// not expired.
+func (u *uploadRepo) CountOpenUploadSessions(userID uint, now time.Time) (int64, error) {
+	var count int64
+	err := u.DB.Model(&models.UploadSession{}).
+		Where("user_id = ? AND status = ? AND expires_at > ?", userID, models.UploadStatusPending, now).
+		Count(&count).Error
+	return count, err
+}
+
+// GetExpiredUploadSessions returns up to limit sessions past their expiry,
+// completed or not.
+func (u *uploadRepo) GetExpiredUploadSessions(now time.Time, limit int) ([]models.UploadSession, error) {
+	var sessions []models.UploadSession
+	err := u.DB.Where("expires_at < ?", now).
+		Order("expires_at ASC").
+		Limit(limit).
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (u *uploadRepo) DeleteUploadSession(id string) error {
+	return u.DB.Delete(&models.UploadSession{}, "id = ?", id).Error
+}
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"gorm.io/gorm"
+)
+
+// backfillVotes brings the votes table to one vote per user and report. Users
+// could once both upvote and downvote a report; their latest vote is kept.
+// The report counters are then recounted and the unique index created, in
+// one transaction that only runs while the index is missing.
+func backfillVotes(db *gorm.DB) error {
+	var exists bool
+	if err := db.Raw("SELECT to_regclass('idx_votes_user_report') IS NOT NULL").Scan(&exists).Error; err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Exec(`DELETE FROM votes a USING votes b
+			WHERE a.user_id = b.user_id AND a.report_id = b.report_id AND a.id < b.id`).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Exec(`UPDATE incident_reports r SET
+				upvote_count = v.upvotes,
+				downvote_count = v.downvotes,
+				weighted_upvotes = v.weighted_upvotes,
+				weighted_downvotes = v.weighted_downvotes
+			FROM (
+				SELECT report_id,
+					COUNT(*) FILTER (WHERE vote_type = 'upvote') AS upvotes,
+					COUNT(*) FILTER (WHERE vote_type = 'downvote') AS downvotes,
+					COALESCE(SUM(weight) FILTER (WHERE vote_type = 'upvote'), 0) AS weighted_upvotes,
+					COALESCE(SUM(weight) FILTER (WHERE vote_type = 'downvote'), 0) AS weighted_downvotes
+				FROM votes GROUP BY report_id
+			) v
+			WHERE r.id::text = v.report_id`).Error
+		if err != nil {
+			return err
+		}
+		return tx.Exec("CREATE UNIQUE INDEX idx_votes_user_report ON votes (user_id, report_id)").Error
+	})
+}
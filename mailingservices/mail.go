@@ -15,6 +15,8 @@ type Mailer interface {
 	SendWelcomeMessage(userEmail, link string) (string, error)
 	SendVerifyAccount(userEmail, link string) (string, error)
 	SendResetPassword(userEmail, link string) (string, error)
+	SendReportAssignment(agencyEmail, agencyName, category, link string) (string, error)
+	SendSLAEscalation(email, agencyName, stage, link string) (string, error)
 }
 
 func (mail *Mailgun) Init() {
@@ -94,3 +96,48 @@ func (mail *Mailgun) SendResetPassword(userEmail, live string) (string, error) {
 
     return res, nil
 }
+
+// SendReportAssignment tells an agency that a report was routed to it.
+func (mail *Mailgun) SendReportAssignment(agencyEmail, agencyName, category, link string) (string, error) {
+	EmailFrom := os.Getenv("MG_EMAIL_FROM")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	m := mail.Client.NewMessage(EmailFrom, "New "+category+" report assigned to "+agencyName, "")
+	m.SetTemplate("report.assignment")
+	if err := m.AddRecipient(agencyEmail); err != nil {
+		return "", err
+	}
+	for name, value := range map[string]string{"agency": agencyName, "category": category, "link": link} {
+		if err := m.AddVariable(name, value); err != nil {
+			return "", err
+		}
+	}
+
+	res, _, err := mail.Client.Send(ctx, m)
+	return res, err
+}
+
+// SendSLAEscalation tells a senior official that an agency missed the target
+// to acknowledge or resolve a report.
+func (mail *Mailgun) SendSLAEscalation(email, agencyName, stage, link string) (string, error) {
+	EmailFrom := os.Getenv("MG_EMAIL_FROM")
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+
+	m := mail.Client.NewMessage(EmailFrom, agencyName+" missed the deadline to "+stage+" a report", "")
+	m.SetTemplate("sla.escalation")
+	if err := m.AddRecipient(email); err != nil {
+		return "", err
+	}
+	for name, value := range map[string]string{"agency": agencyName, "stage": stage, "link": link} {
+		if err := m.AddVariable(name, value); err != nil {
+			return "", err
+		}
+	}
+
+	res, _, err := mail.Client.Send(ctx, m)
+	return res, err
+}
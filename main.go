@@ -2,12 +2,14 @@ package main
 
 import (
 	"log"
+	"strings"
 
 	"github.com/techagentng/citizenx/config"
 	"github.com/techagentng/citizenx/db"
 	"github.com/techagentng/citizenx/mailingservices"
 	"github.com/techagentng/citizenx/server"
 	"github.com/techagentng/citizenx/services"
+	"github.com/techagentng/citizenx/storage"
 	"github.com/go-redis/redis/v8"
 )
 
@@ -29,6 +31,12 @@ func main() {
 	mailgunClient := &mailingservices.Mailgun{}
 	mailgunClient.Init()
 
+	// Initialize blob storage (S3 or local disk, per config)
+	blobStore, err := storage.New(conf)
+	if err != nil {
+		log.Fatalf("error initializing storage: %v", err)
+	}
+
 	// Initialize database
 	gormDB := db.GetDB(conf)
 
@@ -44,15 +52,47 @@ func main() {
 	rewardRepo := db.NewRewardRepo(gormDB)
 	likeRepo := db.NewLikeRepo(gormDB)
 	postRepo := db.NewPostRepo(gormDB)
+	uploadRepo := db.NewUploadRepo(gormDB)
+	mediaJobRepo := db.NewMediaJobRepo(gormDB)
+	categoryRepo := db.NewCategoryRepo(gormDB)
+	agencyRepo := db.NewAgencyRepo(gormDB)
+	officialResponseRepo := db.NewOfficialResponseRepo(gormDB)
+	resolutionVoteRepo := db.NewResolutionVoteRepo(gormDB)
+	ledgerRepo := db.NewLedgerRepo(gormDB)
+	rewardRuleRepo := db.NewRewardRuleRepo(gormDB)
+	redemptionRepo := db.NewRedemptionRepo(gormDB)
+	leaderboardRepo := db.NewLeaderboardRepo(gormDB)
+	badgeRepo := db.NewBadgeRepo(gormDB)
+	feedRepo := db.NewFeedRepo(gormDB)
+	engagementRepo := db.NewEngagementRepo(gormDB)
+	bookmarkRepo := db.NewBookmarkRepo(gormDB)
 
 	// Services
+	leaderboardService := services.NewLeaderboardService(leaderboardRepo, redisClient)
+	ledgerService := services.NewLedgerService(ledgerRepo, leaderboardService)
+	notificationService := services.NewNotificationService()
+	badgeService := services.NewBadgeService(badgeRepo, incidentReportRepo, leaderboardService, notificationService)
+	rewardEngine := services.NewRewardEngine(rewardRuleRepo, categoryRepo, ledgerRepo, ledgerService)
+	payoutProvider, err := services.NewPayoutProvider(conf)
+	if err != nil {
+		log.Fatalf("error initializing payout provider: %v", err)
+	}
+	redemptionService := services.NewRedemptionService(redemptionRepo, ledgerService, payoutProvider, conf)
 	authService := services.NewAuthService(authRepo, conf)
-	mediaService := services.NewMediaService(mediaRepo, rewardRepo, incidentReportRepo, conf)
-	incidentReportService := services.NewIncidentReportService(incidentReportRepo, rewardRepo, mediaRepo, conf, gormDB.DB)
-	rewardService := services.NewRewardService(rewardRepo, incidentReportRepo, conf)
-	likeService := services.NewLikeService(likeRepo, conf)
+	mediaService := services.NewMediaService(mediaRepo, incidentReportRepo, blobStore, services.NewTranscriber(conf), conf)
+	incidentReportService := services.NewIncidentReportService(incidentReportRepo, rewardEngine, badgeService, mediaRepo, conf, gormDB.DB)
+	rewardService := services.NewRewardService(incidentReportRepo, ledgerService, rewardEngine, badgeService, conf)
+	likeService := services.NewLikeService(likeRepo, leaderboardService, badgeService, conf)
 	postService := services.NewPostService(postRepo, conf)
-	notificationService := services.NewNotificationService()
+	mediaJobQueue := services.NewMediaJobQueue(mediaJobRepo, mediaRepo, incidentReportRepo, mediaService, blobStore, notificationService, conf)
+	uploadService := services.NewUploadService(uploadRepo, incidentReportRepo, mediaJobQueue, blobStore, conf)
+	categoryService := services.NewCategoryService(categoryRepo, conf)
+	agencyService := services.NewAgencyService(agencyRepo, categoryRepo, authRepo, mailgunClient, conf)
+	officialResponseService := services.NewOfficialResponseService(officialResponseRepo, incidentReportRepo, agencyRepo, notificationService)
+	feedService := services.NewFeedService(feedRepo, redisClient, conf)
+	engagementService := services.NewEngagementService(engagementRepo, redisClient, conf)
+	bookmarkService := services.NewBookmarkService(bookmarkRepo)
+	resolutionService := services.NewResolutionService(resolutionVoteRepo, incidentReportRepo, categoryRepo, conf)
 
 	// Server setup
 	s := &server.Server{
@@ -62,6 +102,10 @@ func main() {
 		AuthService:              authService,
 		MediaRepository:          mediaRepo,
 		MediaService:             mediaService,
+		UploadService:            uploadService,
+		MediaJobQueue:            mediaJobQueue,
+		Storage:                  blobStore,
+		URLSigner:                storage.NewURLSigner(blobStore, storage.DefaultSignedURLTTL).WithPlaylistEndpoint(strings.TrimRight(conf.BaseUrl, "/")+server.PlaylistPath, conf.JWTSecret),
 		IncidentReportService:    incidentReportService,
 		IncidentReportRepository: incidentReportRepo,
 		RewardService:            rewardService,
@@ -69,6 +113,19 @@ func main() {
 		LikeService:              likeService,
 		PostService:              postService,
 		PostRepository:           postRepo,
+		CategoryService:          categoryService,
+		AgencyService:            agencyService,
+		SLAMonitor:               services.NewSLAMonitor(agencyRepo, mailgunClient, conf),
+		OfficialResponseService:  officialResponseService,
+		ResolutionService:        resolutionService,
+		LedgerService:            ledgerService,
+		RewardEngine:             rewardEngine,
+		RedemptionService:        redemptionService,
+		LeaderboardService:       leaderboardService,
+		BadgeService:             badgeService,
+		FeedService:              feedService,
+		EngagementService:        engagementService,
+		BookmarkService:          bookmarkService,
 		NotificationService:      notificationService,
 		DB: gormDB.DB,
 		RedisClient:              redisClient,
@@ -12,12 +12,10 @@ type IncidentReport struct {
 	UserFullname         string     `json:"fullname"`
 	DateOfIncidence      string     `json:"date_of_incidence"`
 	Description          string     `json:"description" gorm:"type:varchar(1000)"`
-	FeedURLs             string     `json:"feed_urls"`
-	VideoURL             string     `json:"video_url"`
-	AudioURL             string     `json:"audio_url"`
-	ThumbnailURLs        string     `json:"thumbnail_urls"`
-	FullSizeURLs         string     `json:"full_size_urls"`
-	ProductName          string     `json:"product_name"`
+	// Media is loaded from the media table by the handlers that return reports
+	Media []Media `json:"media" gorm:"-"`
+	// ReporterImage is the reporter's profile image when the report was made
+	ReporterImage        string     `json:"reporter_image"`
 	StateName            string     `json:"state_name"`
 	LGAName              string     `json:"lga_name"`
 	Latitude             float64    `json:"latitude"`
@@ -27,47 +25,49 @@ type IncidentReport struct {
 	UserUsername         string     `json:"username"`
 	Telephone            string     `json:"telephone"`
 	Email                string     `json:"email"`
+	// View counts views, deduplicated per viewer within a window
 	View                 int        `json:"view"`
+	ShareCount           int        `json:"share_count" gorm:"default:0"`
 	IsVerified           bool       `json:"is_verified"`
 	UserID               uint       `json:"user_id"`
 	AdminID              uint       `json:"is_admin"`
 	Landmark             string     `json:"landmark"`
 	LikeCount            int        `json:"like_count"`
-	BookmarkedReports    []*User    `gorm:"many2many:incident_report_user;" json:"bookmarked_reports"`
+	// IsResponse is set once an official has responded; AdminID is the last
+	// responder and ResolutionStatus what they reported
 	IsResponse           bool       `json:"is_response"`
+	ResolutionStatus     string     `json:"resolution_status"`
+	// ResolutionRound counts how often citizens reopened the report
+	ResolutionRound      int        `json:"resolution_round"`
 	TimeofIncidence      time.Time  `json:"time_of_incidence"`
 	ReportStatus         string     `json:"report_status"`
+	// ApprovalRound counts how often an approval was rejected, so approving
+	// the report again pays again
+	ApprovalRound        int        `json:"-" gorm:"default:0"`
 	BlockRequest         string     `json:"block_request"`
 	RewardPoint          int        `json:"reward_point"`
 	RewardAccountNumber  string     `json:"reward_account_number"`
 	ActionTypeName       string     `json:"action_type_name"`
 	IsState              bool       `json:"is_state"`
 	Rating               string     `json:"rating"`
-	HospitalName         string     `json:"hospital_name"`
-	Department           string     `json:"department"`
-	DepartmentHeadName   string     `json:"department_head_name"`
-	AccidentCause        string     `json:"accident_cause"`
-	SchoolName           string     `json:"school_name"`
-	VicePrincipal        string     `json:"vice_principal"`
-	OutageLength         string     `json:"outage_length"`
-	AirportName          string     `json:"airport_name"`
-	Country              string     `json:"country"`
-	StateEmbassyLocation string     `json:"state_embassy_location"`
-	NoWater              bool       `json:"no_water"`
-	AmbassedorsName      string     `json:"ambassedors_name"`
-	HospitalAddress      string     `json:"hospital_address"`
-	RoadName             string     `json:"road_name"`
-	AirlineName          string     `json:"airline_name"`
 	Category             string     `json:"category"`
-	Terminal             string     `json:"terminal"`
-	QueueTime            string     `json:"queue_time"`
+	// CategoryID and SubCategoryID point into the admin managed taxonomy
+	CategoryID           uint       `json:"category_id" gorm:"index"`
+	SubCategoryID        *uint      `json:"sub_category_id"`
+	// Attributes holds the fields declared by the category's form schema
+	Attributes           Attributes `json:"attributes" gorm:"type:jsonb"`
 	SubReportType        string     `json:"sub_report_type"`
 	UpvoteCount          int        `json:"upvote_count" gorm:"default:0"`
 	DownvoteCount        int        `json:"downvote_count" gorm:"default:0"`
+	// WeightedUpvotes and WeightedDownvotes sum the voters' reputation weights
+	WeightedUpvotes      float64    `json:"weighted_upvotes" gorm:"default:0"`
+	WeightedDownvotes    float64    `json:"weighted_downvotes" gorm:"default:0"`
 	ReportTypeID         uuid.UUID  `json:"report_type_id"`
 	ReportType           ReportType `gorm:"foreignKey:ReportTypeID;constraint:OnUpdate:CASCADE,OnDelete:SET NULL"`
 	Followers []*User `gorm:"many2many:follows;joinForeignKey:ReportID;joinReferences:UserID" json:"followers"`
 	IsAnonymous bool `json:"is_anonymous" gorm:"column:is_anonymous"`
+	// IsBookmarked is whether the user the report is shown to bookmarked it
+	IsBookmarked bool `json:"is_bookmarked" gorm:"-"`
 }
 
 type ReportCount struct {
@@ -76,17 +76,6 @@ type ReportCount struct {
 	Count     int
 }
 
-// IncidentReportUser defines the relationship between users and incident reports (i.e., bookmarks)
-
-type IncidentReportUser struct {
-	ID               uint      `gorm:"primaryKey;autoIncrement"`
-	UserID           uint      `gorm:"not null"`
-	IncidentReportID string    `gorm:"not null;type:varchar(36);index"`
-	CreatedAt        time.Time `gorm:"autoCreateTime"`
-	UpdatedAt        time.Time `gorm:"autoUpdateTime"`
-	User             User      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
-}
-
 type Actions struct {
 	Model
 	ActionType string `json:"action_type"`
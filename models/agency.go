@@ -0,0 +1,151 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	AssignmentStatusAssigned     = "assigned"
+	AssignmentStatusAcknowledged = "acknowledged"
+	AssignmentStatusInProgress   = "in_progress"
+	AssignmentStatusResolved     = "resolved"
+	AssignmentStatusRejected     = "rejected"
+)
+
+// AssignmentStatuses lists the statuses an agency can move an assignment to.
+var AssignmentStatuses = []string{
+	AssignmentStatusAssigned,
+	AssignmentStatusAcknowledged,
+	AssignmentStatusInProgress,
+	AssignmentStatusResolved,
+	AssignmentStatusRejected,
+}
+
+// Agency is a government body or utility that acts on reports. New reports are
+// assigned to every active agency covering their category and location.
+type Agency struct {
+	Model
+	Name          string `json:"name" gorm:"not null"`
+	Email         string `json:"email"`
+	Phone         string `json:"phone"`
+	ContactPerson string `json:"contact_person"`
+	// WebhookURL receives a signed JSON POST for every new assignment
+	WebhookURL    string `json:"webhook_url"`
+	WebhookSecret string `json:"-"`
+	// Active has no column default so an agency can be created inactive
+	Active bool `json:"active"`
+	// EscalationContacts are the emails of senior officials told about
+	// assignments that miss their SLA
+	EscalationContacts StringList `json:"escalation_contacts" gorm:"type:jsonb"`
+	// Categories holds category slugs, rewritten when a category's slug
+	// changes. States and LGAs narrow the coverage; empty means everywhere.
+	Categories StringList `json:"categories" gorm:"type:jsonb"`
+	States     StringList `json:"states" gorm:"type:jsonb"`
+	LGAs       StringList `json:"lgas" gorm:"type:jsonb"`
+}
+
+// Covers reports whether a report in the category and location falls within
+// the agency's remit.
+func (a *Agency) Covers(categorySlug, state, lga string) bool {
+	if !a.Active || !a.Categories.Contains(categorySlug) {
+		return false
+	}
+	if len(a.States) > 0 && !a.States.Contains(state) {
+		return false
+	}
+	if len(a.LGAs) > 0 && !a.LGAs.Contains(lga) {
+		return false
+	}
+	return true
+}
+
+// ReportAssignment links a report to an agency responsible for it and tracks
+// the agency's progress.
+type ReportAssignment struct {
+	Model
+	IncidentReportID uuid.UUID `json:"incident_report_id" gorm:"type:uuid;not null;uniqueIndex:idx_report_agency"`
+	AgencyID         uint      `json:"agency_id" gorm:"not null;uniqueIndex:idx_report_agency;index"`
+	Status           string    `json:"status" gorm:"default:assigned;index"`
+	Note             string    `json:"note"`
+	// UpdatedBy is the agency user who last changed the status
+	UpdatedBy     uint       `json:"updated_by"`
+	DeliveredAt   *time.Time `json:"delivered_at"`
+	DeliveryError string     `json:"delivery_error,omitempty"`
+
+	// The due times come from the category's SLA targets and are nil when it
+	// has none. Escalated times record when a missed target was escalated.
+	AcknowledgedAt         *time.Time `json:"acknowledged_at"`
+	ResolvedAt             *time.Time `json:"resolved_at"`
+	AcknowledgeDueAt       *time.Time `json:"acknowledge_due_at" gorm:"index"`
+	ResolveDueAt           *time.Time `json:"resolve_due_at" gorm:"index"`
+	AcknowledgeEscalatedAt *time.Time `json:"acknowledge_escalated_at"`
+	ResolveEscalatedAt     *time.Time `json:"resolve_escalated_at"`
+
+	Agency         *Agency         `json:"agency,omitempty" gorm:"foreignKey:AgencyID"`
+	IncidentReport *IncidentReport `json:"incident_report,omitempty" gorm:"foreignKey:IncidentReportID"`
+}
+
+// SLA stages an assignment can breach.
+const (
+	SLAStageAcknowledge = "acknowledge"
+	SLAStageResolve     = "resolve"
+)
+
+// SLAMetric summarises how quickly assignments in a group, an agency or a
+// state, were acknowledged and resolved.
+type SLAMetric struct {
+	Group                 string  `json:"group"`
+	Assigned              int64   `json:"assigned"`
+	Acknowledged          int64   `json:"acknowledged"`
+	Resolved              int64   `json:"resolved"`
+	AvgHoursToAcknowledge float64 `json:"avg_hours_to_acknowledge"`
+	AvgHoursToResolve     float64 `json:"avg_hours_to_resolve"`
+	AcknowledgeBreaches   int64   `json:"acknowledge_breaches"`
+	ResolveBreaches       int64   `json:"resolve_breaches"`
+}
+
+// StringList is a list of strings stored as a JSON array.
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(l)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (l *StringList) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into StringList", value)
+	}
+	return json.Unmarshal(data, l)
+}
+
+// Contains reports whether the list holds s, ignoring case.
+func (l StringList) Contains(s string) bool {
+	for _, item := range l {
+		if strings.EqualFold(strings.TrimSpace(item), strings.TrimSpace(s)) {
+			return true
+		}
+	}
+	return false
+}
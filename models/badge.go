@@ -0,0 +1,47 @@
+package models
+
+// Badge criteria. Count criteria are met once the count reaches the badge's
+// threshold; ranking criteria once the user ranks at or above it.
+const (
+	BadgeCriterionReportsSubmitted = "reports_submitted"
+	BadgeCriterionReportsApproved  = "reports_approved"
+	BadgeCriterionMediaItems       = "media_items"
+	BadgeCriterionUpvotesReceived  = "upvotes_received"
+	BadgeCriterionTopInLGAMonthly  = "top_in_lga_monthly"
+)
+
+// Badge defines an achievement contributors can earn.
+type Badge struct {
+	Model
+	Code        string `json:"code" gorm:"not null;uniqueIndex"`
+	Name        string `json:"name" gorm:"not null"`
+	Description string `json:"description"`
+	IconURL     string `json:"icon_url"`
+	Criterion   string `json:"criterion" gorm:"not null"`
+	Threshold   int    `json:"threshold"`
+	Active      bool   `json:"active" gorm:"default:true"`
+}
+
+// Monthly reports whether the badge is earned afresh each month.
+func (b *Badge) Monthly() bool {
+	return b.Criterion == BadgeCriterionTopInLGAMonthly
+}
+
+// UserBadge records a badge a user earned.
+type UserBadge struct {
+	Model
+	UserID  uint `json:"user_id" gorm:"not null;uniqueIndex:idx_user_badge_period"`
+	BadgeID uint `json:"badge_id" gorm:"not null;uniqueIndex:idx_user_badge_period"`
+	// Period is the month, as 2006-01, a monthly badge was earned for and
+	// empty for the others
+	Period string `json:"period" gorm:"not null;default:'';uniqueIndex:idx_user_badge_period"`
+	Badge  Badge  `json:"badge" gorm:"foreignKey:BadgeID"`
+}
+
+// BadgeProgress holds the counts badge criteria are checked against.
+type BadgeProgress struct {
+	ReportsSubmitted int
+	ReportsApproved  int
+	MediaItems       int
+	UpvotesReceived  int
+}
@@ -5,9 +5,24 @@ import (
 	"time"
 )
 
+// Bookmark is a report a user saved. Each user bookmarks a report once,
+// through idx_bookmarks_user_report created by backfillBookmarks.
 type Bookmark struct {
-	ID        uint      `gorm:"primaryKey"`
-	UserID    uint      `gorm:"not null"`
-	ReportID  uuid.UUID `gorm:"not null"`
-	CreatedAt time.Time
+	ID       uint      `json:"id" gorm:"primaryKey"`
+	UserID   uint      `json:"user_id" gorm:"not null"`
+	ReportID uuid.UUID `json:"report_id" gorm:"not null"`
+	// CollectionID is the collection the user filed the bookmark in, nil
+	// when it is in none
+	CollectionID *uint     `json:"collection_id" gorm:"index"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// BookmarkCollection is a named group a user organizes bookmarks into, such
+// as "my street" or "to share".
+type BookmarkCollection struct {
+	Model
+	UserID uint   `json:"user_id" gorm:"not null;uniqueIndex:idx_bookmark_collection_user_name"`
+	Name   string `json:"name" gorm:"not null;uniqueIndex:idx_bookmark_collection_user_name"`
+	// BookmarkCount is filled in when collections are listed
+	BookmarkCount int `json:"bookmark_count" gorm:"-"`
 }
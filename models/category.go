@@ -0,0 +1,103 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Category is an admin managed report category, e.g. "health". Reports can
+// only be submitted under an active category.
+type Category struct {
+	Model
+	Slug        string `json:"slug" gorm:"uniqueIndex;not null"`
+	Name        string `json:"name" gorm:"not null"`
+	Description string `json:"description"`
+	Icon        string `json:"icon"`
+	// Active has no column default so an inactive one can be created;
+	// callers set it explicitly
+	Active       bool         `json:"active"`
+	SortOrder    int          `json:"sort_order"`
+	Translations Translations `json:"translations" gorm:"type:jsonb"`
+	FormSchema   FormSchema   `json:"form_schema" gorm:"type:jsonb"`
+	// AcknowledgeWithinHours and ResolveWithinHours are the SLA targets for
+	// agencies handling reports in the category; zero means no target.
+	AcknowledgeWithinHours int           `json:"acknowledge_within_hours"`
+	ResolveWithinHours     int           `json:"resolve_within_hours"`
+	SubTypes               []SubCategory `json:"sub_types" gorm:"foreignKey:CategoryID"`
+}
+
+// SubCategory is a sub-report type within a category, e.g. "power outage"
+// under "electricity".
+type SubCategory struct {
+	Model
+	CategoryID   uint         `json:"category_id" gorm:"not null;uniqueIndex:idx_sub_category_slug"`
+	Slug         string       `json:"slug" gorm:"not null;uniqueIndex:idx_sub_category_slug"`
+	Name         string       `json:"name" gorm:"not null"`
+	Description  string       `json:"description"`
+	Icon         string       `json:"icon"`
+	Active       bool         `json:"active"`
+	SortOrder    int          `json:"sort_order"`
+	Translations Translations `json:"translations" gorm:"type:jsonb"`
+}
+
+// Translation holds the localized name and description of a category.
+type Translation struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// Translations maps a language code such as "yo" or "ha" to a translation.
+type Translations map[string]Translation
+
+func (t Translations) Value() (driver.Value, error) {
+	if t == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (t *Translations) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*t = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Translations", value)
+	}
+	return json.Unmarshal(data, t)
+}
+
+// Localize returns the name and description in lang, falling back to the
+// defaults when there is no translation.
+func (t Translations) Localize(lang, name, description string) (string, string) {
+	if tr, ok := t[lang]; ok {
+		if tr.Name != "" {
+			name = tr.Name
+		}
+		if tr.Description != "" {
+			description = tr.Description
+		}
+	}
+	return name, description
+}
+
+// Slugify lower-cases s and joins its words with dashes, so "Road Accident"
+// becomes "road-accident".
+func Slugify(s string) string {
+	fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, "-")
+}
@@ -0,0 +1,23 @@
+package models
+
+// Report feed modes.
+const (
+	FeedLatest   = "latest"
+	FeedHot      = "hot"
+	FeedTrending = "trending"
+	FeedNearMe   = "near_me"
+	FeedForYou   = "for_you"
+)
+
+// FeedPosition is where a report sits in the latest feed.
+type FeedPosition struct {
+	ID        string
+	CreatedAt int64
+}
+
+// FeedPage is one page of a report feed. NextCursor fetches the page after
+// it and is empty on the last page.
+type FeedPage struct {
+	Reports    []map[string]interface{} `json:"incident_reports"`
+	NextCursor string                   `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,251 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Field types a FormSchema can declare.
+const (
+	FieldString  = "string"
+	FieldNumber  = "number"
+	FieldInteger = "integer"
+	FieldBoolean = "boolean"
+)
+
+// fieldNamePattern keeps attribute names safe to use as JSON keys in queries.
+var fieldNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,62}$`)
+
+// FormSchema describes the extra fields a category's report form asks for. It
+// is a subset of JSON Schema: an object of flat, typed properties. Clients
+// render the form from it and submissions are checked against it.
+type FormSchema struct {
+	Type       string               `json:"type,omitempty"`
+	Properties map[string]FormField `json:"properties,omitempty"`
+	Required   []string             `json:"required,omitempty"`
+	// Order lists the property names in the order clients should show them
+	Order []string `json:"x-order,omitempty"`
+}
+
+// FormField is a single property of a FormSchema.
+type FormField struct {
+	Type        string   `json:"type"`
+	Title       string   `json:"title,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Enum        []string `json:"enum,omitempty"`
+	// Format is "date" or "date-time" for string fields
+	Format    string   `json:"format,omitempty"`
+	MinLength *int     `json:"minLength,omitempty"`
+	MaxLength *int     `json:"maxLength,omitempty"`
+	Minimum   *float64 `json:"minimum,omitempty"`
+	Maximum   *float64 `json:"maximum,omitempty"`
+}
+
+func (f FormSchema) Value() (driver.Value, error) {
+	if len(f.Properties) == 0 {
+		return nil, nil
+	}
+	f.Type = "object"
+	b, err := json.Marshal(f)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (f *FormSchema) Scan(value interface{}) error {
+	*f = FormSchema{}
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, f)
+	case string:
+		return json.Unmarshal([]byte(v), f)
+	}
+	return fmt.Errorf("cannot scan %T into FormSchema", value)
+}
+
+// Check reports whether the schema itself is well formed.
+func (f FormSchema) Check() error {
+	if f.Type != "" && f.Type != "object" {
+		return fmt.Errorf("schema type must be object")
+	}
+	for name, field := range f.Properties {
+		if !fieldNamePattern.MatchString(name) {
+			return fmt.Errorf("field name %q must be lower case letters, digits and underscores", name)
+		}
+		switch field.Type {
+		case FieldString:
+			if field.Format != "" && field.Format != "date" && field.Format != "date-time" {
+				return fmt.Errorf("field %s has unsupported format %q", name, field.Format)
+			}
+		case FieldNumber, FieldInteger, FieldBoolean:
+			if len(field.Enum) > 0 || field.Format != "" {
+				return fmt.Errorf("field %s: enum and format only apply to string fields", name)
+			}
+		default:
+			return fmt.Errorf("field %s has unsupported type %q", name, field.Type)
+		}
+	}
+	for _, name := range append(append([]string{}, f.Required...), f.Order...) {
+		if _, ok := f.Properties[name]; !ok {
+			return fmt.Errorf("field %s is not defined in properties", name)
+		}
+	}
+	return nil
+}
+
+// Validate checks submitted attributes against the schema and returns them
+// converted to the declared types. Values may arrive as strings from a form
+// post. Fields the schema does not declare are rejected.
+func (f FormSchema) Validate(input map[string]interface{}) (Attributes, error) {
+	out := make(Attributes, len(input))
+	for name, raw := range input {
+		field, ok := f.Properties[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown field %s", name)
+		}
+		if s, isString := raw.(string); raw == nil || (isString && strings.TrimSpace(s) == "") {
+			continue
+		}
+		value, err := field.convert(raw)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %v", name, err)
+		}
+		out[name] = value
+	}
+	for _, name := range f.Required {
+		if _, ok := out[name]; !ok {
+			return nil, fmt.Errorf("field %s is required", name)
+		}
+	}
+	return out, nil
+}
+
+func (field FormField) convert(raw interface{}) (interface{}, error) {
+	switch field.Type {
+	case FieldString:
+		s, ok := raw.(string)
+		if !ok {
+			return nil, fmt.Errorf("must be a string")
+		}
+		s = strings.TrimSpace(s)
+		if field.MinLength != nil && utf8.RuneCountInString(s) < *field.MinLength {
+			return nil, fmt.Errorf("must be at least %d characters", *field.MinLength)
+		}
+		if field.MaxLength != nil && utf8.RuneCountInString(s) > *field.MaxLength {
+			return nil, fmt.Errorf("must be at most %d characters", *field.MaxLength)
+		}
+		if len(field.Enum) > 0 && !containsString(field.Enum, s) {
+			return nil, fmt.Errorf("must be one of %s", strings.Join(field.Enum, ", "))
+		}
+		switch field.Format {
+		case "date":
+			if _, err := time.Parse("2006-01-02", s); err != nil {
+				return nil, fmt.Errorf("must be a date like 2006-01-02")
+			}
+		case "date-time":
+			if _, err := time.Parse(time.RFC3339, s); err != nil {
+				return nil, fmt.Errorf("must be an RFC 3339 date and time")
+			}
+		}
+		return s, nil
+
+	case FieldNumber, FieldInteger:
+		var n float64
+		switch v := raw.(type) {
+		case float64:
+			n = v
+		case string:
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return nil, fmt.Errorf("must be a number")
+			}
+			n = parsed
+		default:
+			return nil, fmt.Errorf("must be a number")
+		}
+		if field.Type == FieldInteger && n != float64(int64(n)) {
+			return nil, fmt.Errorf("must be a whole number")
+		}
+		if field.Minimum != nil && n < *field.Minimum {
+			return nil, fmt.Errorf("must be at least %v", *field.Minimum)
+		}
+		if field.Maximum != nil && n > *field.Maximum {
+			return nil, fmt.Errorf("must be at most %v", *field.Maximum)
+		}
+		return n, nil
+
+	case FieldBoolean:
+		switch v := raw.(type) {
+		case bool:
+			return v, nil
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("must be true or false")
+			}
+			return b, nil
+		}
+		return nil, fmt.Errorf("must be true or false")
+	}
+	return nil, fmt.Errorf("unsupported type %q", field.Type)
+}
+
+// IsAttributeName reports whether name can be a schema field, and therefore
+// whether it is safe to filter reports on.
+func IsAttributeName(name string) bool {
+	return fieldNamePattern.MatchString(name)
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Attributes holds the category specific fields of a report.
+type Attributes map[string]interface{}
+
+func (a Attributes) Value() (driver.Value, error) {
+	if a == nil {
+		return "{}", nil
+	}
+	b, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (a *Attributes) Scan(value interface{}) error {
+	var data []byte
+	switch v := value.(type) {
+	case nil:
+		*a = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Attributes", value)
+	}
+	return json.Unmarshal(data, a)
+}
+
+// AttributeCount is the number of reports with a given value of an attribute.
+type AttributeCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
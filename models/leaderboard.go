@@ -0,0 +1,71 @@
+package models
+
+import "strings"
+
+// Leaderboard periods.
+const (
+	LeaderboardWeekly  = "weekly"
+	LeaderboardMonthly = "monthly"
+	LeaderboardAllTime = "all_time"
+)
+
+// Leaderboard scopes. State and LGA boards are named by the user's state or
+// LGA when they earned the points.
+const (
+	LeaderboardGlobal = "global"
+	LeaderboardState  = "state"
+	LeaderboardLGA    = "lga"
+)
+
+// LeaderboardEntry is a user's standing on a leaderboard.
+type LeaderboardEntry struct {
+	Rank         int64  `json:"rank"`
+	UserID       uint   `json:"user_id"`
+	Username     string `json:"username"`
+	Fullname     string `json:"fullname"`
+	ThumbNailURL string `json:"thumbnail_url,omitempty"`
+	Points       int64  `json:"points"`
+}
+
+// EarnedPoints is a ledger transaction that counts towards the leaderboards,
+// with the location of the user who earned it.
+type EarnedPoints struct {
+	ID             uint
+	UserID         uint
+	StateName      string
+	LGAName        string
+	Amount         int
+	IdempotencyKey string
+	CreatedAt      int64
+}
+
+// EarnsPoints reports whether the transaction moves the user's standing on
+// the leaderboards. Redemptions, and the refunds of failed ones, spend points
+// rather than earn them.
+func (t *LedgerTransaction) EarnsPoints() bool {
+	if t.Type == LedgerRedemption {
+		return false
+	}
+	return !strings.HasPrefix(t.IdempotencyKey, "reversal:redemption:")
+}
+
+// ReputationStats are the signals a user's reputation is computed from.
+// Rejected reports and being queried by an admin count as flags against them.
+type ReputationStats struct {
+	ApprovedReports   int  `json:"approved_reports"`
+	RejectedReports   int  `json:"rejected_reports"`
+	UpvotesReceived   int  `json:"upvotes_received"`
+	DownvotesReceived int  `json:"downvotes_received"`
+	IsQueried         bool `json:"is_queried"`
+	IsVerified        bool `json:"is_verified"`
+}
+
+// Reputation scores how much the community and moderators trust a user.
+// Weight is the factor to scale the user's votes and rewards by, 1 for a
+// neutral user.
+type Reputation struct {
+	UserID uint    `json:"user_id"`
+	Score  int     `json:"score"`
+	Weight float64 `json:"weight"`
+	ReputationStats
+}
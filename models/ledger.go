@@ -0,0 +1,88 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// Types of points ledger transactions.
+const (
+	LedgerReportSubmitted = "report_submitted"
+	LedgerMediaBonus      = "media_bonus"
+	LedgerApproval        = "approval"
+	LedgerReversal        = "reversal"
+	LedgerRedemption      = "redemption"
+	LedgerAdjustment      = "adjustment"
+)
+
+// LedgerTypes lists every transaction type.
+var LedgerTypes = []string{
+	LedgerReportSubmitted,
+	LedgerMediaBonus,
+	LedgerApproval,
+	LedgerReversal,
+	LedgerRedemption,
+	LedgerAdjustment,
+}
+
+// System accounts on the other side of user postings. Points are issued from
+// AccountRewards and redeemed points go to AccountRedemptions.
+const (
+	AccountRewards     = "system:rewards"
+	AccountRedemptions = "system:redemptions"
+)
+
+// UserAccount is the ledger account holding a user's points.
+func UserAccount(userID uint) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// LedgerTransaction is one movement of points to or from a user. It is never
+// updated or deleted; mistakes are corrected with a reversal or adjustment.
+// Its entries always sum to zero.
+type LedgerTransaction struct {
+	Model
+	// IdempotencyKey identifies the event that caused the transaction, so
+	// posting the same event twice has no effect
+	IdempotencyKey string `json:"idempotency_key" gorm:"not null;uniqueIndex"`
+	Type           string `json:"type" gorm:"not null;index"`
+	UserID         uint   `json:"user_id" gorm:"not null;index"`
+	// Amount is the change to the user's balance
+	Amount           int        `json:"amount"`
+	IncidentReportID *uuid.UUID `json:"incident_report_id,omitempty" gorm:"type:uuid;index"`
+	// ReversesID is the transaction a reversal undoes
+	ReversesID  *uint  `json:"reverses_id,omitempty" gorm:"index"`
+	Description string `json:"description"`
+	// RuleID is the reward rule that paid the points, if any
+	RuleID *uint `json:"rule_id,omitempty" gorm:"index"`
+	// CreatedBy is the admin behind an adjustment or reversal, 0 for the system
+	CreatedBy uint          `json:"created_by"`
+	Entries   []LedgerEntry `json:"entries,omitempty" gorm:"foreignKey:TransactionID"`
+}
+
+// LedgerEntry is one side of a transaction: a signed amount posted to an
+// account. UserID is set on postings to user accounts.
+type LedgerEntry struct {
+	Model
+	TransactionID uint   `json:"transaction_id" gorm:"not null;index"`
+	Account       string `json:"account" gorm:"not null;index"`
+	UserID        *uint  `json:"user_id,omitempty" gorm:"index"`
+	Amount        int    `json:"amount"`
+}
+
+// PointsBalance is the cached balance of a user, kept in step with the ledger
+// as transactions are posted and rebuilt from it by reconciliation.
+type PointsBalance struct {
+	UserID            uint  `json:"user_id" gorm:"primaryKey;autoIncrement:false"`
+	Balance           int   `json:"balance"`
+	LastTransactionID uint  `json:"last_transaction_id"`
+	UpdatedAt         int64 `json:"updated_at"`
+}
+
+// BalanceDrift is a cached balance that disagreed with the ledger.
+type BalanceDrift struct {
+	UserID uint `json:"user_id"`
+	Cached int  `json:"cached"`
+	Ledger int  `json:"ledger"`
+}
@@ -37,6 +37,6 @@ type CreateStateInput struct {
 
 
 type LGAReportCount struct {
-	LGAName     string `gorm:"default:null"json:"lga_name"`
-	ReportCount int    `gorm:"default:null"json:"report_count"`
+	LGAName     string `gorm:"default:null" json:"lga_name"`
+	ReportCount int    `gorm:"default:null" json:"report_count"`
 }
@@ -8,7 +8,33 @@ type Like struct {
 	Count int `json:"count"`
 }
 
+// View records that a viewer saw a report. ID joins the report and the
+// viewer's key, so each viewer is recorded once per report; UserID is 0 for
+// viewers who were not signed in.
 type View struct {
 	ID               string `gorm:"primaryKey"`
-	IncidentReportID string `gorm:"foreignKey:ID"`
+	IncidentReportID string `gorm:"index"`
+	UserID           uint   `json:"user_id"`
+	CreatedAt        int64  `json:"created_at"`
+}
+
+// EngagementBatch records a flushed batch of buffered engagement, so a batch
+// whose Redis copy could not be cleared is not counted twice.
+type EngagementBatch struct {
+	ID        string `gorm:"primaryKey"`
+	AppliedAt int64  `gorm:"index"`
+}
+
+// ReportMetrics is the engagement a report has drawn.
+type ReportMetrics struct {
+	ReportID      string `json:"report_id"`
+	Views         int    `json:"views"`
+	UniqueViewers int    `json:"unique_viewers"`
+	Shares        int    `json:"shares"`
+	Upvotes       int    `json:"upvotes"`
+	Downvotes     int    `json:"downvotes"`
+	Bookmarks     int    `json:"bookmarks"`
+	Follows       int    `json:"follows"`
+	// OwnerID is the reporter, who may see the metrics
+	OwnerID uint `json:"-"`
 }
@@ -1,13 +1,17 @@
 package models
 
-import "github.com/google/uuid"
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type Media struct {
 	ID               string    `json:"id"`
 	FileType         string    `json:"file_type"`
 	FileSize         int64     `json:"file_size"`
 	Filename         string    `json:"file_name"`
-	UserID           uint      `gorm:"foreignKey:ID"`
+	UserID           uint      `json:"-" gorm:"foreignKey:ID"`
 	Width            int       `json:"width"`
 	Height           int       `json:"height"`
 	FeedURL          string    `json:"feed_url"`
@@ -15,9 +19,49 @@ type Media struct {
 	ThumbnailURL     string    `json:"thumbnail_url"`
 	Count            int       `json:"count"`
 	Points           int       `json:"points"`
-	IncidentReportID uuid.UUID `json:"incident_report_id"`
+	IncidentReportID uuid.UUID `json:"incident_report_id" gorm:"index"`
+	// Position orders the media within its report, starting at 0
+	Position  int       `json:"position"`
+	CreatedAt time.Time `json:"created_at"`
+	// CapturedAt is the capture time read from EXIF before the metadata was stripped
+	CapturedAt *time.Time `json:"captured_at"`
+	// LocationDistanceMeters is how far the EXIF GPS position was from the report's
+	// lat/lng. The raw EXIF coordinates are deliberately not kept.
+	LocationDistanceMeters *float64 `json:"location_distance_meters"`
+	// LocationConsistencyScore is 0..1, nil when the photo carried no GPS data
+	LocationConsistencyScore *float64 `json:"location_consistency_score"`
+	// PerceptualHash is the image dHash stored as a signed bigint so Postgres can
+	// XOR it; nil for video and audio
+	PerceptualHash *int64 `json:"perceptual_hash" gorm:"index"`
+	// IsDuplicate marks an image that is a near copy of one already uploaded,
+	// by anyone, to any report. Duplicates earn no media points.
+	IsDuplicate   bool   `json:"is_duplicate" gorm:"default:false"`
+	DuplicateOfID string `json:"duplicate_of_id"`
+	// ProcessingStatus tracks media handed to the background job queue. Media
+	// processed inline during the request is ready as soon as it is saved.
+	ProcessingStatus string `json:"processing_status" gorm:"default:ready"`
+	// For video FeedURL is the HLS master playlist and ThumbnailURL the poster
+	// frame. The probe fields describe the uploaded source.
+	DurationSeconds float64 `json:"duration_seconds"`
+	VideoCodec      string  `json:"video_codec"`
+	AudioCodec      string  `json:"audio_codec"`
+	// Renditions lists the HLS renditions produced, e.g. "240p,480p,720p"
+	Renditions string `json:"renditions"`
+	// Transcript is the recognised speech of an audio upload, with the detected
+	// language. Both are empty when no transcriber is configured.
+	Transcript         string `json:"transcript" gorm:"type:text"`
+	TranscriptLanguage string `json:"transcript_language"`
+	// ModerationFlags lists the moderation keywords found in the transcript
+	ModerationFlags string `json:"moderation_flags"`
 }
 
+const (
+	MediaStatusPending    = "pending"
+	MediaStatusProcessing = "processing"
+	MediaStatusReady      = "ready"
+	MediaStatusFailed     = "failed"
+)
+
 type MediaCount struct {
 	Model
 	Images           int
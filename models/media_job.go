@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+const (
+	MediaJobQueued  = "queued"
+	MediaJobRunning = "running"
+	MediaJobDone    = "done"
+)
+
+// MediaJob is a unit of background media processing. The source file is kept
+// in the blob store under SourceKey until the job succeeds or is dead-lettered,
+// so a crash mid-transcode only delays the work and any instance can run it.
+// SourcePath is the local spool file of jobs queued before that.
+type MediaJob struct {
+	ID               uint       `json:"id" gorm:"primaryKey"`
+	MediaID          string     `json:"media_id" gorm:"type:varchar(36);index"`
+	IncidentReportID string     `json:"report_id" gorm:"type:varchar(36);index"`
+	UserID           uint       `json:"user_id"`
+	SourcePath       string     `json:"-"`
+	SourceKey        string     `json:"-"`
+	Filename         string     `json:"filename"`
+	ContentType      string     `json:"content_type"`
+	Status           string     `json:"status" gorm:"index;default:queued"`
+	Attempts         int        `json:"attempts"`
+	MaxAttempts      int        `json:"max_attempts"`
+	RunAt            time.Time  `json:"run_at" gorm:"index"`
+	LockedAt         *time.Time `json:"locked_at"`
+	LastError        string     `json:"last_error"`
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// DeadMediaJob is a job that ran out of attempts, kept for inspection.
+type DeadMediaJob struct {
+	ID               uint      `json:"id" gorm:"primaryKey"`
+	JobID            uint      `json:"job_id" gorm:"index"`
+	MediaID          string    `json:"media_id" gorm:"type:varchar(36)"`
+	IncidentReportID string    `json:"report_id" gorm:"type:varchar(36)"`
+	UserID           uint      `json:"user_id"`
+	SourcePath       string    `json:"-"`
+	SourceKey        string    `json:"-"`
+	Filename         string    `json:"filename"`
+	Attempts         int       `json:"attempts"`
+	LastError        string    `json:"last_error"`
+	FailedAt         time.Time `json:"failed_at"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OfficialResponse is a statement posted on a report by an admin or by staff
+// of an agency the report is assigned to. Clients show it apart from citizen
+// follow-ups.
+type OfficialResponse struct {
+	Model
+	IncidentReportID uuid.UUID `json:"incident_report_id" gorm:"type:uuid;not null;index"`
+	ResponderID      uint      `json:"responder_id"`
+	ResponderName    string    `json:"responder_name"`
+	// AgencyID is set when the responder spoke for an agency
+	AgencyID   *uint  `json:"agency_id"`
+	AgencyName string `json:"agency_name"`
+	Text       string `json:"text" gorm:"type:text;not null"`
+	// Status is the resolution status the response moved the report to, if any
+	Status                 string     `json:"status"`
+	ExpectedResolutionDate *time.Time `json:"expected_resolution_date"`
+	MediaURLs              StringList `json:"media_urls" gorm:"type:jsonb"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Ways points can be redeemed.
+const (
+	RedemptionAirtime      = "airtime"
+	RedemptionData         = "data"
+	RedemptionBankTransfer = "bank_transfer"
+)
+
+// Redemption statuses. A request waits in pending for an admin, who rejects
+// it or approves it for payout. Approved requests are processing until the
+// payout provider reports them paid or failed.
+const (
+	RedemptionPending    = "pending"
+	RedemptionRejected   = "rejected"
+	RedemptionProcessing = "processing"
+	RedemptionPaid       = "paid"
+	RedemptionFailed     = "failed"
+)
+
+// Redemption is a user's request to cash out points. The points are debited
+// from the ledger when it is made and the debit is reversed if the request
+// is rejected or the payout fails.
+type Redemption struct {
+	Model
+	// Reference identifies the redemption to the ledger and payout provider
+	Reference string `json:"reference" gorm:"not null;uniqueIndex"`
+	UserID    uint   `json:"user_id" gorm:"not null;index"`
+	Type      string `json:"type" gorm:"not null"`
+	Points    int    `json:"points"`
+	// Amount is the value paid out in naira
+	Amount float64 `json:"amount"`
+	Status string  `json:"status" gorm:"not null;index"`
+
+	// PhoneNumber and Network are set for airtime and data
+	PhoneNumber string `json:"phone_number,omitempty"`
+	Network     string `json:"network,omitempty"`
+	// BankCode, AccountNumber and AccountName are set for bank transfers
+	BankCode      string `json:"bank_code,omitempty"`
+	AccountNumber string `json:"account_number,omitempty"`
+	AccountName   string `json:"account_name,omitempty"`
+
+	LedgerTransactionID uint       `json:"ledger_transaction_id"`
+	ReviewedBy          uint       `json:"reviewed_by,omitempty"`
+	ReviewedAt          *time.Time `json:"reviewed_at,omitempty"`
+	RejectionReason     string     `json:"rejection_reason,omitempty"`
+	Provider            string     `json:"provider,omitempty"`
+	ProviderReference   string     `json:"provider_reference,omitempty"`
+	FailureReason       string     `json:"failure_reason,omitempty"`
+	PaidAt              *time.Time `json:"paid_at,omitempty"`
+}
@@ -39,6 +39,16 @@ type SubReport struct {
 type RatingPercentage struct {
 	GoodPercentage float64 `json:"good_percentage"`
 	BadPercentage  float64 `json:"bad_percentage"`
+	// ResolutionConfirmation is how often citizens confirmed resolved
+	// reports were fixed
+	ResolutionConfirmation *ConfirmationReport `json:"resolution_confirmation,omitempty"`
+}
+
+// ConfirmationReport holds resolution confirmation rates by agency and by
+// state.
+type ConfirmationReport struct {
+	ByAgency []ConfirmationMetric `json:"by_agency"`
+	ByState  []ConfirmationMetric `json:"by_state"`
 }
 type ReportCriteria struct {
 	ReportTypes []string   `json:"report_types"`
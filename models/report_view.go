@@ -0,0 +1,155 @@
+package models
+
+// ReportAudience identifies who an incident report is being serialized for.
+// Every handler that returns a report should pass it through ForAudience (or
+// RedactReportMap for the map based queries) so anonymity and contact-detail
+// hiding are applied the same way everywhere.
+type ReportAudience int
+
+const (
+	// AudiencePublic is any viewer who is neither the reporter nor a moderator,
+	// including unauthenticated share previews.
+	AudiencePublic ReportAudience = iota
+	// AudienceOwner is the user who submitted the report.
+	AudienceOwner
+	// AudienceModerator is an admin reviewing the report.
+	AudienceModerator
+)
+
+const (
+	AnonymousFullname = "Anonymous"
+	AnonymousUsername = "anonymous"
+)
+
+// MediaHidden reports whether the report's media has been withdrawn: blocked
+// and rejected reports keep their media for moderators only.
+func (r IncidentReport) MediaHidden() bool {
+	return mediaHidden(r.BlockRequest, r.ReportStatus)
+}
+
+func mediaHidden(blockRequest, reportStatus string) bool {
+	return blockRequest == "true" || reportStatus == "rejected"
+}
+
+// ForAudience returns a copy of the report with the fields the audience is not
+// allowed to see removed. Owners and moderators get the full report, except
+// that only moderators see the media of a hidden report.
+func (r IncidentReport) ForAudience(audience ReportAudience) IncidentReport {
+	if audience != AudienceModerator && r.MediaHidden() {
+		r.Media = nil
+	}
+	r.Media = MediaForAudience(r.Media, audience)
+	if audience != AudiencePublic {
+		return r
+	}
+
+	// Contact details are never public, anonymous or not.
+	r.Email = ""
+	r.Telephone = ""
+	r.RewardAccountNumber = ""
+	r.Followers = nil
+
+	if r.IsAnonymous || r.UserIsAnonymous {
+		r.UserID = 0
+		r.UserFullname = AnonymousFullname
+		r.UserUsername = AnonymousUsername
+		r.ReporterImage = ""
+	}
+	return r
+}
+
+// ForAudience returns a copy of the media item without what was learned from
+// analysing the upload, which only the reporter and moderators see. The
+// moderation flags are for moderators alone.
+func (m Media) ForAudience(audience ReportAudience) Media {
+	if audience != AudienceModerator {
+		m.ModerationFlags = ""
+	}
+	if audience != AudiencePublic {
+		return m
+	}
+	m.CapturedAt = nil
+	m.LocationDistanceMeters = nil
+	m.LocationConsistencyScore = nil
+	m.PerceptualHash = nil
+	m.IsDuplicate = false
+	m.DuplicateOfID = ""
+	m.Transcript = ""
+	m.TranscriptLanguage = ""
+	return m
+}
+
+// MediaForAudience applies ForAudience to every item in the slice.
+func MediaForAudience(media []Media, audience ReportAudience) []Media {
+	if media == nil {
+		return nil
+	}
+	views := make([]Media, 0, len(media))
+	for _, item := range media {
+		views = append(views, item.ForAudience(audience))
+	}
+	return views
+}
+
+// ReportsForAudience applies ForAudience to every report in the slice.
+func ReportsForAudience(reports []IncidentReport, audience func(IncidentReport) ReportAudience) []IncidentReport {
+	views := make([]IncidentReport, 0, len(reports))
+	for _, report := range reports {
+		views = append(views, report.ForAudience(audience(report)))
+	}
+	return views
+}
+
+// RedactReportMap applies the same rules as ForAudience to a report row that
+// was scanned into a map (as GetAllReports and GetAllIncidentReportsByUser do).
+// The map is modified in place.
+func RedactReportMap(report map[string]interface{}, audience ReportAudience) {
+	blockRequest, _ := report["block_request"].(string)
+	reportStatus, _ := report["report_status"].(string)
+	if audience != AudienceModerator && mediaHidden(blockRequest, reportStatus) {
+		report["media"] = []Media{}
+	}
+	if media, ok := report["media"].([]Media); ok {
+		report["media"] = MediaForAudience(media, audience)
+	}
+	if audience != AudiencePublic {
+		return
+	}
+
+	for _, key := range []string{"email", "telephone", "reward_account_number"} {
+		if _, ok := report[key]; ok {
+			report[key] = ""
+		}
+	}
+
+	if isTrue(report["is_anonymous"]) || isTrue(report["user_is_anonymous"]) {
+		report["user_id"] = nil
+		report["user_fullname"] = AnonymousFullname
+		report["user_username"] = AnonymousUsername
+		report["profile_image"] = nil
+		report["thumbnail_urls"] = nil
+		report["reporter_image"] = nil
+	}
+}
+
+// ReportOwnerID reads the reporter's user ID from a report row scanned into a map.
+func ReportOwnerID(report map[string]interface{}) uint {
+	switch v := report["user_id"].(type) {
+	case int64:
+		return uint(v)
+	case int32:
+		return uint(v)
+	case int:
+		return uint(v)
+	case uint:
+		return v
+	case float64:
+		return uint(v)
+	}
+	return 0
+}
+
+func isTrue(v interface{}) bool {
+	b, ok := v.(bool)
+	return ok && b
+}
@@ -0,0 +1,36 @@
+package models
+
+import "github.com/google/uuid"
+
+// ResolutionStatusReopened marks a report citizens said was still broken after
+// it was resolved.
+const ResolutionStatusReopened = "reopened"
+
+// ResolutionVote is a citizen's verdict on a resolved report: fixed or still
+// broken. Votes belong to a resolution round so a report resolved again after
+// reopening is voted on afresh.
+type ResolutionVote struct {
+	Model
+	IncidentReportID uuid.UUID `json:"incident_report_id" gorm:"type:uuid;not null;uniqueIndex:idx_resolution_vote"`
+	UserID           uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_resolution_vote"`
+	Round            int       `json:"round" gorm:"not null;uniqueIndex:idx_resolution_vote"`
+	Fixed            bool      `json:"fixed"`
+	IsReporter       bool      `json:"is_reporter"`
+}
+
+// ResolutionTally counts the votes in a report's current resolution round.
+type ResolutionTally struct {
+	Round       int   `json:"round"`
+	Fixed       int64 `json:"fixed"`
+	StillBroken int64 `json:"still_broken"`
+}
+
+// ConfirmationMetric is the share of resolution votes in a group, an agency or
+// a state, that confirmed the fix.
+type ConfirmationMetric struct {
+	Group            string  `json:"group"`
+	Votes            int64   `json:"votes"`
+	Fixed            int64   `json:"fixed"`
+	StillBroken      int64   `json:"still_broken"`
+	ConfirmationRate float64 `json:"confirmation_rate"`
+}
@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// Actions a reward rule can pay for.
+const (
+	// RewardActionReportSubmitted pays once per submitted report
+	RewardActionReportSubmitted = "report_submitted"
+	// RewardActionDescription pays for a report with a description
+	RewardActionDescription = "report_description"
+	// RewardActionLocation pays for a report with a location
+	RewardActionLocation = "report_location"
+	// RewardActionMediaItem pays per photo, video or audio attached
+	RewardActionMediaItem = "media_item"
+	// RewardActionReportApproved pays per point the report earned when it
+	// was submitted, once an admin approves it
+	RewardActionReportApproved = "report_approved"
+)
+
+// RewardActions lists every action rules can be written for.
+var RewardActions = []string{
+	RewardActionReportSubmitted,
+	RewardActionDescription,
+	RewardActionLocation,
+	RewardActionMediaItem,
+	RewardActionReportApproved,
+}
+
+// RewardRule says how many points an action earns. Every active rule that
+// matches an action pays out, so a category or campaign rule adds to the
+// general one rather than replacing it.
+type RewardRule struct {
+	Model
+	Name   string `json:"name" gorm:"not null"`
+	Action string `json:"action" gorm:"not null;index"`
+	// CategoryID limits the rule to reports in one category; nil matches all
+	CategoryID *uint `json:"category_id" gorm:"index"`
+	// Points are paid per unit of the action, e.g. per media item
+	Points int `json:"points"`
+	// VerifiedMultiplier scales the points for verified reports; 0 or 1
+	// leaves them unchanged
+	VerifiedMultiplier float64 `json:"verified_multiplier"`
+	// DailyCap is the most a user can earn from the rule in a UTC day; 0 is
+	// no cap
+	DailyCap int `json:"daily_cap"`
+	// FirstReportOnly pays only for a user's first report
+	FirstReportOnly bool `json:"first_report_only"`
+	// StartsAt and EndsAt bound a campaign; nil is open ended
+	StartsAt *time.Time `json:"starts_at"`
+	EndsAt   *time.Time `json:"ends_at"`
+	Active   bool       `json:"active"`
+}
+
+// Applies reports whether the rule is live at the given time.
+func (r *RewardRule) Applies(at time.Time) bool {
+	if !r.Active {
+		return false
+	}
+	if r.StartsAt != nil && at.Before(*r.StartsAt) {
+		return false
+	}
+	if r.EndsAt != nil && !at.Before(*r.EndsAt) {
+		return false
+	}
+	return true
+}
@@ -11,4 +11,6 @@ type Role struct {
 const (
 	RoleUser  = "User"
 	RoleAdmin = "Admin"
+	// RoleAgency is given to staff of an agency, who see its assigned reports
+	RoleAgency = "Agency"
 )
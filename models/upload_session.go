@@ -0,0 +1,30 @@
+package models
+
+import "time"
+
+const (
+	UploadStatusPending   = "pending"
+	UploadStatusCompleted = "completed"
+)
+
+// UploadSession tracks a resumable upload. The client sends the file as
+// numbered chunks of ChunkSize bytes (the last one may be shorter); Offset is
+// how many bytes have been received so far and NextChunk the chunk expected next.
+// Chunks are kept in the blob store, so any instance can take the next one.
+// TempPath is the local file of sessions started before that.
+type UploadSession struct {
+	ID               string    `json:"id" gorm:"primaryKey;type:varchar(36)"`
+	UserID           uint      `json:"user_id" gorm:"index"`
+	IncidentReportID string    `json:"report_id" gorm:"type:varchar(36);index"`
+	Filename         string    `json:"filename"`
+	ContentType      string    `json:"content_type"`
+	TotalSize        int64     `json:"total_size"`
+	ChunkSize        int64     `json:"chunk_size"`
+	Offset           int64     `json:"offset"`
+	NextChunk        int       `json:"next_chunk"`
+	Status           string    `json:"status" gorm:"default:pending"`
+	TempPath         string    `json:"-"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
@@ -44,9 +44,10 @@ type User struct {
 	Downvotes         int               `json:"down_vote"`
 	RoleID            uuid.UUID         `gorm:"type:uuid" json:"role_id"`
 	Role              Role              `gorm:"foreignKey:RoleID" json:"role"`
-	BookmarkedReports []*IncidentReport `gorm:"many2many:incident_report_user;" json:"bookmarked_reports"`
 	FollowingReports []*IncidentReport `gorm:"many2many:follows;joinForeignKey:UserID;joinReferences:ReportID" json:"following_reports"`
 	ExpoPushToken string `gorm:"type:text" json:"expo_push_token"`
+	// AgencyID is set for agency staff
+	AgencyID *uint `json:"agency_id" gorm:"index"`
 }
 
 type ReportUserRequest struct {
@@ -1,9 +1,31 @@
 package models
 
-// Reward represents rewards earned by users
+// Vote directions. A user who has not voted on a report, or retracted their
+// vote, has no row and is reported as VoteNone.
+const (
+	VoteUp   = "upvote"
+	VoteDown = "downvote"
+	VoteNone = "none"
+)
+
+// Votes holds a user's one vote on a report. The pair is unique through
+// idx_votes_user_report, created by backfillVotes once duplicates are gone.
 type Votes struct {
 	Model
 	UserID   uint   `json:"user_id" gorm:"foreignKey:UserID"`
 	ReportID string `json:"report_type_id"`
 	VoteType string `json:"vote_type"`
+	// Weight is the voter's reputation weight when they voted
+	Weight float64 `json:"weight" gorm:"default:1"`
+}
+
+// VoteCounts are a report's votes, raw and weighted by the voters'
+// reputation.
+type VoteCounts struct {
+	Upvotes           int     `json:"upvotes"`
+	Downvotes         int     `json:"downvotes"`
+	WeightedUpvotes   float64 `json:"weighted_upvotes"`
+	WeightedDownvotes float64 `json:"weighted_downvotes"`
+	// UserVote is the asking user's own vote
+	UserVote string `json:"user_vote"`
 }
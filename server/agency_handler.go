@@ -0,0 +1,166 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/techagentng/citizenx/models"
+	"github.com/techagentng/citizenx/services"
+)
+
+// agencyErrorStatus maps agency service errors onto HTTP status codes.
+func agencyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrAgencyNotFound), errors.Is(err, services.ErrAssignmentNotFound), errors.Is(err, services.ErrUserNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidAgency), errors.Is(err, services.ErrInvalidStatus):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrNotAgencyMember):
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+type assignmentStatusRequest struct {
+	Status string `json:"status" binding:"required"`
+	Note   string `json:"note"`
+}
+
+// routeReport assigns a new report to the agencies responsible for it. It runs
+// in the background so notifying agencies does not hold up the reporter.
+func (s *Server) routeReport(report *models.IncidentReport) {
+	if s.AgencyService == nil {
+		return
+	}
+	go func() {
+		if _, err := s.AgencyService.RouteReport(report); err != nil {
+			log.Printf("Unable to route report %s: %v", report.ID, err)
+		}
+	}()
+}
+
+func (s *Server) handleGetAgencies() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		agencies, err := s.AgencyService.ListAgencies()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"agencies": agencies})
+	}
+}
+
+// handleCreateAgency creates an agency. The webhook secret used to sign
+// deliveries is only returned here.
+func (s *Server) handleCreateAgency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input services.AgencyInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		agency, err := s.AgencyService.CreateAgency(input)
+		if err != nil {
+			c.JSON(agencyErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"agency": agency, "webhook_secret": agency.WebhookSecret})
+	}
+}
+
+func (s *Server) handleUpdateAgency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := uintParam(c, "id")
+		if !ok {
+			return
+		}
+		var input services.AgencyInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		agency, err := s.AgencyService.UpdateAgency(id, input)
+		if err != nil {
+			c.JSON(agencyErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"agency": agency})
+	}
+}
+
+func (s *Server) handleDeleteAgency() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := uintParam(c, "id")
+		if !ok {
+			return
+		}
+		if err := s.AgencyService.DeleteAgency(id); err != nil {
+			c.JSON(agencyErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Agency deleted"})
+	}
+}
+
+// handleAddAgencyMember gives a user access to the agency portal.
+func (s *Server) handleAddAgencyMember() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := uintParam(c, "id")
+		if !ok {
+			return
+		}
+		userID, ok := uintParam(c, "user_id")
+		if !ok {
+			return
+		}
+		if err := s.AgencyService.AddMember(id, userID); err != nil {
+			c.JSON(agencyErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "User added to agency"})
+	}
+}
+
+// handleGetAgencyAssignments lists the reports assigned to the current
+// user's agency, optionally filtered by ?status=.
+func (s *Server) handleGetAgencyAssignments() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, err := getPageFromQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+			return
+		}
+		user, _ := c.MustGet("user").(*models.User)
+		assignments, err := s.AgencyService.GetAssignments(user, c.Query("status"), page)
+		if err != nil {
+			c.JSON(agencyErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"assignments": assignments})
+	}
+}
+
+// handleUpdateAssignmentStatus lets agency staff record progress on a report
+// assigned to their agency.
+func (s *Server) handleUpdateAssignmentStatus() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := uintParam(c, "id")
+		if !ok {
+			return
+		}
+		var req assignmentStatusRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		user, _ := c.MustGet("user").(*models.User)
+		assignment, err := s.AgencyService.UpdateAssignmentStatus(user, id, req.Status, req.Note)
+		if err != nil {
+			c.JSON(agencyErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"assignment": assignment})
+	}
+}
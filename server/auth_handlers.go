@@ -1,8 +1,6 @@
 package server
 
 import (
-	// "bytes"
-	"bytes"
 	"context"
 	"crypto/rand"
 	"encoding/base64"
@@ -25,66 +23,20 @@ import (
 	"golang.org/x/oauth2/google"
 	"gorm.io/gorm"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/gin-gonic/gin"
 	"github.com/techagentng/citizenx/errors"
 	errs "github.com/techagentng/citizenx/errors"
 	"github.com/techagentng/citizenx/models"
 	"github.com/techagentng/citizenx/server/response"
+	"github.com/techagentng/citizenx/storage"
 	jwtPackage "github.com/techagentng/citizenx/services/jwt"
 )
 
-func createS3Client() (*s3.Client, error) {
-	cfg, err := config.LoadDefaultConfig(context.Background(),
-		config.WithRegion(os.Getenv("AWS_REGION")),
-		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
-			os.Getenv("AWS_ACCESS_KEY_ID"),
-			os.Getenv("AWS_SECRET_ACCESS_KEY"),
-			"",
-		)),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("unable to load SDK config, %v", err)
-	}
-
-	return s3.NewFromConfig(cfg), nil
-}
-
-func uploadFileToS3(client *s3.Client, file multipart.File, bucketName, key string) (string, error) {
+// uploadFile stores an uploaded form file under key and returns its URL.
+// Private files are only reachable through URLs signed by s.URLSigner.
+func (s *Server) uploadFile(file multipart.File, size int64, contentType, key string, public bool) (string, error) {
 	defer file.Close()
-
-	// Read the file content
-	fileContent, err := io.ReadAll(file)
-	if err != nil {
-		fmt.Printf("Error reading file content: %v\n", err) // Log the error
-		return "", fmt.Errorf("failed to read file content: %v", err)
-	}
-
-	//     // Log bucket and key information
-	fmt.Printf("Uploading to bucket: %s\n", bucketName)
-	fmt.Printf("Uploading with key: %s\n", key)
-
-	// Upload the file to S3
-	_, err = client.PutObject(context.TODO(), &s3.PutObjectInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(key),
-		Body:   bytes.NewReader(fileContent),
-		ACL:    types.ObjectCannedACLPublicRead,
-	})
-	if err != nil {
-		fmt.Printf("Error uploading file to S3: %v\n", err) // Log the error
-		return "", fmt.Errorf("failed to upload file to S3x: %v", err)
-	}
-
-	// Log successful upload
-	fileURL := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucketName, os.Getenv("AWS_REGION"), key)
-	fmt.Printf("File uploaded successfully, URL: %s\n", fileURL) // Log the URL
-
-	return fileURL, nil
+	return s.Storage.Put(context.TODO(), key, file, size, storage.PutOptions{ContentType: contentType, Public: public})
 }
 
 // Define allowed MIME types and max file size
@@ -159,22 +111,15 @@ func (s *Server) handleUpdateUserImageUrl() gin.HandlerFunc {
 			return
 		}
 
-		// Create S3 client
-		s3Client, err := createS3Client()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create S3 client"})
-			return
-		}
-
 		userIDString := strconv.FormatUint(uint64(userID), 10)
 
 		// Generate unique filename
 		filename := userIDString + "_" + fileHeader.Filename
 
-		// Upload file to S3
-		filepath, err := uploadFileToS3(s3Client, file, os.Getenv("AWS_BUCKET"), filename)
+		// Upload file to storage
+		filepath, err := s.uploadFile(file, fileHeader.Size, fileHeader.Header.Get("Content-Type"), filename, true)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload file to S3xx"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload file"})
 			return
 		}
 
@@ -224,19 +169,12 @@ func (s *Server) handleSignup() gin.HandlerFunc {
         if err == nil {
             defer file.Close()
 
-            // Create S3 client
-            s3Client, err := createS3Client()
-            if err != nil {
-                response.JSON(c, "", http.StatusInternalServerError, nil, err)
-                return
-            }
-
             // Generate unique filename
             userID := c.PostForm("user_id")
             filename := fmt.Sprintf("%s_%s", userID, handler.Filename)
 
-            // Upload file to S3
-            filePath, err = uploadFileToS3(s3Client, file, os.Getenv("AWS_BUCKET"), filename)
+            // Upload file to storage
+            filePath, err = s.uploadFile(file, handler.Size, handler.Header.Get("Content-Type"), filename, true)
             if err != nil {
                 response.JSON(c, "", http.StatusInternalServerError, nil, err)
                 return
@@ -777,7 +715,7 @@ type AuthPayload struct {
 
 // AuthRequest represents the authentication request structure.
 type AuthRequest struct {
-	email string `json:"email"`
+	Email string `json:"email"`
 }
 
 var AccessTokenDuration = 15 * time.Minute
@@ -975,7 +913,7 @@ func (s *Server) SocialAuthenticate(authRequest *AuthRequest, authPayloadOption
 	}
 
 	// Get email from authRequest
-	email := authRequest.email
+	email := authRequest.Email
 
 	// Fetch the role from the repository based on userID
 	userRole, err := s.AuthRepository.GetUserRoleByUserID(userIDUint)
@@ -1206,12 +1144,19 @@ func (s *Server) handleShowProfile() gin.HandlerFunc {
 			return
 		}
 
+		badges, err := s.BadgeService.GetUserBadges(userIDStr)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user badges"})
+			return
+		}
+
 		// Prepare response data with the necessary fields
 		responseData := gin.H{
 			"email":        user.Email,
 			"name":         user.Fullname,
 			"profileImage": user.ThumbNailURL,
 			"username":     user.Username,
+			"badges":       badges,
 		}
 
 		// Return the response with the user's profile data
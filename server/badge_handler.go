@@ -0,0 +1,55 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// handleGetBadges lists the badges contributors can earn.
+func (s *Server) handleGetBadges() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		badges, err := s.BadgeService.ListBadges()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"badges": badges})
+	}
+}
+
+// handleGetPublicProfile shows another user's public profile with the badges
+// they earned. Anonymous users keep their name hidden.
+func (s *Server) handleGetPublicProfile() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := uintParam(c, "id")
+		if !ok {
+			return
+		}
+		user, err := s.AuthRepository.FindUserByID(id)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		badges, err := s.BadgeService.GetUserBadges(id)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		profile := gin.H{
+			"id":           user.ID,
+			"username":     user.Username,
+			"name":         user.Fullname,
+			"profileImage": user.ThumbNailURL,
+			"is_verified":  user.IsVerified,
+			"badges":       badges,
+		}
+		if user.IsAnonymous {
+			profile["username"] = "Anonymous"
+			profile["name"] = ""
+			profile["profileImage"] = ""
+		}
+		c.JSON(http.StatusOK, gin.H{"profile": profile})
+	}
+}
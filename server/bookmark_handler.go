@@ -1 +1,184 @@
 package server
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/techagentng/citizenx/services"
+)
+
+// bookmarkErrorStatus maps bookmark errors onto HTTP status codes.
+func bookmarkErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrReportNotFound), errors.Is(err, services.ErrBookmarkNotFound),
+		errors.Is(err, services.ErrBookmarkCollectionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidBookmarkCollection):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrBookmarkCollectionExists):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+type bookmarkRequest struct {
+	// CollectionID files the bookmark in a collection; zero takes it out of
+	// its collection and leaving it out keeps the current one
+	CollectionID *uint `json:"collection_id"`
+}
+
+type bookmarkCollectionRequest struct {
+	Name string `json:"name" binding:"required"`
+}
+
+func bookmarkReportID(c *gin.Context) (uuid.UUID, bool) {
+	reportID, err := uuid.Parse(c.Param("reportID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid report ID format"})
+		return uuid.Nil, false
+	}
+	return reportID, true
+}
+
+// HandleBookmarkReport adds a report to the caller's bookmarks, optionally in
+// a collection. Bookmarking a report twice is not an error.
+func (s *Server) HandleBookmarkReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("userID").(uint)
+		reportID, ok := bookmarkReportID(c)
+		if !ok {
+			return
+		}
+		var req bookmarkRequest
+		if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		bookmark, err := s.BookmarkService.Bookmark(userID, reportID, req.CollectionID)
+		if err != nil {
+			c.JSON(bookmarkErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Report bookmarked successfully", "bookmark": bookmark})
+	}
+}
+
+// HandleUnbookmarkReport removes a report from the caller's bookmarks.
+func (s *Server) HandleUnbookmarkReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("userID").(uint)
+		reportID, ok := bookmarkReportID(c)
+		if !ok {
+			return
+		}
+		if err := s.BookmarkService.Unbookmark(userID, reportID); err != nil {
+			c.JSON(bookmarkErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Bookmark removed"})
+	}
+}
+
+// HandleGetBookmarkedReports lists a page of the caller's bookmarked reports,
+// only those in a collection when ?collection_id= is given.
+func (s *Server) HandleGetBookmarkedReports() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("userID").(uint)
+		page, err := getPageFromQuery(c)
+		if err != nil || page < 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+			return
+		}
+		var collectionID *uint
+		if raw := c.Query("collection_id"); raw != "" {
+			id, err := strconv.ParseUint(raw, 10, 32)
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collection_id"})
+				return
+			}
+			value := uint(id)
+			collectionID = &value
+		}
+
+		reports, err := s.BookmarkService.GetBookmarkedReports(userID, collectionID, page)
+		if err != nil {
+			c.JSON(bookmarkErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"bookmarked_reports": s.presentReports(c, reports),
+			"page":               page,
+		})
+	}
+}
+
+func (s *Server) handleGetBookmarkCollections() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("userID").(uint)
+		collections, err := s.BookmarkService.ListCollections(userID)
+		if err != nil {
+			c.JSON(bookmarkErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"collections": collections})
+	}
+}
+
+func (s *Server) handleCreateBookmarkCollection() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("userID").(uint)
+		var req bookmarkCollectionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		collection, err := s.BookmarkService.CreateCollection(userID, req.Name)
+		if err != nil {
+			c.JSON(bookmarkErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"collection": collection})
+	}
+}
+
+func (s *Server) handleRenameBookmarkCollection() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("userID").(uint)
+		id, ok := uintParam(c, "id")
+		if !ok {
+			return
+		}
+		var req bookmarkCollectionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		collection, err := s.BookmarkService.RenameCollection(userID, id, req.Name)
+		if err != nil {
+			c.JSON(bookmarkErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"collection": collection})
+	}
+}
+
+// handleDeleteBookmarkCollection deletes a collection; its reports stay
+// bookmarked.
+func (s *Server) handleDeleteBookmarkCollection() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("userID").(uint)
+		id, ok := uintParam(c, "id")
+		if !ok {
+			return
+		}
+		if err := s.BookmarkService.DeleteCollection(userID, id); err != nil {
+			c.JSON(bookmarkErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Collection deleted"})
+	}
+}
@@ -0,0 +1,191 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/techagentng/citizenx/services"
+)
+
+// categoryErrorStatus maps category service errors onto HTTP status codes.
+func categoryErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrCategoryNotFound), errors.Is(err, services.ErrSubCategoryNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrCategoryExists), errors.Is(err, services.ErrSubCategoryExists):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrInvalidCategory), errors.Is(err, services.ErrUnknownCategory), errors.Is(err, services.ErrUnknownSubCategory),
+		errors.Is(err, services.ErrInvalidFormSchema), errors.Is(err, services.ErrInvalidAttributes),
+		errors.Is(err, services.ErrInvalidSLA):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func uintParam(c *gin.Context, name string) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param(name), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + name})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// handleGetAllCategories lists the active categories and sub-types in display
+// order, translated when ?lang= names a language the admins have filled in.
+func (s *Server) handleGetAllCategories() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		categories, err := s.CategoryService.ListCategories(false, c.Query("lang"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"categories": categories})
+	}
+}
+
+// handleGetCategoryForm serves the form definition for one category: its
+// field schema and the sub-types a report can be filed under.
+func (s *Server) handleGetCategoryForm() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		category, err := s.CategoryService.GetCategoryForm(c.Param("slug"), c.Query("lang"))
+		if err != nil {
+			c.JSON(categoryErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"category":    category.Slug,
+			"name":        category.Name,
+			"form_schema": category.FormSchema,
+			"sub_types":   category.SubTypes,
+		})
+	}
+}
+
+// handleAdminGetCategories lists the whole taxonomy, inactive entries included.
+func (s *Server) handleAdminGetCategories() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		categories, err := s.CategoryService.ListCategories(true, "")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"categories": categories})
+	}
+}
+
+func (s *Server) handleCreateCategory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input services.CategoryInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		category, err := s.CategoryService.CreateCategory(input)
+		if err != nil {
+			c.JSON(categoryErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"category": category})
+	}
+}
+
+func (s *Server) handleUpdateCategory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := uintParam(c, "id")
+		if !ok {
+			return
+		}
+		var input services.CategoryInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		category, err := s.CategoryService.UpdateCategory(id, input)
+		if err != nil {
+			c.JSON(categoryErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"category": category})
+	}
+}
+
+// handleDeleteCategory removes a category and its sub-types. Reports filed
+// under it keep their category name; deactivating is usually preferable.
+func (s *Server) handleDeleteCategory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := uintParam(c, "id")
+		if !ok {
+			return
+		}
+		if err := s.CategoryService.DeleteCategory(id); err != nil {
+			c.JSON(categoryErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Category deleted"})
+	}
+}
+
+func (s *Server) handleCreateSubCategory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		categoryID, ok := uintParam(c, "id")
+		if !ok {
+			return
+		}
+		var input services.CategoryInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		sub, err := s.CategoryService.CreateSubCategory(categoryID, input)
+		if err != nil {
+			c.JSON(categoryErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"sub_type": sub})
+	}
+}
+
+func (s *Server) handleUpdateSubCategory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		categoryID, ok := uintParam(c, "id")
+		if !ok {
+			return
+		}
+		id, ok := uintParam(c, "sub_id")
+		if !ok {
+			return
+		}
+		var input services.CategoryInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		sub, err := s.CategoryService.UpdateSubCategory(categoryID, id, input)
+		if err != nil {
+			c.JSON(categoryErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"sub_type": sub})
+	}
+}
+
+func (s *Server) handleDeleteSubCategory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		categoryID, ok := uintParam(c, "id")
+		if !ok {
+			return
+		}
+		id, ok := uintParam(c, "sub_id")
+		if !ok {
+			return
+		}
+		if err := s.CategoryService.DeleteSubCategory(categoryID, id); err != nil {
+			c.JSON(categoryErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Sub-category deleted"})
+	}
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/techagentng/citizenx/services"
+)
+
+// engagementErrorStatus maps engagement errors onto HTTP status codes.
+func engagementErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrReportNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrMetricsForbidden):
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+// viewerKey identifies who is viewing for deduplication: the signed in user,
+// else the device ID the app sends, else a hash of the client's address and
+// user agent so raw addresses are not stored.
+func viewerKey(c *gin.Context) string {
+	if userID, ok := c.Get("userID"); ok {
+		return fmt.Sprintf("user:%v", userID)
+	}
+	if device := c.GetHeader("X-Device-ID"); device != "" {
+		sum := sha256.Sum256([]byte(device))
+		return "device:" + hex.EncodeToString(sum[:12])
+	}
+	sum := sha256.Sum256([]byte(c.ClientIP() + "|" + c.Request.UserAgent()))
+	return "client:" + hex.EncodeToString(sum[:12])
+}
+
+// trackReportView counts a view for the public routes, where a failure to
+// count must not fail the page.
+func (s *Server) trackReportView(c *gin.Context, reportID string) {
+	if s.EngagementService == nil {
+		return
+	}
+	if err := s.EngagementService.TrackView(reportID, viewerKey(c), 0); err != nil {
+		log.Printf("Error tracking view of report %s: %v", reportID, err)
+	}
+}
+
+// trackReportShare counts a share link being opened.
+func (s *Server) trackReportShare(c *gin.Context, reportID string) {
+	if s.EngagementService == nil {
+		return
+	}
+	if err := s.EngagementService.TrackShare(reportID, viewerKey(c)); err != nil {
+		log.Printf("Error tracking share of report %s: %v", reportID, err)
+	}
+}
+
+// handleTrackReportView lets the app record that the user opened a report.
+func (s *Server) handleTrackReportView() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reportID := c.Param("id")
+		userID := c.MustGet("userID").(uint)
+		if err := s.EngagementService.TrackView(reportID, viewerKey(c), userID); err != nil {
+			c.JSON(engagementErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusAccepted, gin.H{"message": "View recorded"})
+	}
+}
+
+// handleGetReportMetrics shows a report's engagement to its reporter and
+// admins.
+func (s *Server) handleGetReportMetrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID := c.MustGet("userID").(uint)
+		metrics, err := s.EngagementService.GetMetrics(c.Param("id"), userID, c.GetString("user_role"))
+		if err != nil {
+			c.JSON(engagementErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"metrics": metrics})
+	}
+}
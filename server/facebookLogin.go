@@ -37,7 +37,7 @@ func (s *Server) handleFBLogin() gin.HandlerFunc {
 		var OAuth2Config = GetFacebookOAuthConfig()
 		state, err := generateJWTToken(s.Config.JWTSecret)
 		if err != nil {
-			fmt.Errorf("error generating token state: %v", err)
+			log.Printf("error generating token state: %v", err)
 		}
 		url := OAuth2Config.AuthCodeURL(state)
 		c.Redirect(http.StatusTemporaryRedirect, url)
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/techagentng/citizenx/models"
+	"github.com/techagentng/citizenx/services"
+)
+
+// respondWithFeed serves a page of a report feed for /incident_reports?feed=.
+// The next page is fetched by passing back next_cursor as cursor.
+func (s *Server) respondWithFeed(c *gin.Context, feed string, user *models.User) {
+	query := services.FeedQuery{
+		Mode:   feed,
+		User:   user,
+		Cursor: c.Query("cursor"),
+	}
+	if lat, lng := c.Query("lat"), c.Query("lng"); lat != "" || lng != "" {
+		latitude, latErr := strconv.ParseFloat(lat, 64)
+		longitude, lngErr := strconv.ParseFloat(lng, 64)
+		if latErr != nil || lngErr != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "lat and lng must both be numbers"})
+			return
+		}
+		query.Latitude, query.Longitude = &latitude, &longitude
+	}
+
+	page, err := s.FeedService.GetFeed(query)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, services.ErrInvalidFeed) || errors.Is(err, services.ErrInvalidCursor) || errors.Is(err, services.ErrFeedLocation) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"feed":             feed,
+		"incident_reports": s.presentReportMaps(c, page.Reports),
+		"next_cursor":      page.NextCursor,
+	})
+}
@@ -338,7 +338,7 @@ func VerifyResetToken(tokenString string) (*TokenClaims, error) {
 
 	if claims, ok := token.Claims.(*TokenClaims); ok && token.Valid {
 		if claims.TokenType != "password_reset_token" {
-			return nil, fmt.Errorf("invalid token type", http.StatusAccepted)
+			return nil, fmt.Errorf("invalid token type")
 		}
 		return claims, nil
 	}
@@ -23,6 +23,7 @@ import (
 	"github.com/techagentng/citizenx/errors"
 	"github.com/techagentng/citizenx/models"
 	"github.com/techagentng/citizenx/server/response"
+	"github.com/techagentng/citizenx/services"
 	jwtPackage "github.com/techagentng/citizenx/services/jwt"
 	"gorm.io/gorm"
 )
@@ -156,15 +157,6 @@ func CreateMediaCount(mediaTypeCounts map[string]int) (imageCount, videoCount, a
 	return imageCount, videoCount, audioCount
 }
 
-// Function to calculate the total number of media points
-func calculateMediaPoints(mediaTypeCounts map[string]int) int {
-	totalPoints := 0
-	for _, count := range mediaTypeCounts {
-		totalPoints += count
-	}
-	return totalPoints
-}
-
 type GeocodingResponse struct {
 	Results []struct {
 		AddressComponents []struct {
@@ -411,6 +403,30 @@ func (s *Server) handleIncidentReport() gin.HandlerFunc {
 		profileImage := c.GetString("profile_image")
 		isAnonymousStr := c.PostForm("is_anonymous")
 		isAnonymous := isAnonymousStr == "true"
+
+		// Reports can only be filed under a category from the taxonomy
+		reportCategory, subCategory, err := s.CategoryService.ResolveCategory(category, c.PostForm("sub_category"))
+		if err != nil {
+			c.JSON(categoryErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		category = reportCategory.Name
+		var subCategoryID *uint
+		if subCategory != nil {
+			subCategoryID = &subCategory.ID
+		}
+
+		rawAttributes, err := attributesFromForm(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		attributes, err := s.CategoryService.ValidateAttributes(reportCategory, rawAttributes)
+		if err != nil {
+			c.JSON(categoryErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+
 		reportType, err := s.IncidentReportRepository.GetReportTypeByCategory(category)
 		if err != nil && err != gorm.ErrRecordNotFound {
 			log.Printf("Error fetching report type: %v\n", err)
@@ -457,7 +473,10 @@ func (s *Server) handleIncidentReport() gin.HandlerFunc {
 			Address:         c.PostForm("address"),
 			Rating:          c.PostForm("rating"),
 			Category:        category,
-			ThumbnailURLs:   profileImage,
+			CategoryID:      reportCategory.ID,
+			SubCategoryID:   subCategoryID,
+			Attributes:      attributes,
+			ReporterImage:   profileImage,
 			TimeofIncidence: time.Now(),
 			ReportTypeID:    reportType.ID, 
 			IsAnonymous:    isAnonymous,
@@ -469,6 +488,7 @@ func (s *Server) handleIncidentReport() gin.HandlerFunc {
 			response.JSON(c, "Unable to save incident report", http.StatusInternalServerError, nil, err)
 			return
 		}
+		s.routeReport(incidentReport)
 
 		c.JSON(http.StatusCreated, gin.H{
 			"message":             "Incident Report Submitted Successfully",
@@ -479,6 +499,22 @@ func (s *Server) handleIncidentReport() gin.HandlerFunc {
 }
 
 
+// attributesFromForm reads the category specific fields of a submission,
+// sent either as a JSON object in the attributes field or as
+// attributes[name]=value form fields.
+func attributesFromForm(c *gin.Context) (map[string]interface{}, error) {
+	attributes := map[string]interface{}{}
+	if raw := strings.TrimSpace(c.PostForm("attributes")); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &attributes); err != nil {
+			return nil, fmt.Errorf("attributes must be a JSON object")
+		}
+	}
+	for name, value := range c.PostFormMap("attributes") {
+		attributes[name] = value
+	}
+	return attributes, nil
+}
+
 // Helper function to parse coordinates from the request form
 func parseCoordinates(c *gin.Context) (float64, float64, error) {
 	lat, lng := 0.0, 0.0
@@ -511,16 +547,20 @@ func (s *Server) handleUploadMedia() gin.HandlerFunc {
         }
 
         // Process media files and collect URLs and types
-        feedURLs, fullsizeURLs, _, _, fileTypes, err := s.processAndSaveMedia(c)
+        feedURLs, _, fullsizeURLs, fileTypes, queuedIDs, err := s.processAndSaveMedia(c)
         if err != nil {
             log.Printf("Error processing media: %v", err)
             response.JSON(c, "Unable to process media files", http.StatusInternalServerError, nil, err)
             return
         }
 
-        // Calculate points (10 points per media file)
-        mediaCount := len(feedURLs) + len(fileTypes) // Count all media items
-        points := mediaCount * 10
+        // Count the media items the reward rules pay for: every stored item
+        // has one file type, and reused photos are stored but earn no points
+        duplicates, err := s.MediaRepository.CountDuplicateMediaByFeedURLs(feedURLs)
+        if err != nil {
+            log.Printf("Error checking duplicate media: %v", err)
+        }
+        mediaCount := len(fileTypes) - int(duplicates)
 
         // Retrieve user ID from context
         userI, exists := c.Get("user")
@@ -535,22 +575,31 @@ func (s *Server) handleUploadMedia() gin.HandlerFunc {
             return
         }
 
-        // Save the reward
-        reward := &models.Reward{
-            IncidentReportID: reportID,
-            UserID:           user.ID,
-            RewardType:       "media_upload",
-            Point:            points,
-            Balance:          points, 
-        }
-
-        if err := s.RewardRepository.SaveReward(reward); err != nil {
-            log.Printf("Error saving reward: %v\n", err)
-            response.JSON(c, "Unable to save reward", http.StatusInternalServerError, nil, err)
-            return
+        // Credit the media bonus, keyed by the stored media so a retried
+        // upload is paid once
+        var points int
+        if stored := append(feedURLs, queuedIDs...); len(stored) > 0 {
+            event := services.RewardEvent{
+                Action:   models.RewardActionMediaItem,
+                UserID:   user.ID,
+                ReportID: reportID,
+                Quantity: mediaCount,
+                Key:      services.MediaBonusKey(stored[0]),
+            }
+            if report, err := s.IncidentReportRepository.GetReportByID(reportID); err == nil {
+                event.CategoryID = report.CategoryID
+                event.Verified = report.IsVerified
+            }
+            points, err = s.RewardEngine.Award(event)
+            if err != nil {
+                log.Printf("Error saving reward: %v\n", err)
+                response.JSON(c, "Unable to save reward", http.StatusInternalServerError, nil, err)
+                return
+            }
         }
+        s.BadgeService.EvaluateAsync(user.ID, services.BadgeEventMediaAdded)
 
-		updatedBalance, err := s.RewardRepository.GetUserRewardBalance(user.ID)
+		updatedBalance, err := s.LedgerService.GetBalance(user.ID)
 if err != nil {
     response.JSON(c, "Could not retrieve updated reward balance", http.StatusInternalServerError, nil, err)
     return
@@ -558,8 +607,8 @@ if err != nil {
 
 response.JSON(c, "Media added to report successfully", http.StatusOK, gin.H{
     "reportID":     reportID,
-    "feedURLs":     feedURLs,
-    "fullsizeURLs": fullsizeURLs,
+    "feedURLs":     s.signURLs(c, feedURLs),
+    "fullsizeURLs": s.signURLs(c, fullsizeURLs),
     "fileTypes":    fileTypes,
     "reward": gin.H{
         "points":  points,
@@ -569,6 +618,9 @@ response.JSON(c, "Media added to report successfully", http.StatusOK, gin.H{
     }
 }
 
+// processAndSaveMedia stores the uploaded images and queues the rest. It
+// returns the feed, thumbnail and full size URLs of the images, the file type
+// of every stored item and the IDs of the queued media.
 func (s *Server) processAndSaveMedia(c *gin.Context) ([]string, []string, []string, []string, []string, error) {
 	// Parse multipart form with a size limit
 	if err := c.Request.ParseMultipartForm(100 << 20); err != nil {
@@ -585,7 +637,8 @@ func (s *Server) processAndSaveMedia(c *gin.Context) ([]string, []string, []stri
 
 	// Initialize URL and file type slices
 	var feedURLs, thumbnailURLs, fullsizeURLs, fileTypes []string
-	var videoURL, audioURL string
+	var pendingMedia []*multipart.FileHeader
+	var queuedIDs []string
 
 	// Retrieve userID from context
 // Retrieve userID from context
@@ -629,20 +682,16 @@ if !ok {
 			if mediaFile.Size > 100*1024*1024 {
 				return nil, nil, nil, nil, nil, fmt.Errorf("video file %s exceeds the 100 MB size limit", mediaFile.Filename)
 			}
-			videoURL, err = s.MediaService.UploadFileToS3(mediaFile, userID, "video")
-			if err == nil {
-				feedURLs = append(feedURLs, videoURL)
-				fileTypes = append(fileTypes, "video")
-			}
+			// Videos are transcoded to HLS in the background once the report is saved
+			pendingMedia = append(pendingMedia, mediaFile)
+			fileTypes = append(fileTypes, "video")
 		case "audio":
 			if mediaFile.Size > 50*1024*1024 {
 				return nil, nil, nil, nil, nil, fmt.Errorf("audio file %s exceeds the 50 MB size limit", mediaFile.Filename)
 			}
-			audioURL, err = s.MediaService.UploadFileToS3(mediaFile, userID, "audio")
-			if err == nil {
-				feedURLs = append(feedURLs, audioURL)
-				fileTypes = append(fileTypes, "audio")
-			}
+			// Audio is transcribed in the background along with the videos
+			pendingMedia = append(pendingMedia, mediaFile)
+			fileTypes = append(fileTypes, "audio")
 		default:
 			log.Printf("Unsupported file type for %s", mediaFile.Filename)
 			return nil, nil, nil, nil, nil, fmt.Errorf("unsupported file type: %s", fileType)
@@ -654,28 +703,19 @@ if !ok {
 		}
 	}
 
-	// Update incident report with the processed URLs
-	incidentReport, err := s.IncidentReportRepository.GetIncidentReportByID(reportID)
-	if err != nil {
-		log.Printf("Error retrieving report: %v", err)
-		return nil, nil, nil, nil, nil, fmt.Errorf("error retrieving report: %v", err)
-	}
-
-	incidentReport.FeedURLs = strings.Join(feedURLs, ",")
-	incidentReport.ThumbnailURLs = strings.Join(thumbnailURLs, ",")
-	incidentReport.FullSizeURLs = strings.Join(fullsizeURLs, ",")
-	incidentReport.VideoURL = videoURL
-	incidentReport.AudioURL = audioURL
-
-	// Save the updated report
-	if err := s.IncidentReportRepository.UpdateIncidentReport(incidentReport); err != nil {
-		log.Printf("Error updating incident report: %v", err)
-		return nil, nil, nil, nil, nil, fmt.Errorf("error updating incident report: %v", err)
+	// Images are already saved as media rows; the rest are processed in the background
+	for _, mediaFile := range pendingMedia {
+		media, err := s.MediaJobQueue.EnqueueUpload(mediaFile, userID, reportID)
+		if err != nil {
+			log.Printf("Error queueing media file %s: %v", mediaFile.Filename, err)
+			return nil, nil, nil, nil, nil, fmt.Errorf("error queueing media file: %v", err)
+		}
+		queuedIDs = append(queuedIDs, media.ID)
 	}
 
 	// Return the final URLs
 	log.Println("Media processed and saved successfully")
-	return feedURLs, thumbnailURLs, fullsizeURLs, fileTypes, nil, nil
+	return feedURLs, thumbnailURLs, fullsizeURLs, fileTypes, queuedIDs, nil
 }
 
 
@@ -704,13 +744,19 @@ func (s *Server) handleGetAllReport() gin.HandlerFunc {
 		}
 
 		log.Printf("currentUser: %v", currentUser)
-		_, ok := currentUser.(*models.User)
+		user, ok := currentUser.(*models.User)
 		if !ok {
 			log.Printf("Failed to assert currentUser type: %T", currentUser)
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user type"})
 			return
 		}
 
+		// A feed mode pages through ranked reports
+		if feed := c.Query("feed"); feed != "" {
+			s.respondWithFeed(c, feed, user)
+			return
+		}
+
 		// Just fetch all reports — no state filtering
 		reports, err := s.IncidentReportService.GetAllReports("") 
 		if err != nil {
@@ -720,7 +766,7 @@ func (s *Server) handleGetAllReport() gin.HandlerFunc {
 		}
 
 		c.JSON(http.StatusOK, gin.H{
-			"incident_reports": reports,
+			"incident_reports": s.presentReportMaps(c, reports),
 		})
 	}
 }
@@ -747,7 +793,7 @@ func (s *Server) handleGetAllReportsByState() gin.HandlerFunc {
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{"incident_reports": reports})
+		c.JSON(http.StatusOK, gin.H{"incident_reports": s.presentReports(c, reports)})
 	}
 }
 
@@ -771,7 +817,7 @@ func (s *Server) handleGetAllReportsByLGA() gin.HandlerFunc {
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{"incident_reports": reports})
+		c.JSON(http.StatusOK, gin.H{"incident_reports": s.presentReports(c, reports)})
 	}
 }
 
@@ -795,7 +841,70 @@ func (s *Server) handleGetAllReportsByReportType() gin.HandlerFunc {
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{"incident_reports": reports})
+		c.JSON(http.StatusOK, gin.H{"incident_reports": s.presentReports(c, reports)})
+	}
+}
+
+// handleSearchReports finds reports whose description or audio transcript
+// contains the q parameter and whose attributes match any attr[name]=value
+// parameters.
+func (s *Server) handleSearchReports() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		attributes, err := attributeFilters(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		query := strings.TrimSpace(c.Query("q"))
+		if len(query) < 2 && len(attributes) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Search query must be at least 2 characters"})
+			return
+		}
+
+		page, err := getPageFromQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+			return
+		}
+
+		reports, err := s.IncidentReportService.SearchReports(query, attributes, page)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"incident_reports": s.presentReports(c, reports)})
+	}
+}
+
+// attributeFilters reads attr[name]=value query parameters used to filter
+// reports on category specific fields.
+func attributeFilters(c *gin.Context) (map[string]string, error) {
+	attributes := c.QueryMap("attr")
+	for name := range attributes {
+		if !models.IsAttributeName(name) {
+			return nil, fmt.Errorf("invalid attribute filter %q", name)
+		}
+	}
+	return attributes, nil
+}
+
+// handleGetAttributeCounts counts reports per value of a category specific
+// field, optionally narrowed to a category, state and LGA.
+func (s *Server) handleGetAttributeCounts() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		field := c.Param("field")
+		if !models.IsAttributeName(field) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attribute name"})
+			return
+		}
+
+		counts, err := s.IncidentReportRepository.CountReportsByAttribute(field, c.Query("category"), c.Query("state"), c.Query("lga"))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"field": field, "counts": counts})
 	}
 }
 
@@ -825,11 +934,6 @@ func (s *Server) handleApproveReportPoints() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Retrieve the report by ID
 		reportID := c.Param("reportID")
-		userID := c.Param("userID")
-		userID64, err := strconv.ParseUint(userID, 10, 32)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid userID"})
-		}
 
 		if reportID == "" {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Report ID is required"})
@@ -849,7 +953,7 @@ func (s *Server) handleApproveReportPoints() gin.HandlerFunc {
 		}
 
 		// Reward points to the user for the approved report
-		if err := s.RewardService.ApproveReportPoints(reportID, uint(userID64)); err != nil {
+		if err := s.RewardService.ApproveReportPoints(reportID); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
@@ -862,11 +966,6 @@ func (s *Server) handleRejectReportPoints() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Retrieve the report by ID
 		reportID := c.Param("reportID")
-		userID := c.Param("userID")
-		userID64, err := strconv.ParseUint(userID, 10, 32)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid userID"})
-		}
 
 		if reportID == "" {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Report ID is required"})
@@ -886,10 +985,11 @@ func (s *Server) handleRejectReportPoints() gin.HandlerFunc {
 		}
 
 		// Reward points to the user for the approved report
-		if err := s.RewardService.RejectReportPoints(reportID, uint(userID64)); err != nil {
+		if err := s.RewardService.RejectReportPoints(reportID); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+		s.forgetReportMedia(report.ID)
 
 		c.JSON(http.StatusOK, gin.H{"message": "Report rejected successfully"})
 	}
@@ -899,11 +999,6 @@ func (s *Server) handleAcceptReportPoints() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Retrieve the report by ID
 		reportID := c.Param("reportID")
-		userID := c.Param("userID")
-		userID64, err := strconv.ParseUint(userID, 10, 32)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid userID"})
-		}
 
 		if reportID == "" {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Report ID is required"})
@@ -923,7 +1018,7 @@ func (s *Server) handleAcceptReportPoints() gin.HandlerFunc {
 		}
 
 		// Reward points to the user for the approved report
-		if err := s.RewardService.AcceptReportPoints(reportID, uint(userID64)); err != nil {
+		if err := s.RewardService.AcceptReportPoints(reportID); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
@@ -1051,7 +1146,7 @@ func (s *Server) handleGetAllReportsByStateByTime() gin.HandlerFunc {
 			return
 		}
 
-		c.JSON(http.StatusOK, reports)
+		c.JSON(http.StatusOK, s.presentReports(c, reports))
 	}
 }
 
@@ -1119,6 +1214,12 @@ func (s *Server) handleGetReportTypeCounts() gin.HandlerFunc {
 			return
 		}
 
+		sla, err := s.AgencyService.GetSLAMetrics(state, lga, startDate, endDate)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+
 		// Convert the slice of StateReportCount to a map
 		topStatesMap := make(map[string]int)
 		for _, stateReport := range topStates {
@@ -1131,6 +1232,7 @@ func (s *Server) handleGetReportTypeCounts() gin.HandlerFunc {
 			"total_users":   totalUsers,
 			"total_reports": totalReports,
 			"top_states":    topStatesMap,
+			"sla":           sla,
 		})
 	}
 }
@@ -1312,17 +1414,6 @@ func (s *Server) HandleGetVariadicBarChart() gin.HandlerFunc {
 	}
 }
 
-func (s *Server) handleGetAllCategories() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		categories, err := s.IncidentReportRepository.GetAllCategories()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
-			return
-		}
-		c.JSON(http.StatusOK, gin.H{"categories": categories})
-	}
-}
-
 func (s *Server) handleGetAllStates() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		states, err := s.IncidentReportRepository.GetAllStates()
@@ -1354,6 +1445,13 @@ func (s *Server) handleGetRatingPercentages() gin.HandlerFunc {
 			return
 		}
 
+		confirmation, err := s.ResolutionService.GetConfirmationRates(reportType, state)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch resolution confirmation rates"})
+			return
+		}
+		percentages.ResolutionConfirmation = confirmation
+
 		c.JSON(http.StatusOK, percentages)
 	}
 }
@@ -1469,7 +1567,7 @@ func (s *Server) HandleGetAllReportsByUser() gin.HandlerFunc {
 		}
 
 		// Return the reports as a JSON response
-		c.JSON(http.StatusOK, gin.H{"reports": reports})
+		c.JSON(http.StatusOK, gin.H{"reports": s.presentReportMaps(c, reports)})
 	}
 }
 
@@ -1477,100 +1575,19 @@ func (s *Server) HandleGetAllReportsByUser() gin.HandlerFunc {
 func (s *Server) HandleGetVoteCounts() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		reportID := c.Param("reportID")
-		upvotes, downvotes, err := s.LikeService.GetVoteCounts(reportID)
+		userID := c.MustGet("userID").(uint)
+		counts, err := s.LikeService.GetVoteCounts(reportID, userID)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.JSON(voteErrorStatus(err), gin.H{"error": err.Error()})
 			return
 		}
 
 		c.JSON(http.StatusOK, gin.H{
-			"upvotes":   upvotes,
-			"downvotes": downvotes,
-		})
-	}
-}
-
-func (s *Server) HandleBookmarkReport() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// Get userID from context
-		userIDCtx, exists := c.Get("userID")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"error": "user not authenticated",
-			})
-			return
-		}
-
-		userID, ok := userIDCtx.(uint)
-		if !ok {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "invalid user ID format",
-			})
-			return
-		}
-
-		// Get and parse reportID
-		reportIDStr := c.Param("reportID")
-		reportID, err := uuid.Parse(reportIDStr)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error": "invalid report ID format",
-			})
-			return
-		}
-
-		// Call the bookmark service
-		err = s.IncidentReportService.BookmarkReport(userID, reportID)
-		if err != nil {
-			status := http.StatusInternalServerError
-
-			// Handle specific error cases
-			switch err.Error() {
-			case "report not found":
-				status = http.StatusNotFound
-			case "report already bookmarked":
-				status = http.StatusConflict
-			}
-
-			c.JSON(status, gin.H{
-				"error": err.Error(),
-			})
-			return
-		}
-
-		// Success response
-		c.JSON(http.StatusOK, gin.H{
-			"message": "Report bookmarked successfully",
-		})
-	}
-}
-
-func (s *Server) HandleGetBookmarkedReports() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		// Extract user ID from context
-		userIDCtx, ok := c.Get("userID")
-		if !ok {
-			response.JSON(c, "", http.StatusInternalServerError, nil, errors.New("userID not found in context", http.StatusInternalServerError))
-			return
-		}
-
-		// Assert the type of userID as uint
-		userID, ok := userIDCtx.(uint)
-		if !ok {
-			response.JSON(c, "", http.StatusInternalServerError, nil, errors.New("userID is not of type uint", http.StatusInternalServerError))
-			return
-		}
-
-		// Fetch bookmarked reports
-		bookmarkedReports, err := s.IncidentReportService.GetBookmarkedReports(userID)
-		if err != nil {
-			response.JSON(c, "", http.StatusInternalServerError, nil, err)
-			return
-		}
-
-		// Return the bookmarked reports
-		c.JSON(http.StatusOK, gin.H{
-			"bookmarked_reports": bookmarkedReports,
+			"upvotes":            counts.Upvotes,
+			"downvotes":          counts.Downvotes,
+			"weighted_upvotes":   counts.WeightedUpvotes,
+			"weighted_downvotes": counts.WeightedDownvotes,
+			"user_vote":          counts.UserVote,
 		})
 	}
 }
@@ -1656,9 +1673,14 @@ func (s *Server) handleGetReportsByFilters() gin.HandlerFunc {
 		category := c.Query("category")
 		state := c.Query("state")
 		lga := c.Query("lga")
+		attributes, err := attributeFilters(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 
 		// Call the repository function with all filters
-		reports, filters, err := s.IncidentReportRepository.GetFilteredIncidentReports(category, state, lga)
+		reports, filters, err := s.IncidentReportRepository.GetFilteredIncidentReports(category, state, lga, attributes)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -1671,7 +1693,7 @@ func (s *Server) handleGetReportsByFilters() gin.HandlerFunc {
 
 		// Return the reports and the applied filters
 		c.JSON(http.StatusOK, gin.H{
-			"reports": reports,
+			"reports": s.presentReports(c, reports),
 			"filters": filters,
 		})
 	}
@@ -1701,6 +1723,7 @@ func (s *Server) UpdateBlockRequestHandler() gin.HandlerFunc {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
 		}
+		s.forgetReportMedia(reportID)
 
 		// Return a success response with the provided message and report ID
 		c.JSON(http.StatusOK, gin.H{
@@ -1852,18 +1875,29 @@ func (s *Server) HandleFollowReport() gin.HandlerFunc {
                 return
             }
 
-            // Create S3 client
-            s3Client, err := createS3Client()
-            if err != nil {
-                c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create S3 client"})
-                return
-            }
-
             // Generate a unique filename for the media
             mediaFilename := fmt.Sprintf("%s_%s", reportID.String(), handler.Filename)
 
-            // Upload the file to S3
-            mediaURL, err = uploadFileToS3(s3Client, file, os.Getenv("AWS_BUCKET"), mediaFilename)
+            // Photos never leave the server with their EXIF (GPS, device serial)
+            var upload multipart.File = file
+            uploadSize := handler.Size
+            if strings.HasPrefix(handler.Header.Get("Content-Type"), "image/") {
+                fileBytes, err := io.ReadAll(file)
+                if err != nil {
+                    c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read media"})
+                    return
+                }
+                stripped, err := services.StripImageMetadata(fileBytes)
+                if err != nil {
+                    c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to process image"})
+                    return
+                }
+                upload = services.NewByteFile(stripped)
+                uploadSize = int64(len(stripped))
+            }
+
+            // Upload the file to storage
+            mediaURL, err = s.uploadFile(upload, uploadSize, handler.Header.Get("Content-Type"), mediaFilename, false)
             if err != nil {
                 c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload media"})
                 return
@@ -1890,7 +1924,7 @@ func (s *Server) HandleFollowReport() gin.HandlerFunc {
             "message": "Successfully followed the report",
             "data": gin.H{
                 "followText":  followText,
-                "followMedia": mediaURL,
+                "followMedia": s.signURLs(c, []string{mediaURL})[0],
             },
             "notification": gin.H{
                 "status": "sent",
@@ -2111,7 +2145,13 @@ func uploadImage(ctx *gin.Context, s *Server, formField string, userID uint, cat
     if err != nil || file == nil {
         return ""
     }
-    url, err := s.MediaService.UploadFileToS3(file, userID, category)
+    upload, err := file.Open()
+    if err != nil {
+        return ""
+    }
+    // Official portraits are shown on public pages, so they are not signed
+    key := fmt.Sprintf("media/%d_%s_%s%s", userID, category, uuid.New().String(), filepath.Ext(file.Filename))
+    url, err := s.uploadFile(upload, file.Size, file.Header.Get("Content-Type"), key, true)
     if err != nil {
         return ""
     }
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/techagentng/citizenx/models"
+	"github.com/techagentng/citizenx/services"
+)
+
+// leaderboardErrorStatus maps leaderboard errors onto HTTP status codes.
+func leaderboardErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrInvalidLeaderboard):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrUserNotFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// handleGetLeaderboard lists the top earners of a period, across the country
+// or in one state or LGA.
+func (s *Server) handleGetLeaderboard() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, err := getPageFromQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+			return
+		}
+		period := c.DefaultQuery("period", models.LeaderboardWeekly)
+		scope := c.DefaultQuery("scope", models.LeaderboardGlobal)
+		entries, err := s.LeaderboardService.GetLeaderboard(period, scope, c.Query("name"), page)
+		if err != nil {
+			c.JSON(leaderboardErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"period": period, "scope": scope, "leaderboard": entries})
+	}
+}
+
+// handleGetLeaderboardStanding returns the user's own rank on a board.
+func (s *Server) handleGetLeaderboardStanding() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		period := c.DefaultQuery("period", models.LeaderboardWeekly)
+		scope := c.DefaultQuery("scope", models.LeaderboardGlobal)
+		userID := c.MustGet("userID").(uint)
+		standing, err := s.LeaderboardService.GetStanding(userID, period, scope)
+		if err != nil {
+			c.JSON(leaderboardErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"period": period, "scope": scope, "standing": standing})
+	}
+}
+
+func (s *Server) handleRebuildLeaderboards() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := s.LeaderboardService.Rebuild(); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Leaderboards rebuilt"})
+	}
+}
+
+func (s *Server) handleGetReputation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := uintParam(c, "id")
+		if !ok {
+			return
+		}
+		reputation, err := s.LeaderboardService.GetReputation(id)
+		if err != nil {
+			c.JSON(leaderboardErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"reputation": reputation})
+	}
+}
@@ -1,15 +1,33 @@
 package server
 
 import (
+	stderrors "errors"
 	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/techagentng/citizenx/errors"
 	"github.com/techagentng/citizenx/server/response"
+	"github.com/techagentng/citizenx/services"
 )
 
-// HandleUpvoteReport handles the upvoting of a report
+// voteErrorStatus maps vote errors onto HTTP status codes.
+func voteErrorStatus(err error) int {
+	switch {
+	case stderrors.Is(err, services.ErrReportNotFound):
+		return http.StatusNotFound
+	case stderrors.Is(err, services.ErrInvalidVote):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+type voteRequest struct {
+	Vote string `json:"vote" binding:"required"`
+}
+
+// HandleUpvoteReport upvotes a report, or retracts the upvote when the user
+// already upvoted it
 func (s *Server) HandleUpvoteReport() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		log.Println("Upvote handler called")
@@ -28,17 +46,18 @@ func (s *Server) HandleUpvoteReport() gin.HandlerFunc {
 		}
 
 		reportID := c.Param("reportID")
-		err := s.LikeService.LikeReport(userID, reportID)
+		counts, err := s.LikeService.LikeReport(userID, reportID)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.JSON(voteErrorStatus(err), gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{"message": "Upvoted successfully"})
+		c.JSON(http.StatusOK, gin.H{"message": "Vote updated successfully", "votes": counts})
 	}
 }
 
-// HandleDownvoteReport handles the downvoting of a report
+// HandleDownvoteReport downvotes a report, or retracts the downvote when the
+// user already downvoted it
 func (s *Server) HandleDownvoteReport() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Extract user ID and report ID from the request
@@ -56,12 +75,31 @@ func (s *Server) HandleDownvoteReport() gin.HandlerFunc {
 		}
 
 		reportID := c.Param("reportID")
-		err := s.LikeService.DownVoteReport(userID, reportID)
+		counts, err := s.LikeService.DownVoteReport(userID, reportID)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			c.JSON(voteErrorStatus(err), gin.H{"error": err.Error()})
 			return
 		}
 
-		c.JSON(http.StatusOK, gin.H{"message": "Downvoted successfully"})
+		c.JSON(http.StatusOK, gin.H{"message": "Vote updated successfully", "votes": counts})
+	}
+}
+
+// HandleVoteReport sets the user's vote on a report to upvote, downvote or
+// none, switching or retracting any earlier vote
+func (s *Server) HandleVoteReport() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req voteRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "vote is required"})
+			return
+		}
+		userID := c.MustGet("userID").(uint)
+		counts, err := s.LikeService.Vote(userID, c.Param("reportID"), req.Vote)
+		if err != nil {
+			c.JSON(voteErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Vote updated successfully", "votes": counts})
 	}
 }
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/techagentng/citizenx/storage"
+)
+
+// PlaylistPath is where HLS playlists are served from, relative to the API root.
+const PlaylistPath = "/api/v1/media/playlist"
+
+// handleGetPlaylist serves an HLS playlist with its segments signed. It is
+// public because video players cannot send our auth header; the signature in
+// the URL, handed out with the report, is what grants access.
+func (s *Server) handleGetPlaylist() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.URLSigner == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "playlist not found"})
+			return
+		}
+		key := strings.TrimPrefix(c.Param("key"), "/")
+		body, err := s.URLSigner.ServePlaylist(c.Request.Context(), key, c.Query("expires"), c.Query("signature"))
+		switch {
+		case errors.Is(err, storage.ErrInvalidSignature):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		case errors.Is(err, storage.ErrNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "playlist not found"})
+			return
+		case err != nil:
+			log.Printf("Error serving playlist %s: %v", key, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load playlist"})
+			return
+		}
+
+		c.Header("Cache-Control", "private, max-age=300")
+		c.Data(http.StatusOK, "application/vnd.apple.mpegurl", body)
+	}
+}
+
+// handleGetFlaggedMedia lists media whose transcript matched a moderation
+// keyword, for admins reviewing voice reports.
+func (s *Server) handleGetFlaggedMedia() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, err := getPageFromQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+			return
+		}
+
+		media, err := s.MediaRepository.GetFlaggedMedia(page)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		for i := range media {
+			media[i].FeedURL = s.signURLs(c, []string{media[i].FeedURL})[0]
+		}
+		c.JSON(http.StatusOK, gin.H{"media": media})
+	}
+}
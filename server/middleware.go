@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"sync"
 
 	// ratelimit "github.com/JGLTechnologies/gin-rate-limit"
@@ -285,3 +286,18 @@ func containsString(s []string, str string) bool {
 	}
 	return false
 }
+
+// requireRole rejects requests from users without the given role. It runs
+// after Authorize, which sets user_role.
+func requireRole(roles ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		role := c.GetString("user_role")
+		for _, allowed := range roles {
+			if role == allowed {
+				c.Next()
+				return
+			}
+		}
+		c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": strings.Join(roles, " or ") + " access required"})
+	}
+}
@@ -0,0 +1,161 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/techagentng/citizenx/models"
+	"github.com/techagentng/citizenx/services"
+)
+
+// maxResponseMedia caps the files attached to one official response.
+const maxResponseMedia = 5
+
+// officialResponseErrorStatus maps official response errors onto HTTP status
+// codes.
+func officialResponseErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrReportNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrEmptyResponse), errors.Is(err, services.ErrInvalidResolutionDue), errors.Is(err, services.ErrInvalidStatus):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrNotOfficial), errors.Is(err, services.ErrNotAgencyMember), errors.Is(err, services.ErrReportNotAssigned):
+		return http.StatusForbidden
+	}
+	return agencyErrorStatus(err)
+}
+
+// handlePostOfficialResponse lets an admin, or staff of an agency the report
+// is assigned to, respond to a report. It takes a multipart form with text,
+// an optional status, expected_resolution_date and media files.
+func (s *Server) handlePostOfficialResponse() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		reportID := c.Param("id")
+		if _, err := uuid.Parse(reportID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid report ID format"})
+			return
+		}
+
+		var files []*multipart.FileHeader
+		if form, err := c.MultipartForm(); err == nil {
+			files = form.File["media"]
+		}
+		if len(files) > maxResponseMedia {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("At most %d media files are allowed", maxResponseMedia)})
+			return
+		}
+
+		user, _ := c.MustGet("user").(*models.User)
+		role := c.GetString("user_role")
+		input := services.OfficialResponseInput{
+			Text:                   c.PostForm("text"),
+			Status:                 c.PostForm("status"),
+			ExpectedResolutionDate: c.PostForm("expected_resolution_date"),
+		}
+		// Nothing is stored for a response that would be turned down
+		if err := s.OfficialResponseService.CheckResponse(user, role, reportID, input); err != nil {
+			c.JSON(officialResponseErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		for _, header := range files {
+			if !isValidMediaType(header.Header.Get("Content-Type")) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid media type. Only image or video files are allowed"})
+				return
+			}
+		}
+
+		input.MediaURLs = make([]string, 0, len(files))
+		for _, header := range files {
+			mediaURL, err := s.uploadResponseMedia(reportID, header)
+			if err != nil {
+				s.deleteStoredMedia(input.MediaURLs)
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			input.MediaURLs = append(input.MediaURLs, mediaURL)
+		}
+
+		response, err := s.OfficialResponseService.PostResponse(user, role, reportID, input)
+		if err != nil {
+			s.deleteStoredMedia(input.MediaURLs)
+			c.JSON(officialResponseErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		response.MediaURLs = s.signURLs(c, response.MediaURLs)
+		c.JSON(http.StatusCreated, gin.H{"response": response})
+	}
+}
+
+// handleGetOfficialResponses lists the official responses on a report, kept
+// apart from citizen follow-ups.
+func (s *Server) handleGetOfficialResponses() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		responses, err := s.OfficialResponseService.GetResponses(c.Param("id"))
+		if err != nil {
+			c.JSON(officialResponseErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		for i := range responses {
+			responses[i].MediaURLs = s.signURLs(c, responses[i].MediaURLs)
+		}
+		c.JSON(http.StatusOK, gin.H{"responses": responses})
+	}
+}
+
+// uploadResponseMedia stores one attachment privately, stripping image
+// metadata first as follow-up media does.
+func (s *Server) uploadResponseMedia(reportID string, header *multipart.FileHeader) (string, error) {
+	contentType := header.Header.Get("Content-Type")
+	if !isValidMediaType(contentType) {
+		return "", fmt.Errorf("Invalid media type. Only image or video files are allowed")
+	}
+	file, err := header.Open()
+	if err != nil {
+		return "", fmt.Errorf("Failed to read media")
+	}
+	defer file.Close()
+
+	var upload multipart.File = file
+	uploadSize := header.Size
+	if strings.HasPrefix(contentType, "image/") {
+		fileBytes, err := io.ReadAll(file)
+		if err != nil {
+			return "", fmt.Errorf("Failed to read media")
+		}
+		stripped, err := services.StripImageMetadata(fileBytes)
+		if err != nil {
+			return "", fmt.Errorf("Failed to process image")
+		}
+		upload = services.NewByteFile(stripped)
+		uploadSize = int64(len(stripped))
+	}
+
+	key := fmt.Sprintf("responses/%s/%s_%s", reportID, uuid.New().String(), header.Filename)
+	mediaURL, err := s.uploadFile(upload, uploadSize, contentType, key, false)
+	if err != nil {
+		return "", fmt.Errorf("Failed to upload media")
+	}
+	return mediaURL, nil
+}
+
+// deleteStoredMedia removes attachments stored for a response that was not
+// saved.
+func (s *Server) deleteStoredMedia(urls []string) {
+	for _, u := range urls {
+		key, ok := s.Storage.KeyForURL(u)
+		if !ok {
+			continue
+		}
+		if err := s.Storage.Delete(context.Background(), key); err != nil {
+			log.Printf("Error deleting unused media %s: %v", key, err)
+		}
+	}
+}
@@ -1,16 +1,15 @@
 package server
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
 	"strconv"
 	"strings"
 
 	"html/template"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/techagentng/citizenx/models"
 	jwtPackage "github.com/techagentng/citizenx/services/jwt"
 )
@@ -64,22 +63,15 @@ func (s *Server) handleCreatePost() gin.HandlerFunc {
 			return
 		}
 
-		// Create S3 client
-		s3Client, err := createS3Client()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create S3 client"})
-			return
-		}
-
 		userIDString := strconv.FormatUint(uint64(userID), 10)
 
 		// Generate unique filename
 		filename := userIDString + "_" + fileHeader.Filename
 
-		// Upload file to S3
-		filepath, err := uploadFileToS3(s3Client, file, os.Getenv("AWS_BUCKET"), filename)
+		// Upload file to storage
+		filepath, err := s.uploadFile(file, fileHeader.Size, fileHeader.Header.Get("Content-Type"), filename, true)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload file to S3"})
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload file"})
 			return
 		}
 
@@ -190,6 +182,9 @@ func (s *Server) GetAppPostByID() gin.HandlerFunc{
         c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
         return
     }
+    s.trackReportView(c, report.ID.String())
+    view := report.ForAudience(models.AudiencePublic)
+    report = &view
 
     c.JSON(http.StatusOK, gin.H{
         "id":          report.ID,
@@ -208,7 +203,8 @@ func (s *Server) GetPostPreviewByID() gin.HandlerFunc {
         // Add proper headers
         c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
         c.Writer.Header().Set("Access-Control-Allow-Methods", "GET")
-        c.Writer.Header().Set("Cache-Control", "public, max-age=3600")
+        // Kept shorter than the validity of the signed image URL in the page
+        c.Writer.Header().Set("Cache-Control", "public, max-age=600")
         c.Writer.Header().Set("Content-Type", "text/html; charset=utf-8")
         
         
@@ -217,24 +213,28 @@ func (s *Server) GetPostPreviewByID() gin.HandlerFunc {
             c.String(http.StatusNotFound, "Post not found")
             return
         }
-
-        // Format image URL properly
+        // Previews are fetched when a shared link is opened or unfurled
+        s.trackReportShare(c, post.ID.String())
+        // Share previews are always rendered for the public, whoever follows the link
+        post.Media = s.loadReportMedia([]uuid.UUID{post.ID})[post.ID]
+        view := post.ForAudience(models.AudiencePublic)
+        post = &view
+
+        // The first image of the report is used for the link preview
         var imageURL string
-        if post.FeedURLs != "" {
-            // If FeedURLs is a JSON array, parse it
-            var urls []string
-            if err := json.Unmarshal([]byte(post.FeedURLs), &urls); err == nil && len(urls) > 0 {
-                imageURL = urls[0]
-            } else {
-                // If it's a single URL
-                imageURL = post.FeedURLs
-            }
-            
-            // Ensure URL is absolute
-            if !strings.HasPrefix(imageURL, "http") {
-                imageURL = "https://citizenx.ng" + imageURL
+        for _, item := range post.Media {
+            if item.FileType == "image" && item.FeedURL != "" {
+                imageURL = item.FeedURL
+                break
             }
         }
+        if imageURL != "" && s.URLSigner != nil {
+            imageURL = s.URLSigner.Sign(c.Request.Context(), imageURL)
+        }
+        // Ensure URL is absolute
+        if imageURL != "" && !strings.HasPrefix(imageURL, "http") {
+            imageURL = "https://citizenx.ng" + imageURL
+        }
 
         // Add proper meta tags for social sharing
         data := map[string]interface{}{
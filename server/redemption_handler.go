@@ -0,0 +1,133 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/techagentng/citizenx/services"
+)
+
+// redemptionErrorStatus maps redemption errors onto HTTP status codes.
+func redemptionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrRedemptionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidRedemption), errors.Is(err, services.ErrBelowMinimum):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrInsufficientPoints), errors.Is(err, services.ErrRedemptionNotPending), errors.Is(err, services.ErrRedemptionNotPayable):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+type redemptionRejectRequest struct {
+	Reason string `json:"reason" binding:"required"`
+}
+
+// handleRequestRedemption lets a user cash out points as airtime, data or a
+// bank transfer.
+func (s *Server) handleRequestRedemption() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input services.RedemptionInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "type and points are required"})
+			return
+		}
+		userID := c.MustGet("userID").(uint)
+		redemption, err := s.RedemptionService.RequestRedemption(userID, input)
+		if err != nil {
+			c.JSON(redemptionErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"redemption": redemption})
+	}
+}
+
+func (s *Server) handleGetUserRedemptions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, err := getPageFromQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+			return
+		}
+		userID := c.MustGet("userID").(uint)
+		redemptions, err := s.RedemptionService.GetUserRedemptions(userID, page)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"redemptions": redemptions})
+	}
+}
+
+// handleGetRedemptions lists redemptions for admins, optionally by status.
+func (s *Server) handleGetRedemptions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, err := getPageFromQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+			return
+		}
+		redemptions, err := s.RedemptionService.ListRedemptions(c.Query("status"), page)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"redemptions": redemptions})
+	}
+}
+
+func (s *Server) handleApproveRedemption() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := uintParam(c, "id")
+		if !ok {
+			return
+		}
+		adminID := c.MustGet("userID").(uint)
+		redemption, err := s.RedemptionService.ApproveRedemption(adminID, id)
+		if err != nil {
+			c.JSON(redemptionErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"redemption": redemption})
+	}
+}
+
+func (s *Server) handleRejectRedemption() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := uintParam(c, "id")
+		if !ok {
+			return
+		}
+		var req redemptionRejectRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "reason is required"})
+			return
+		}
+		adminID := c.MustGet("userID").(uint)
+		redemption, err := s.RedemptionService.RejectRedemption(adminID, id, req.Reason)
+		if err != nil {
+			c.JSON(redemptionErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"redemption": redemption})
+	}
+}
+
+// handleRefreshRedemption checks on a payout the provider is still
+// processing.
+func (s *Server) handleRefreshRedemption() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := uintParam(c, "id")
+		if !ok {
+			return
+		}
+		redemption, err := s.RedemptionService.RefreshRedemption(id)
+		if err != nil {
+			c.JSON(redemptionErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"redemption": redemption})
+	}
+}
@@ -0,0 +1,168 @@
+package server
+
+import (
+	"log"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/techagentng/citizenx/models"
+)
+
+// reportAudience works out which view of a report the caller is entitled to.
+// Routes outside the authorized group have no user in the context and always
+// get the public view.
+func reportAudience(c *gin.Context, ownerID uint) models.ReportAudience {
+	if c.GetString("user_role") == models.RoleAdmin {
+		return models.AudienceModerator
+	}
+	if userID, ok := c.Get("userID"); ok {
+		if id, ok := userID.(uint); ok && id != 0 && id == ownerID {
+			return models.AudienceOwner
+		}
+	}
+	return models.AudiencePublic
+}
+
+// presentReport returns the view of a single report for the caller, with its
+// media attached and the media URLs signed.
+func (s *Server) presentReport(c *gin.Context, report models.IncidentReport) models.IncidentReport {
+	return s.presentReports(c, []models.IncidentReport{report})[0]
+}
+
+// presentReports returns the view of each report for the caller.
+func (s *Server) presentReports(c *gin.Context, reports []models.IncidentReport) []models.IncidentReport {
+	ids := make([]uuid.UUID, 0, len(reports))
+	for _, report := range reports {
+		ids = append(ids, report.ID)
+	}
+	media := s.loadReportMedia(ids)
+	bookmarked := s.loadBookmarked(c, ids)
+	for i := range reports {
+		reports[i].Media = media[reports[i].ID]
+		reports[i].IsBookmarked = reports[i].IsBookmarked || bookmarked[reports[i].ID]
+	}
+
+	views := models.ReportsForAudience(reports, func(r models.IncidentReport) models.ReportAudience {
+		return reportAudience(c, r.UserID)
+	})
+	for i := range views {
+		views[i].Media = s.signMedia(c, views[i].Media)
+	}
+	return views
+}
+
+// presentReportMaps attaches media to report rows scanned into maps and
+// redacts them in place.
+func (s *Server) presentReportMaps(c *gin.Context, reports []map[string]interface{}) []map[string]interface{} {
+	ids := make([]uuid.UUID, 0, len(reports))
+	for _, report := range reports {
+		if id, ok := reportMapID(report); ok {
+			ids = append(ids, id)
+		}
+	}
+	media := s.loadReportMedia(ids)
+	bookmarked := s.loadBookmarked(c, ids)
+
+	for _, report := range reports {
+		id, _ := reportMapID(report)
+		report["media"] = media[id]
+		report["is_bookmarked"] = bookmarked[id]
+		models.RedactReportMap(report, reportAudience(c, models.ReportOwnerID(report)))
+		if list, ok := report["media"].([]models.Media); ok {
+			report["media"] = s.signMedia(c, list)
+		}
+	}
+	return reports
+}
+
+// loadReportMedia fetches the media of the given reports. A failure is logged
+// and the reports are returned without media rather than failing the request.
+func (s *Server) loadReportMedia(ids []uuid.UUID) map[uuid.UUID][]models.Media {
+	if s.MediaRepository == nil {
+		return nil
+	}
+	media, err := s.MediaRepository.GetMediaByReportIDs(ids)
+	if err != nil {
+		log.Printf("Error loading report media: %v", err)
+		return nil
+	}
+	return media
+}
+
+// forgetReportMedia drops the cached signed URLs of a report's media once the
+// report is hidden, so they are not handed out again.
+func (s *Server) forgetReportMedia(reportID uuid.UUID) {
+	if s.URLSigner == nil {
+		return
+	}
+	media := s.loadReportMedia([]uuid.UUID{reportID})
+	for _, item := range media[reportID] {
+		s.URLSigner.Forget(item.FeedURL, item.ThumbnailURL, item.FullSizeURL)
+	}
+}
+
+// loadBookmarked looks up which of the reports the caller bookmarked. Routes
+// without a signed in user see none bookmarked, and a failure is logged like
+// loadReportMedia does.
+func (s *Server) loadBookmarked(c *gin.Context, ids []uuid.UUID) map[uuid.UUID]bool {
+	if s.BookmarkService == nil {
+		return nil
+	}
+	userID, ok := c.Get("userID")
+	if !ok {
+		return nil
+	}
+	id, ok := userID.(uint)
+	if !ok || id == 0 {
+		return nil
+	}
+	bookmarked, err := s.BookmarkService.BookmarkedReportIDs(id, ids)
+	if err != nil {
+		log.Printf("Error loading bookmarks: %v", err)
+		return nil
+	}
+	return bookmarked
+}
+
+func reportMapID(report map[string]interface{}) (uuid.UUID, bool) {
+	switch v := report["id"].(type) {
+	case string:
+		id, err := uuid.Parse(v)
+		return id, err == nil
+	case []byte:
+		id, err := uuid.ParseBytes(v)
+		return id, err == nil
+	case uuid.UUID:
+		return v, true
+	}
+	return uuid.Nil, false
+}
+
+// signMedia returns the media list with every rendition URL signed. The items
+// have already been through models.MediaForAudience.
+func (s *Server) signMedia(c *gin.Context, media []models.Media) []models.Media {
+	signed := make([]models.Media, 0, len(media))
+	for _, item := range media {
+		if s.URLSigner != nil {
+			ctx := c.Request.Context()
+			item.FeedURL = s.URLSigner.Sign(ctx, item.FeedURL)
+			item.ThumbnailURL = s.URLSigner.Sign(ctx, item.ThumbnailURL)
+			item.FullSizeURL = s.URLSigner.Sign(ctx, item.FullSizeURL)
+		}
+		signed = append(signed, item)
+	}
+	return signed
+}
+
+// signURLs returns signed copies of freshly stored media URLs for the response
+// to an upload.
+func (s *Server) signURLs(c *gin.Context, urls []string) []string {
+	if s.URLSigner == nil {
+		return urls
+	}
+	signed := make([]string, 0, len(urls))
+	for _, u := range urls {
+		signed = append(signed, s.URLSigner.Sign(c.Request.Context(), u))
+	}
+	return signed
+}
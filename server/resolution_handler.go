@@ -0,0 +1,56 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/techagentng/citizenx/services"
+)
+
+// resolutionErrorStatus maps resolution vote errors onto HTTP status codes.
+func resolutionErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrReportNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrReportNotResolved):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrLocationRequired):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrTooFarFromReport):
+		return http.StatusForbidden
+	}
+	return http.StatusInternalServerError
+}
+
+// handleResolutionVote records whether the reporter or a nearby citizen thinks
+// a resolved report is fixed or still broken.
+func (s *Server) handleResolutionVote() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input services.ResolutionVoteInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "fixed is required"})
+			return
+		}
+		userID := c.MustGet("userID").(uint)
+		tally, reopened, err := s.ResolutionService.Vote(userID, c.Param("id"), input)
+		if err != nil {
+			c.JSON(resolutionErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"tally": tally, "reopened": reopened})
+	}
+}
+
+// handleGetResolutionVotes returns the vote tally for the report's current
+// resolution.
+func (s *Server) handleGetResolutionVotes() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tally, err := s.ResolutionService.GetTally(c.Param("id"))
+		if err != nil {
+			c.JSON(resolutionErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"tally": tally})
+	}
+}
@@ -1,11 +1,36 @@
 package server
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/techagentng/citizenx/services"
 )
 
+// ledgerErrorStatus maps points ledger errors onto HTTP status codes.
+func ledgerErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrTransactionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidPoints), errors.Is(err, services.ErrAlreadyReversed):
+		return http.StatusBadRequest
+	case errors.Is(err, services.ErrInsufficientPoints):
+		return http.StatusConflict
+	}
+	return http.StatusInternalServerError
+}
+
+type pointsAdjustmentRequest struct {
+	UserID uint   `json:"user_id" binding:"required"`
+	Amount int    `json:"amount" binding:"required"`
+	Reason string `json:"reason" binding:"required"`
+}
+
+type pointsReversalRequest struct {
+	Reason string `json:"reason"`
+}
+
 func (s *Server) handleSumAllRewardsBalance() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		totalBalance, err := s.RewardService.GetAllRewardsBalanceCount()
@@ -19,7 +44,12 @@ func (s *Server) handleSumAllRewardsBalance() gin.HandlerFunc {
 
 func (s *Server) handleGetAllRewardsList() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		rewards, err := s.RewardService.GetAllRewards()
+		page, err := getPageFromQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+			return
+		}
+		rewards, err := s.RewardService.GetAllRewards(page)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -45,8 +75,8 @@ func (s *Server) handleGetUserRewardBalance() gin.HandlerFunc {
 			return
 		}
 
-		// Fetch the reward balance for the user using the service
-		balance, err := s.RewardRepository.GetUserRewardBalance(userID)
+		// Fetch the reward balance for the user from the points ledger
+		balance, err := s.LedgerService.GetBalance(userID)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 			return
@@ -58,3 +88,136 @@ func (s *Server) handleGetUserRewardBalance() gin.HandlerFunc {
 		})
 	}
 }
+
+// handleGetPointsHistory returns a page of the signed in user's points ledger.
+func (s *Server) handleGetPointsHistory() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, err := getPageFromQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid page number"})
+			return
+		}
+		userID := c.MustGet("userID").(uint)
+		history, err := s.LedgerService.GetHistory(userID, page)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		balance, err := s.LedgerService.GetBalance(userID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"balance": balance, "transactions": history})
+	}
+}
+
+// handleAdjustPoints lets an admin correct a user's balance. An
+// Idempotency-Key header makes a retried request apply once.
+func (s *Server) handleAdjustPoints() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req pointsAdjustmentRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "user_id, a non-zero amount and reason are required"})
+			return
+		}
+		adminID := c.MustGet("userID").(uint)
+		txn, err := s.LedgerService.Adjust(adminID, req.UserID, req.Amount, c.GetHeader("Idempotency-Key"), req.Reason)
+		if err != nil {
+			c.JSON(ledgerErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"transaction": txn})
+	}
+}
+
+// handleReversePoints lets an admin reverse a ledger transaction.
+func (s *Server) handleReversePoints() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := uintParam(c, "id")
+		if !ok {
+			return
+		}
+		var req pointsReversalRequest
+		_ = c.ShouldBindJSON(&req)
+		adminID := c.MustGet("userID").(uint)
+		txn, err := s.LedgerService.ReverseByID(id, adminID, req.Reason)
+		if err != nil {
+			c.JSON(ledgerErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"transaction": txn})
+	}
+}
+
+// rewardRuleErrorStatus maps reward rule errors onto HTTP status codes.
+func rewardRuleErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrRewardRuleNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrInvalidRewardRule), errors.Is(err, services.ErrCategoryNotFound):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+func (s *Server) handleGetRewardRules() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rules, err := s.RewardEngine.ListRules()
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"rules": rules})
+	}
+}
+
+func (s *Server) handleCreateRewardRule() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var input services.RewardRuleInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		rule, err := s.RewardEngine.CreateRule(input)
+		if err != nil {
+			c.JSON(rewardRuleErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"rule": rule})
+	}
+}
+
+func (s *Server) handleUpdateRewardRule() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := uintParam(c, "id")
+		if !ok {
+			return
+		}
+		var input services.RewardRuleInput
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+		rule, err := s.RewardEngine.UpdateRule(id, input)
+		if err != nil {
+			c.JSON(rewardRuleErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"rule": rule})
+	}
+}
+
+func (s *Server) handleDeleteRewardRule() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, ok := uintParam(c, "id")
+		if !ok {
+			return
+		}
+		if err := s.RewardEngine.DeleteRule(id); err != nil {
+			c.JSON(rewardRuleErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "Reward rule deleted"})
+	}
+}
@@ -5,6 +5,7 @@ import (
 
 	// rateLimit "github.com/JGLTechnologies/gin-rate-limit"
 	// "net/http"
+	"net/url"
 	"os"
 	// "path/filepath"
 	// "runtime"
@@ -12,6 +13,8 @@ import (
 	// "github.com/gin-contrib/cors"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/techagentng/citizenx/models"
+	"github.com/techagentng/citizenx/storage"
 )
 
 func (s *Server) setupRouter() *gin.Engine {
@@ -43,7 +46,7 @@ func (s *Server) setupRouter() *gin.Engine {
 	r.Use(cors.New(cors.Config{
 		AllowOrigins: []string{"https://www.citizenx.ng","http://localhost:3001","https://citizenx-dashboard-sbqx.onrender.com"}, // Replace with your frontend's origin
 		AllowMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders: []string{"Origin", "Authorization", "Content-Type", "X-Client-State"},
+		AllowHeaders: []string{"Origin", "Authorization", "Content-Type", "X-Client-State", "X-Device-ID"},
 		ExposeHeaders: []string{"Content-Length", "X-Client-State"},
 		AllowCredentials: true,
 		MaxAge: 12 * time.Hour,
@@ -51,6 +54,13 @@ func (s *Server) setupRouter() *gin.Engine {
 	
 	// Increase memory limit for multipart forms
 	r.MaxMultipartMemory = 32 << 20
+
+	// In development files are stored on disk and served from here
+	if local, ok := s.Storage.(*storage.LocalStore); ok {
+		if base, err := url.Parse(local.BaseURL()); err == nil && base.Path != "" {
+			r.GET(base.Path+"/*key", gin.WrapH(local.Handler()))
+		}
+	}
 	// Define application routes
 	s.defineRoutes(r)
 
@@ -63,6 +73,7 @@ func (s *Server) defineRoutes(router *gin.Engine) {
 
 	apirouter := router.Group("/api/v1")
 	apirouter.POST("/auth/signup", s.handleSignup())
+	apirouter.GET("/media/playlist/*key", s.handleGetPlaylist())
 	apirouter.POST("/auth/login", s.handleLogin())
 	apirouter.POST("/no-cred/login", restrictAccessToProtectedRoutes(), s.handleNonCredentialLogin())
 	apirouter.GET("/fb/auth", s.handleFBLogin())
@@ -74,6 +85,7 @@ func (s *Server) defineRoutes(router *gin.Engine) {
 	apirouter.GET("/incident_reports/state/:state", s.handleGetAllReportsByState())
 	apirouter.GET("/incident_reports/lga/:lga", s.handleGetAllReportsByLGA())
 	apirouter.GET("/incident_reports/report_type/:report_type", s.handleGetAllReportsByReportType())
+	apirouter.GET("/incident_reports/search", s.handleSearchReports())
 	apirouter.POST("/password/forgot", s.HandleForgotPassword())
 	apirouter.POST("/password/reset/:token", s.ResetPasswordHandler()) //
 	apirouter.POST("/report-type/states", s.HandleGetVariadicBarChart())
@@ -83,6 +95,8 @@ func (s *Server) defineRoutes(router *gin.Engine) {
 	apirouter.POST("/password/forgot/mobile", s.HandleForgotPasswordMobile())
 	apirouter.POST("/password/token/validate", s.ValidateResetTokenHandler())
 	apirouter.POST("/password/reset/mobile", s.ResetPasswordMobileHandler())
+	apirouter.GET("/leaderboards", s.handleGetLeaderboard())
+	apirouter.GET("/badges", s.handleGetBadges())
 
 	authorized := apirouter.Group("/")
 	authorized.Use(s.Authorize())
@@ -92,15 +106,63 @@ func (s *Server) defineRoutes(router *gin.Engine) {
 	authorized.GET("/users/online", s.handleGetOnlineUsers())
 	authorized.POST("/user/report/", s.handleIncidentReport())  //
 	authorized.POST("/user/report/media", s.handleUploadMedia())
+	authorized.POST("/uploads", s.handleCreateUpload())
+	authorized.GET("/uploads/:id", s.handleGetUpload())
+	authorized.PUT("/uploads/:id/chunks/:chunk", s.handleUploadChunk())
+	authorized.POST("/uploads/:id/complete", s.handleCompleteUpload())
 	authorized.GET("/categories", s.handleGetAllCategories())
+	authorized.GET("/categories/:slug/form", s.handleGetCategoryForm())
+	admin := authorized.Group("/admin")
+	admin.Use(requireRole(models.RoleAdmin))
+	admin.GET("/media/flagged", s.handleGetFlaggedMedia())
+	admin.GET("/approve/:reportID/report", s.handleApproveReportPoints())
+	admin.GET("/reject/:reportID/report", s.handleRejectReportPoints())
+	admin.GET("/accept/:reportID/report", s.handleAcceptReportPoints())
+	admin.GET("/categories", s.handleAdminGetCategories())
+	admin.POST("/categories", s.handleCreateCategory())
+	admin.PUT("/categories/:id", s.handleUpdateCategory())
+	admin.DELETE("/categories/:id", s.handleDeleteCategory())
+	admin.POST("/categories/:id/subtypes", s.handleCreateSubCategory())
+	admin.PUT("/categories/:id/subtypes/:sub_id", s.handleUpdateSubCategory())
+	admin.DELETE("/categories/:id/subtypes/:sub_id", s.handleDeleteSubCategory())
+	admin.GET("/agencies", s.handleGetAgencies())
+	admin.POST("/agencies", s.handleCreateAgency())
+	admin.PUT("/agencies/:id", s.handleUpdateAgency())
+	admin.DELETE("/agencies/:id", s.handleDeleteAgency())
+	admin.PUT("/agencies/:id/members/:user_id", s.handleAddAgencyMember())
+	admin.POST("/rewards/adjustments", s.handleAdjustPoints())
+	admin.POST("/rewards/transactions/:id/reverse", s.handleReversePoints())
+	admin.GET("/reward-rules", s.handleGetRewardRules())
+	admin.POST("/reward-rules", s.handleCreateRewardRule())
+	admin.PUT("/reward-rules/:id", s.handleUpdateRewardRule())
+	admin.DELETE("/reward-rules/:id", s.handleDeleteRewardRule())
+	admin.GET("/redemptions", s.handleGetRedemptions())
+	admin.PUT("/redemptions/:id/approve", s.handleApproveRedemption())
+	admin.PUT("/redemptions/:id/reject", s.handleRejectRedemption())
+	admin.POST("/redemptions/:id/refresh", s.handleRefreshRedemption())
+	admin.POST("/leaderboards/rebuild", s.handleRebuildLeaderboards())
+	agency := authorized.Group("/agency")
+	agency.Use(requireRole(models.RoleAgency))
+	agency.GET("/assignments", s.handleGetAgencyAssignments())
+	agency.PUT("/assignments/:id/status", s.handleUpdateAssignmentStatus())
+	authorized.GET("/incident-report/:id/responses", s.handleGetOfficialResponses())
+	authorized.POST("/incident-report/:id/responses", requireRole(models.RoleAdmin, models.RoleAgency), s.handlePostOfficialResponse())
+	authorized.GET("/incident-report/:id/resolution-votes", s.handleGetResolutionVotes())
+	authorized.POST("/incident-report/:id/resolution-votes", s.handleResolutionVote())
+	authorized.POST("/incident-report/:id/views", s.handleTrackReportView())
+	authorized.GET("/incident-report/:id/metrics", s.handleGetReportMetrics())
 	authorized.GET("/states", s.handleGetAllStates())
 	authorized.PUT("/me/updateUserProfile", s.handleEditUserProfile())
 	authorized.GET("/me", s.handleShowProfile())
+	authorized.PUT("/user/bookmark/:reportID", s.HandleBookmarkReport())
+	authorized.DELETE("/user/bookmark/:reportID", s.HandleUnbookmarkReport())
+	// the GET is kept for app versions that bookmark with it
 	authorized.GET("/user/bookmark/:reportID", s.HandleBookmarkReport())
-	authorized.GET("/user/bookmarked/report", s.HandleGetBookmarkedReports()) //
-	authorized.GET("/approve/:reportID/:userID/report", s.handleApproveReportPoints())
-	authorized.GET("/reject/:reportID/:userID/report", s.handleRejectReportPoints())
-	authorized.GET("/accept/:reportID/:userID/report", s.handleAcceptReportPoints())
+	authorized.GET("/user/bookmarked/report", s.HandleGetBookmarkedReports())
+	authorized.GET("/user/bookmark-collections", s.handleGetBookmarkCollections())
+	authorized.POST("/user/bookmark-collections", s.handleCreateBookmarkCollection())
+	authorized.PUT("/user/bookmark-collections/:id", s.handleRenameBookmarkCollection())
+	authorized.DELETE("/user/bookmark-collections/:id", s.handleDeleteBookmarkCollection())
 	authorized.GET("/report-percentage-by-state", s.handleGetReportPercentageByState())
 	authorized.GET("/today/report", s.handleGetTodayReportCount())
 	authorized.GET("/all/user", s.handleGetTotalUserCount())
@@ -112,6 +174,7 @@ func (s *Server) defineRoutes(router *gin.Engine) {
 	authorized.GET("/users/lga/:lga/report-type/:reportType", s.handleGetReportsByTypeAndLGA())
 	authorized.GET("/rewards/list", s.handleGetAllRewardsList())
 	authorized.GET("/report/type/count", s.handleGetReportTypeCounts())
+	authorized.GET("/report/attributes/:field/counts", s.handleGetAttributeCounts())
 	authorized.GET("/lgas", s.handleGetLGAs())
 	authorized.GET("/lgas/lat/lng", s.IncidentMarkersHandler())
 	authorized.DELETE("/incident-report/:id", s.DeleteIncidentReportHandler())
@@ -125,6 +188,7 @@ func (s *Server) defineRoutes(router *gin.Engine) {
 	authorized.GET("/report/sub_reports", s.HandleGetSubReportsByCategory())
 	authorized.PUT("/report/upvote/:reportID", s.HandleUpvoteReport())
 	authorized.PUT("/report/downvote/:reportID", s.HandleDownvoteReport())
+	authorized.PUT("/report/vote/:reportID", s.HandleVoteReport())
 	authorized.GET("/user/reports", s.HandleGetAllReportsByUser())  //
 	authorized.GET("/report/votecounts/:reportID", s.HandleGetVoteCounts())
 	authorized.GET("/report/counts/lga/:lga", s.GetReportTypeCountsByLGA())
@@ -133,6 +197,12 @@ func (s *Server) defineRoutes(router *gin.Engine) {
 	authorized.GET("/top/report/categories", s.handleGetTopCategories())
 	authorized.GET("/report/type/id", s.GetReportsByCategory())
 	authorized.GET("/get/user/balance", s.handleGetUserRewardBalance())
+	authorized.GET("/rewards/history", s.handleGetPointsHistory())
+	authorized.POST("/rewards/redemptions", s.handleRequestRedemption())
+	authorized.GET("/rewards/redemptions", s.handleGetUserRedemptions())
+	authorized.GET("/leaderboards/me", s.handleGetLeaderboardStanding())
+	authorized.GET("/users/:id/reputation", s.handleGetReputation())
+	authorized.GET("/users/:id/profile", s.handleGetPublicProfile())
 	authorized.GET("reports/filters", s.handleGetReportsByFilters())
 	authorized.POST("posts/create", s.handleCreatePost())
 	authorized.GET("/all/posts/:userID", s.handleGetPostsByUserID())
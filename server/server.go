@@ -15,6 +15,7 @@ import (
 	"github.com/techagentng/citizenx/db"
 	"github.com/techagentng/citizenx/mailingservices"
 	"github.com/techagentng/citizenx/services"
+	"github.com/techagentng/citizenx/storage"
 	"gorm.io/gorm"
 )
 
@@ -25,6 +26,10 @@ type Server struct {
 	Mail                     mailingservices.Mailer
 	MediaRepository          db.MediaRepository
 	MediaService             services.MediaService
+	UploadService            services.UploadService
+	MediaJobQueue            services.MediaJobQueue
+	Storage                  storage.BlobStore
+	URLSigner                *storage.URLSigner
 	IncidentReportService    services.IncidentReportService
 	IncidentReportRepository db.IncidentReportRepository
 	RewardService            services.RewardService
@@ -32,6 +37,19 @@ type Server struct {
 	LikeService              services.LikeService
 	PostService              services.PostService
 	PostRepository           db.PostRepository
+	CategoryService          services.CategoryService
+	AgencyService            services.AgencyService
+	SLAMonitor               services.SLAMonitor
+	OfficialResponseService  services.OfficialResponseService
+	ResolutionService        services.ResolutionService
+	LedgerService            services.LedgerService
+	RewardEngine             services.RewardEngine
+	RedemptionService        services.RedemptionService
+	LeaderboardService       services.LeaderboardService
+	BadgeService             services.BadgeService
+	FeedService              services.FeedService
+	EngagementService        services.EngagementService
+	BookmarkService          services.BookmarkService
 	NotificationService *services.NotificationService
 	DB *gorm.DB 
 	SessionSecret            string
@@ -59,6 +77,22 @@ func (s *Server) Start() {
 		}
 	}()
 
+	// Background media processing stops with the server
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+	if s.MediaJobQueue != nil {
+		go s.MediaJobQueue.Start(workerCtx)
+	}
+	if s.UploadService != nil {
+		go s.UploadService.Start(workerCtx)
+	}
+	if s.SLAMonitor != nil {
+		go s.SLAMonitor.Start(workerCtx)
+	}
+	if s.EngagementService != nil {
+		go s.EngagementService.Start(workerCtx)
+	}
+
 	log.Printf("Server started on %s\n", PORT)
 	gracefulShutdown(srv)
 }
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/techagentng/citizenx/services"
+)
+
+type createUploadRequest struct {
+	ReportID    string `json:"report_id" binding:"required"`
+	Filename    string `json:"filename" binding:"required"`
+	ContentType string `json:"content_type" binding:"required"`
+	TotalSize   int64  `json:"total_size" binding:"required"`
+}
+
+// uploadErrorStatus maps upload service errors onto HTTP status codes.
+func uploadErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, services.ErrUploadNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, services.ErrUploadExpired):
+		return http.StatusGone
+	case errors.Is(err, services.ErrUploadCompleted), errors.Is(err, services.ErrChunkOutOfOrder):
+		return http.StatusConflict
+	case errors.Is(err, services.ErrUploadTooLarge):
+		return http.StatusRequestEntityTooLarge
+	case errors.Is(err, services.ErrTooManyUploads):
+		return http.StatusTooManyRequests
+	case errors.Is(err, services.ErrChunkSizeMismatch), errors.Is(err, services.ErrUploadIncomplete), errors.Is(err, services.ErrUnsupportedUpload):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// handleCreateUpload starts a resumable upload for one media file.
+func (s *Server) handleCreateUpload() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req createUploadRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+
+		session, err := s.UploadService.CreateSession(c.GetUint("userID"), req.ReportID, req.Filename, req.ContentType, req.TotalSize)
+		if err != nil {
+			c.JSON(uploadErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusCreated, gin.H{"upload": session})
+	}
+}
+
+// handleGetUpload reports how far an upload has got so the client can resume
+// from next_chunk after a dropped connection.
+func (s *Server) handleGetUpload() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		session, err := s.UploadService.GetSession(c.GetUint("userID"), c.Param("id"))
+		if err != nil {
+			c.JSON(uploadErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+		c.JSON(http.StatusOK, gin.H{"upload": session})
+	}
+}
+
+// handleUploadChunk stores one numbered chunk sent as the raw request body.
+func (s *Server) handleUploadChunk() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		chunk, err := strconv.Atoi(c.Param("chunk"))
+		if err != nil || chunk < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chunk number"})
+			return
+		}
+
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, services.UploadChunkSize+1)
+		session, err := s.UploadService.WriteChunk(c.GetUint("userID"), c.Param("id"), chunk, c.Request.Body)
+		if err != nil {
+			c.JSON(uploadErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		c.Header("Upload-Offset", strconv.FormatInt(session.Offset, 10))
+		c.JSON(http.StatusOK, gin.H{"upload": session})
+	}
+}
+
+// handleCompleteUpload queues the assembled file for processing. The media is
+// attached to the report once its job finishes.
+func (s *Server) handleCompleteUpload() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		media, err := s.UploadService.Complete(c.GetUint("userID"), c.Param("id"))
+		if err != nil {
+			c.JSON(uploadErrorStatus(err), gin.H{"error": err.Error()})
+			return
+		}
+		s.BadgeService.EvaluateAsync(c.GetUint("userID"), services.BadgeEventMediaAdded)
+		c.JSON(http.StatusAccepted, gin.H{"message": "Media is being processed", "media": media})
+	}
+}
@@ -0,0 +1,475 @@
+package services
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/techagentng/citizenx/config"
+	"github.com/techagentng/citizenx/db"
+	"github.com/techagentng/citizenx/mailingservices"
+	"github.com/techagentng/citizenx/models"
+	"gorm.io/gorm"
+)
+
+// webhookTimeout bounds a single webhook delivery.
+const webhookTimeout = 10 * time.Second
+
+var (
+	ErrAgencyNotFound     = errors.New("agency not found")
+	ErrInvalidAgency      = errors.New("agency name and at least one category are required")
+	ErrAssignmentNotFound = errors.New("assignment not found")
+	ErrInvalidStatus      = fmt.Errorf("status must be one of %s", strings.Join(models.AssignmentStatuses, ", "))
+	ErrNotAgencyMember    = errors.New("user does not belong to an agency")
+	ErrUserNotFound       = errors.New("user not found")
+)
+
+// AgencyInput carries the fields an admin sets on an agency. Fields left nil
+// keep their current value on update.
+type AgencyInput struct {
+	Name          *string  `json:"name"`
+	Email         *string  `json:"email"`
+	Phone         *string  `json:"phone"`
+	ContactPerson *string  `json:"contact_person"`
+	WebhookURL    *string  `json:"webhook_url"`
+	Active        *bool    `json:"active"`
+	Categories    []string `json:"categories"`
+	States        []string `json:"states"`
+	LGAs          []string `json:"lgas"`
+	// EscalationContacts are emails told about missed SLA targets
+	EscalationContacts []string `json:"escalation_contacts"`
+}
+
+// AgencyService manages agencies and routes new reports to the agencies
+// responsible for them.
+type AgencyService interface {
+	ListAgencies() ([]models.Agency, error)
+	CreateAgency(input AgencyInput) (*models.Agency, error)
+	UpdateAgency(id uint, input AgencyInput) (*models.Agency, error)
+	DeleteAgency(id uint) error
+	AddMember(agencyID, userID uint) error
+	RouteReport(report *models.IncidentReport) ([]models.ReportAssignment, error)
+	GetAssignments(user *models.User, status string, page int) ([]models.ReportAssignment, error)
+	UpdateAssignmentStatus(user *models.User, assignmentID uint, status, note string) (*models.ReportAssignment, error)
+	GetSLAMetrics(state, lga, startDate, endDate string) (*SLAReport, error)
+}
+
+// SLAReport holds SLA metrics grouped by agency and by state.
+type SLAReport struct {
+	ByAgency []models.SLAMetric `json:"by_agency"`
+	ByState  []models.SLAMetric `json:"by_state"`
+}
+
+type agencyService struct {
+	Config       *config.Config
+	agencyRepo   db.AgencyRepository
+	categoryRepo db.CategoryRepository
+	authRepo     db.AuthRepository
+	mailer       mailingservices.Mailer
+	httpClient   *http.Client
+}
+
+func NewAgencyService(agencyRepo db.AgencyRepository, categoryRepo db.CategoryRepository, authRepo db.AuthRepository, mailer mailingservices.Mailer, conf *config.Config) AgencyService {
+	return &agencyService{
+		Config:       conf,
+		agencyRepo:   agencyRepo,
+		categoryRepo: categoryRepo,
+		authRepo:     authRepo,
+		mailer:       mailer,
+		httpClient:   &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+func (s *agencyService) ListAgencies() ([]models.Agency, error) {
+	agencies, err := s.agencyRepo.ListAgencies()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agencies: %v", err)
+	}
+	return agencies, nil
+}
+
+// CreateAgency stores a new agency with a fresh webhook secret, which the
+// caller hands to the agency once.
+func (s *agencyService) CreateAgency(input AgencyInput) (*models.Agency, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("failed to generate webhook secret: %v", err)
+	}
+	agency := &models.Agency{Active: true, WebhookSecret: hex.EncodeToString(secret)}
+	applyAgencyInput(input, agency)
+	if agency.Name == "" || len(agency.Categories) == 0 {
+		return nil, ErrInvalidAgency
+	}
+	if err := s.agencyRepo.CreateAgency(agency); err != nil {
+		return nil, fmt.Errorf("failed to create agency: %v", err)
+	}
+	return agency, nil
+}
+
+func (s *agencyService) UpdateAgency(id uint, input AgencyInput) (*models.Agency, error) {
+	agency, err := s.getAgency(id)
+	if err != nil {
+		return nil, err
+	}
+	applyAgencyInput(input, agency)
+	if agency.Name == "" || len(agency.Categories) == 0 {
+		return nil, ErrInvalidAgency
+	}
+	if err := s.agencyRepo.UpdateAgency(agency); err != nil {
+		return nil, fmt.Errorf("failed to update agency: %v", err)
+	}
+	return agency, nil
+}
+
+func (s *agencyService) DeleteAgency(id uint) error {
+	if _, err := s.getAgency(id); err != nil {
+		return err
+	}
+	if err := s.agencyRepo.DeleteAgency(id); err != nil {
+		return fmt.Errorf("failed to delete agency: %v", err)
+	}
+	return nil
+}
+
+// AddMember gives a user the agency role and scopes them to the agency.
+func (s *agencyService) AddMember(agencyID, userID uint) error {
+	if _, err := s.getAgency(agencyID); err != nil {
+		return err
+	}
+	role, err := s.authRepo.FindRoleByName(models.RoleAgency)
+	if err != nil {
+		return fmt.Errorf("failed to find agency role: %v", err)
+	}
+	if err := s.agencyRepo.AddAgencyMember(agencyID, userID, role.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("failed to add agency member: %v", err)
+	}
+	return nil
+}
+
+// RouteReport assigns a new report to every active agency covering its
+// category and location and notifies them by email and webhook. Delivery
+// failures are recorded on the assignment rather than returned.
+func (s *agencyService) RouteReport(report *models.IncidentReport) ([]models.ReportAssignment, error) {
+	category, err := s.categoryRepo.GetCategoryByID(report.CategoryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch category: %v", err)
+	}
+	if category == nil {
+		return nil, nil
+	}
+	agencies, err := s.agencyRepo.ListActiveAgencies()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list agencies: %v", err)
+	}
+	existing, err := s.agencyRepo.GetReportAssignments(report.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch assignments: %v", err)
+	}
+	alreadyAssigned := make(map[uint]bool, len(existing))
+	for _, assignment := range existing {
+		alreadyAssigned[assignment.AgencyID] = true
+	}
+
+	now := time.Now()
+	acknowledgeDue := slaDueAt(now, category.AcknowledgeWithinHours)
+	resolveDue := slaDueAt(now, category.ResolveWithinHours)
+
+	var assignments []models.ReportAssignment
+	var assigned []models.Agency
+	for _, agency := range agencies {
+		if !alreadyAssigned[agency.ID] && agency.Covers(category.Slug, report.StateName, report.LGAName) {
+			assignments = append(assignments, models.ReportAssignment{
+				IncidentReportID: report.ID,
+				AgencyID:         agency.ID,
+				Status:           models.AssignmentStatusAssigned,
+				AcknowledgeDueAt: acknowledgeDue,
+				ResolveDueAt:     resolveDue,
+			})
+			assigned = append(assigned, agency)
+		}
+	}
+	if err := s.agencyRepo.CreateAssignments(assignments); err != nil {
+		return nil, fmt.Errorf("failed to assign report: %v", err)
+	}
+
+	for i := range assignments {
+		deliveryErr := s.deliverAssignment(&assigned[i], &assignments[i], category, report)
+		var message string
+		if deliveryErr != nil {
+			log.Printf("Unable to notify agency %d of report %s: %v", assigned[i].ID, report.ID, deliveryErr)
+			message = deliveryErr.Error()
+		}
+		if err := s.agencyRepo.MarkAssignmentDelivered(assignments[i].ID, message); err != nil {
+			log.Printf("Unable to record delivery of assignment %d: %v", assignments[i].ID, err)
+		}
+	}
+	return assignments, nil
+}
+
+// GetAssignments returns a page of the assignments of the user's agency.
+func (s *agencyService) GetAssignments(user *models.User, status string, page int) ([]models.ReportAssignment, error) {
+	if user == nil || user.AgencyID == nil {
+		return nil, ErrNotAgencyMember
+	}
+	if status != "" && !isAssignmentStatus(status) {
+		return nil, ErrInvalidStatus
+	}
+	assignments, err := s.agencyRepo.GetAgencyAssignments(*user.AgencyID, status, page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch assignments: %v", err)
+	}
+	return assignments, nil
+}
+
+// UpdateAssignmentStatus records an agency's progress on a report assigned to
+// it.
+func (s *agencyService) UpdateAssignmentStatus(user *models.User, assignmentID uint, status, note string) (*models.ReportAssignment, error) {
+	if user == nil || user.AgencyID == nil {
+		return nil, ErrNotAgencyMember
+	}
+	if !isAssignmentStatus(status) {
+		return nil, ErrInvalidStatus
+	}
+	assignment, err := s.agencyRepo.GetAssignment(*user.AgencyID, assignmentID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch assignment: %v", err)
+	}
+	if assignment == nil {
+		return nil, ErrAssignmentNotFound
+	}
+	applyAssignmentStatus(assignment, user.ID, status, note)
+	if err := s.agencyRepo.UpdateAssignment(assignment); err != nil {
+		return nil, fmt.Errorf("failed to update assignment: %v", err)
+	}
+	return assignment, nil
+}
+
+// applyAssignmentStatus moves the assignment to the status set by an agency
+// user, stamping when it was acknowledged and closed.
+func applyAssignmentStatus(assignment *models.ReportAssignment, userID uint, status, note string) {
+	now := time.Now()
+	assignment.Status = status
+	assignment.Note = note
+	assignment.UpdatedBy = userID
+	// Any move away from assigned acknowledges the report; resolved and
+	// rejected close it
+	if status != models.AssignmentStatusAssigned && assignment.AcknowledgedAt == nil {
+		assignment.AcknowledgedAt = &now
+	}
+	switch {
+	case status == models.AssignmentStatusResolved || status == models.AssignmentStatusRejected:
+		if assignment.ResolvedAt == nil {
+			assignment.ResolvedAt = &now
+		}
+	default:
+		assignment.ResolvedAt = nil
+	}
+}
+
+// GetSLAMetrics reports SLA performance by agency and by state for
+// assignments made between startDate and endDate (YYYY-MM-DD, inclusive).
+func (s *agencyService) GetSLAMetrics(state, lga, startDate, endDate string) (*SLAReport, error) {
+	var from, to *time.Time
+	if startDate != "" {
+		t, err := time.Parse("2006-01-02", startDate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse start date: %v", err)
+		}
+		from = &t
+	}
+	if endDate != "" {
+		t, err := time.Parse("2006-01-02", endDate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse end date: %v", err)
+		}
+		t = t.AddDate(0, 0, 1)
+		to = &t
+	}
+
+	byAgency, err := s.agencyRepo.GetSLAMetrics("agency", state, lga, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch SLA metrics by agency: %v", err)
+	}
+	byState, err := s.agencyRepo.GetSLAMetrics("state", state, lga, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch SLA metrics by state: %v", err)
+	}
+	return &SLAReport{ByAgency: byAgency, ByState: byState}, nil
+}
+
+func (s *agencyService) getAgency(id uint) (*models.Agency, error) {
+	agency, err := s.agencyRepo.GetAgencyByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch agency: %v", err)
+	}
+	if agency == nil {
+		return nil, ErrAgencyNotFound
+	}
+	return agency, nil
+}
+
+// assignmentWebhook is the body POSTed to an agency's webhook.
+type assignmentWebhook struct {
+	Event        string                  `json:"event"`
+	AssignmentID uint                    `json:"assignment_id"`
+	AgencyID     uint                    `json:"agency_id"`
+	Link         string                  `json:"link"`
+	Report       assignmentWebhookReport `json:"report"`
+}
+
+type assignmentWebhookReport struct {
+	ID          uuid.UUID         `json:"id"`
+	Category    string            `json:"category"`
+	Description string            `json:"description"`
+	StateName   string            `json:"state_name"`
+	LGAName     string            `json:"lga_name"`
+	Address     string            `json:"address"`
+	Latitude    float64           `json:"latitude"`
+	Longitude   float64           `json:"longitude"`
+	Attributes  models.Attributes `json:"attributes"`
+	ReportedAt  time.Time         `json:"reported_at"`
+}
+
+// deliverAssignment emails the agency and calls its webhook, whichever are
+// configured, and returns the errors of both.
+func (s *agencyService) deliverAssignment(agency *models.Agency, assignment *models.ReportAssignment, category *models.Category, report *models.IncidentReport) error {
+	link := reportPortalLink(s.Config, report.ID)
+	var errs []string
+
+	if agency.Email != "" && s.mailer != nil {
+		if _, err := s.mailer.SendReportAssignment(agency.Email, agency.Name, category.Name, link); err != nil {
+			errs = append(errs, fmt.Sprintf("email: %v", err))
+		}
+	}
+
+	if agency.WebhookURL != "" {
+		body, err := json.Marshal(assignmentWebhook{
+			Event:        "report.assigned",
+			AssignmentID: assignment.ID,
+			AgencyID:     agency.ID,
+			Link:         link,
+			Report: assignmentWebhookReport{
+				ID:          report.ID,
+				Category:    category.Slug,
+				Description: report.Description,
+				StateName:   report.StateName,
+				LGAName:     report.LGAName,
+				Address:     report.Address,
+				Latitude:    report.Latitude,
+				Longitude:   report.Longitude,
+				Attributes:  report.Attributes,
+				ReportedAt:  report.TimeofIncidence,
+			},
+		})
+		if err == nil {
+			err = s.postWebhook(agency, body)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("webhook: %v", err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// postWebhook sends body to the agency's webhook, signed with an HMAC-SHA256
+// of the body under the agency's webhook secret.
+func (s *agencyService) postWebhook(agency *models.Agency, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, agency.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	mac := hmac.New(sha256.New, []byte(agency.WebhookSecret))
+	mac.Write(body)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-CitizenX-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// reportPortalLink is where agency staff open a report assigned to them.
+func reportPortalLink(conf *config.Config, reportID uuid.UUID) string {
+	base := "https://citizenx.ng"
+	if conf != nil && conf.FRONTEND_URL != "" {
+		base = strings.TrimRight(conf.FRONTEND_URL, "/")
+	}
+	return base + "/agency/reports/" + reportID.String()
+}
+
+func slaDueAt(from time.Time, hours int) *time.Time {
+	if hours <= 0 {
+		return nil
+	}
+	due := from.Add(time.Duration(hours) * time.Hour)
+	return &due
+}
+
+func isAssignmentStatus(status string) bool {
+	for _, s := range models.AssignmentStatuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+func applyAgencyInput(input AgencyInput, agency *models.Agency) {
+	for _, field := range []struct {
+		src *string
+		dst *string
+	}{
+		{input.Name, &agency.Name},
+		{input.Email, &agency.Email},
+		{input.Phone, &agency.Phone},
+		{input.ContactPerson, &agency.ContactPerson},
+		{input.WebhookURL, &agency.WebhookURL},
+	} {
+		if field.src != nil {
+			*field.dst = strings.TrimSpace(*field.src)
+		}
+	}
+	if input.Active != nil {
+		agency.Active = *input.Active
+	}
+	if input.Categories != nil {
+		agency.Categories = agency.Categories[:0]
+		for _, category := range input.Categories {
+			if slug := models.Slugify(category); slug != "" {
+				agency.Categories = append(agency.Categories, slug)
+			}
+		}
+	}
+	if input.States != nil {
+		agency.States = models.StringList(input.States)
+	}
+	if input.LGAs != nil {
+		agency.LGAs = models.StringList(input.LGAs)
+	}
+	if input.EscalationContacts != nil {
+		agency.EscalationContacts = models.StringList(input.EscalationContacts)
+	}
+}
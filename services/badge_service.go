@@ -0,0 +1,196 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/techagentng/citizenx/db"
+	"github.com/techagentng/citizenx/models"
+)
+
+// Events that can earn badges.
+const (
+	BadgeEventReportSubmitted = "report_submitted"
+	BadgeEventReportApproved  = "report_approved"
+	BadgeEventVoteReceived    = "vote_received"
+	BadgeEventMediaAdded      = "media_added"
+)
+
+// badgeCriterionEvents lists the events after which each criterion can newly
+// be met, so an event only checks the badges it can affect. Media is usually
+// added after its report is submitted, and its bonus moves the leaderboard.
+var badgeCriterionEvents = map[string][]string{
+	models.BadgeCriterionReportsSubmitted: {BadgeEventReportSubmitted},
+	models.BadgeCriterionReportsApproved:  {BadgeEventReportApproved},
+	models.BadgeCriterionMediaItems:       {BadgeEventReportSubmitted, BadgeEventReportApproved, BadgeEventMediaAdded},
+	models.BadgeCriterionUpvotesReceived:  {BadgeEventVoteReceived},
+	models.BadgeCriterionTopInLGAMonthly:  {BadgeEventReportSubmitted, BadgeEventReportApproved, BadgeEventMediaAdded},
+}
+
+// BadgeService awards achievement badges as contributors reach them.
+type BadgeService interface {
+	ListBadges() ([]models.Badge, error)
+	GetUserBadges(userID uint) ([]models.UserBadge, error)
+	Evaluate(userID uint, event string) ([]models.UserBadge, error)
+	EvaluateAsync(userID uint, event string)
+	EvaluateReportOwnerAsync(reportID string, event string)
+}
+
+type badgeService struct {
+	badgeRepo   db.BadgeRepository
+	reportRepo  db.IncidentReportRepository
+	leaderboard LeaderboardService
+	notifier    *NotificationService
+}
+
+func NewBadgeService(badgeRepo db.BadgeRepository, reportRepo db.IncidentReportRepository, leaderboard LeaderboardService, notifier *NotificationService) BadgeService {
+	return &badgeService{
+		badgeRepo:   badgeRepo,
+		reportRepo:  reportRepo,
+		leaderboard: leaderboard,
+		notifier:    notifier,
+	}
+}
+
+func (s *badgeService) ListBadges() ([]models.Badge, error) {
+	badges, err := s.badgeRepo.GetActiveBadges()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch badges: %v", err)
+	}
+	return badges, nil
+}
+
+func (s *badgeService) GetUserBadges(userID uint) ([]models.UserBadge, error) {
+	userBadges, err := s.badgeRepo.GetUserBadges(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user badges: %v", err)
+	}
+	return userBadges, nil
+}
+
+// Evaluate awards the badges the event brought within the user's reach and
+// returns those newly earned. The user is notified of each.
+func (s *badgeService) Evaluate(userID uint, event string) ([]models.UserBadge, error) {
+	badges, err := s.badgeRepo.GetActiveBadges()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch badges: %v", err)
+	}
+
+	var progress *models.BadgeProgress
+	var earned []models.UserBadge
+	for _, badge := range badges {
+		if !badgeAffectedBy(badge.Criterion, event) {
+			continue
+		}
+		if badge.Criterion != models.BadgeCriterionTopInLGAMonthly && progress == nil {
+			if progress, err = s.badgeRepo.GetBadgeProgress(userID); err != nil {
+				return nil, fmt.Errorf("failed to fetch badge progress: %v", err)
+			}
+		}
+		met, err := s.criterionMet(userID, badge, progress)
+		if err != nil {
+			return nil, err
+		}
+		if !met {
+			continue
+		}
+
+		userBadge := models.UserBadge{UserID: userID, BadgeID: badge.ID, Badge: badge}
+		if badge.Monthly() {
+			userBadge.Period = time.Now().In(lagosLocation()).Format("2006-01")
+		}
+		isNew, err := s.badgeRepo.AwardBadge(&userBadge)
+		if err != nil {
+			return nil, fmt.Errorf("failed to award badge %s: %v", badge.Code, err)
+		}
+		if isNew {
+			earned = append(earned, userBadge)
+		}
+	}
+
+	for _, userBadge := range earned {
+		s.notify(userID, userBadge.Badge)
+	}
+	return earned, nil
+}
+
+func badgeAffectedBy(criterion, event string) bool {
+	for _, e := range badgeCriterionEvents[criterion] {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *badgeService) criterionMet(userID uint, badge models.Badge, progress *models.BadgeProgress) (bool, error) {
+	switch badge.Criterion {
+	case models.BadgeCriterionReportsSubmitted:
+		return progress.ReportsSubmitted >= badge.Threshold, nil
+	case models.BadgeCriterionReportsApproved:
+		return progress.ReportsApproved >= badge.Threshold, nil
+	case models.BadgeCriterionMediaItems:
+		return progress.MediaItems >= badge.Threshold, nil
+	case models.BadgeCriterionUpvotesReceived:
+		return progress.UpvotesReceived >= badge.Threshold, nil
+	case models.BadgeCriterionTopInLGAMonthly:
+		if s.leaderboard == nil {
+			return false, nil
+		}
+		standing, err := s.leaderboard.GetStanding(userID, models.LeaderboardMonthly, models.LeaderboardLGA)
+		if err != nil {
+			return false, err
+		}
+		return standing.Rank > 0 && standing.Rank <= int64(badge.Threshold), nil
+	}
+	return false, nil
+}
+
+// EvaluateAsync evaluates badges in the background so the request that
+// caused the event does not wait on it.
+func (s *badgeService) EvaluateAsync(userID uint, event string) {
+	go func() {
+		if _, err := s.Evaluate(userID, event); err != nil {
+			log.Printf("Failed to evaluate badges for user %d: %v", userID, err)
+		}
+	}()
+}
+
+// EvaluateReportOwnerAsync evaluates badges for the user who made the report.
+func (s *badgeService) EvaluateReportOwnerAsync(reportID string, event string) {
+	go func() {
+		report, err := s.reportRepo.GetReportByID(reportID)
+		if err != nil {
+			log.Printf("Failed to fetch report %s for badges: %v", reportID, err)
+			return
+		}
+		if _, err := s.Evaluate(report.UserID, event); err != nil {
+			log.Printf("Failed to evaluate badges for user %d: %v", report.UserID, err)
+		}
+	}()
+}
+
+func (s *badgeService) notify(userID uint, badge models.Badge) {
+	if s.notifier == nil {
+		return
+	}
+	token, err := s.reportRepo.GetExpoPushToken(userID)
+	if err != nil || token == "" {
+		return
+	}
+	err = s.notifier.SendPushNotification(
+		token,
+		"Badge earned",
+		fmt.Sprintf("You earned the %s badge: %s", badge.Name, badge.Description),
+		map[string]interface{}{
+			"badgeId":   fmt.Sprint(badge.ID),
+			"badgeCode": badge.Code,
+			"type":      "badge-earned",
+			"deepLink":  "/profile/badges",
+		},
+	)
+	if err != nil {
+		log.Printf("Failed to send badge notification: %v", err)
+	}
+}
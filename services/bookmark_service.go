@@ -0,0 +1,183 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/techagentng/citizenx/db"
+	"github.com/techagentng/citizenx/models"
+)
+
+// maxCollectionNameLength bounds the name of a bookmark collection.
+const maxCollectionNameLength = 50
+
+var (
+	ErrBookmarkNotFound           = errors.New("report is not bookmarked")
+	ErrBookmarkCollectionNotFound = errors.New("bookmark collection not found")
+	ErrBookmarkCollectionExists   = errors.New("a bookmark collection with this name already exists")
+	ErrInvalidBookmarkCollection  = fmt.Errorf("collection name is required and at most %d characters", maxCollectionNameLength)
+)
+
+// BookmarkService manages the reports users bookmark and the named
+// collections they organize them into.
+type BookmarkService interface {
+	// Bookmark adds the report to the user's bookmarks. A nil collectionID
+	// leaves an existing bookmark's collection unchanged and zero takes it
+	// out of its collection.
+	Bookmark(userID uint, reportID uuid.UUID, collectionID *uint) (*models.Bookmark, error)
+	Unbookmark(userID uint, reportID uuid.UUID) error
+	GetBookmarkedReports(userID uint, collectionID *uint, page int) ([]models.IncidentReport, error)
+	BookmarkedReportIDs(userID uint, reportIDs []uuid.UUID) (map[uuid.UUID]bool, error)
+	ListCollections(userID uint) ([]models.BookmarkCollection, error)
+	CreateCollection(userID uint, name string) (*models.BookmarkCollection, error)
+	RenameCollection(userID, id uint, name string) (*models.BookmarkCollection, error)
+	DeleteCollection(userID, id uint) error
+}
+
+type bookmarkService struct {
+	bookmarkRepo db.BookmarkRepository
+}
+
+func NewBookmarkService(bookmarkRepo db.BookmarkRepository) BookmarkService {
+	return &bookmarkService{bookmarkRepo: bookmarkRepo}
+}
+
+func (s *bookmarkService) Bookmark(userID uint, reportID uuid.UUID, collectionID *uint) (*models.Bookmark, error) {
+	exists, err := s.bookmarkRepo.ReportExists(reportID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrReportNotFound
+	}
+	if collectionID != nil && *collectionID != 0 {
+		collection, err := s.bookmarkRepo.GetCollection(userID, *collectionID)
+		if err != nil {
+			return nil, err
+		}
+		if collection == nil {
+			return nil, ErrBookmarkCollectionNotFound
+		}
+	}
+
+	bookmark, err := s.bookmarkRepo.GetBookmark(userID, reportID)
+	if err != nil {
+		return nil, err
+	}
+	if bookmark == nil {
+		bookmark = &models.Bookmark{UserID: userID, ReportID: reportID}
+	} else if collectionID == nil {
+		return bookmark, nil
+	}
+	if collectionID != nil {
+		bookmark.CollectionID = nil
+		if *collectionID != 0 {
+			id := *collectionID
+			bookmark.CollectionID = &id
+		}
+	}
+	if err := s.bookmarkRepo.SaveBookmark(bookmark); err != nil {
+		return nil, err
+	}
+	return bookmark, nil
+}
+
+func (s *bookmarkService) Unbookmark(userID uint, reportID uuid.UUID) error {
+	removed, err := s.bookmarkRepo.DeleteBookmark(userID, reportID)
+	if err != nil {
+		return err
+	}
+	if !removed {
+		return ErrBookmarkNotFound
+	}
+	return nil
+}
+
+func (s *bookmarkService) GetBookmarkedReports(userID uint, collectionID *uint, page int) ([]models.IncidentReport, error) {
+	if collectionID != nil {
+		collection, err := s.bookmarkRepo.GetCollection(userID, *collectionID)
+		if err != nil {
+			return nil, err
+		}
+		if collection == nil {
+			return nil, ErrBookmarkCollectionNotFound
+		}
+	}
+	reports, err := s.bookmarkRepo.GetBookmarkedReports(userID, collectionID, page)
+	if err != nil {
+		return nil, err
+	}
+	for i := range reports {
+		reports[i].IsBookmarked = true
+	}
+	return reports, nil
+}
+
+func (s *bookmarkService) BookmarkedReportIDs(userID uint, reportIDs []uuid.UUID) (map[uuid.UUID]bool, error) {
+	return s.bookmarkRepo.GetBookmarkedIDs(userID, reportIDs)
+}
+
+func (s *bookmarkService) ListCollections(userID uint) ([]models.BookmarkCollection, error) {
+	return s.bookmarkRepo.GetCollections(userID)
+}
+
+func (s *bookmarkService) CreateCollection(userID uint, name string) (*models.BookmarkCollection, error) {
+	name, err := s.checkCollectionName(userID, name, 0)
+	if err != nil {
+		return nil, err
+	}
+	collection := &models.BookmarkCollection{UserID: userID, Name: name}
+	if err := s.bookmarkRepo.SaveCollection(collection); err != nil {
+		return nil, err
+	}
+	return collection, nil
+}
+
+func (s *bookmarkService) RenameCollection(userID, id uint, name string) (*models.BookmarkCollection, error) {
+	collection, err := s.bookmarkRepo.GetCollection(userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if collection == nil {
+		return nil, ErrBookmarkCollectionNotFound
+	}
+	name, err = s.checkCollectionName(userID, name, id)
+	if err != nil {
+		return nil, err
+	}
+	collection.Name = name
+	if err := s.bookmarkRepo.SaveCollection(collection); err != nil {
+		return nil, err
+	}
+	return collection, nil
+}
+
+func (s *bookmarkService) DeleteCollection(userID, id uint) error {
+	collection, err := s.bookmarkRepo.GetCollection(userID, id)
+	if err != nil {
+		return err
+	}
+	if collection == nil {
+		return ErrBookmarkCollectionNotFound
+	}
+	return s.bookmarkRepo.DeleteCollection(collection)
+}
+
+// checkCollectionName returns the trimmed name if it is valid and not used
+// by another of the user's collections.
+func (s *bookmarkService) checkCollectionName(userID uint, name string, excludeID uint) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len([]rune(name)) > maxCollectionNameLength {
+		return "", ErrInvalidBookmarkCollection
+	}
+	taken, err := s.bookmarkRepo.CollectionNameTaken(userID, name, excludeID)
+	if err != nil {
+		return "", err
+	}
+	if taken {
+		return "", ErrBookmarkCollectionExists
+	}
+	return name, nil
+}
@@ -0,0 +1,337 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/techagentng/citizenx/config"
+	"github.com/techagentng/citizenx/db"
+	"github.com/techagentng/citizenx/models"
+)
+
+var (
+	ErrCategoryNotFound    = errors.New("category not found")
+	ErrSubCategoryNotFound = errors.New("sub-category not found")
+	ErrCategoryExists      = errors.New("a category with this slug already exists")
+	ErrSubCategoryExists   = errors.New("a sub-category with this slug already exists in the category")
+	ErrInvalidCategory     = errors.New("name or slug is required")
+	ErrUnknownCategory     = errors.New("unknown or inactive category")
+	ErrUnknownSubCategory  = errors.New("unknown or inactive sub-category for this category")
+	ErrInvalidFormSchema   = errors.New("invalid form schema")
+	ErrInvalidAttributes   = errors.New("invalid report attributes")
+	ErrInvalidSLA          = errors.New("SLA targets cannot be negative")
+)
+
+// CategoryInput carries the fields an admin sets on a category or sub-type.
+// Fields left nil keep their current value on update.
+type CategoryInput struct {
+	Slug         *string             `json:"slug"`
+	Name         *string             `json:"name"`
+	Description  *string             `json:"description"`
+	Icon         *string             `json:"icon"`
+	Active       *bool               `json:"active"`
+	SortOrder    *int                `json:"sort_order"`
+	Translations models.Translations `json:"translations"`
+	// The form schema and SLA targets only apply to categories
+	FormSchema             *models.FormSchema `json:"form_schema"`
+	AcknowledgeWithinHours *int               `json:"acknowledge_within_hours"`
+	ResolveWithinHours     *int               `json:"resolve_within_hours"`
+}
+
+// CategoryService manages the report taxonomy and resolves the category a
+// report is submitted under.
+type CategoryService interface {
+	ListCategories(includeInactive bool, lang string) ([]models.Category, error)
+	GetCategoryForm(slug, lang string) (*models.Category, error)
+	CreateCategory(input CategoryInput) (*models.Category, error)
+	UpdateCategory(id uint, input CategoryInput) (*models.Category, error)
+	DeleteCategory(id uint) error
+	CreateSubCategory(categoryID uint, input CategoryInput) (*models.SubCategory, error)
+	UpdateSubCategory(categoryID, id uint, input CategoryInput) (*models.SubCategory, error)
+	DeleteSubCategory(categoryID, id uint) error
+	ResolveCategory(category, subCategory string) (*models.Category, *models.SubCategory, error)
+	ValidateAttributes(category *models.Category, input map[string]interface{}) (models.Attributes, error)
+}
+
+type categoryService struct {
+	Config       *config.Config
+	categoryRepo db.CategoryRepository
+}
+
+func NewCategoryService(categoryRepo db.CategoryRepository, conf *config.Config) CategoryService {
+	return &categoryService{
+		Config:       conf,
+		categoryRepo: categoryRepo,
+	}
+}
+
+// ListCategories returns the taxonomy with names and descriptions in lang
+// where a translation exists.
+func (s *categoryService) ListCategories(includeInactive bool, lang string) ([]models.Category, error) {
+	categories, err := s.categoryRepo.ListCategories(includeInactive)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list categories: %v", err)
+	}
+	for i := range categories {
+		localizeCategory(&categories[i], lang)
+	}
+	return categories, nil
+}
+
+// GetCategoryForm returns an active category with its form schema and active
+// sub-types, for clients rendering the report form.
+func (s *categoryService) GetCategoryForm(slug, lang string) (*models.Category, error) {
+	category, err := s.categoryRepo.GetCategoryBySlug(models.Slugify(slug))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch category: %v", err)
+	}
+	if category == nil || !category.Active {
+		return nil, ErrCategoryNotFound
+	}
+	active := category.SubTypes[:0]
+	for _, sub := range category.SubTypes {
+		if sub.Active {
+			active = append(active, sub)
+		}
+	}
+	category.SubTypes = active
+	localizeCategory(category, lang)
+	return category, nil
+}
+
+func (s *categoryService) CreateCategory(input CategoryInput) (*models.Category, error) {
+	category := &models.Category{Active: true}
+	applyCategoryInput(input, &category.Slug, &category.Name, &category.Description, &category.Icon, &category.Active, &category.SortOrder, &category.Translations)
+	if category.Slug == "" || category.Name == "" {
+		return nil, ErrInvalidCategory
+	}
+	if err := applyCategoryFields(input, category); err != nil {
+		return nil, err
+	}
+	existing, err := s.categoryRepo.GetCategoryBySlug(category.Slug)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check category slug: %v", err)
+	}
+	if existing != nil {
+		return nil, ErrCategoryExists
+	}
+	if err := s.categoryRepo.CreateCategory(category); err != nil {
+		return nil, fmt.Errorf("failed to create category: %v", err)
+	}
+	return category, nil
+}
+
+func (s *categoryService) UpdateCategory(id uint, input CategoryInput) (*models.Category, error) {
+	category, err := s.getCategory(id)
+	if err != nil {
+		return nil, err
+	}
+	oldSlug := category.Slug
+	applyCategoryInput(input, &category.Slug, &category.Name, &category.Description, &category.Icon, &category.Active, &category.SortOrder, &category.Translations)
+	if category.Slug == "" || category.Name == "" {
+		return nil, ErrInvalidCategory
+	}
+	if err := applyCategoryFields(input, category); err != nil {
+		return nil, err
+	}
+	if category.Slug != oldSlug {
+		existing, err := s.categoryRepo.GetCategoryBySlug(category.Slug)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check category slug: %v", err)
+		}
+		if existing != nil {
+			return nil, ErrCategoryExists
+		}
+	}
+	if err := s.categoryRepo.UpdateCategory(category, oldSlug); err != nil {
+		return nil, fmt.Errorf("failed to update category: %v", err)
+	}
+	return category, nil
+}
+
+func (s *categoryService) DeleteCategory(id uint) error {
+	if _, err := s.getCategory(id); err != nil {
+		return err
+	}
+	if err := s.categoryRepo.DeleteCategory(id); err != nil {
+		return fmt.Errorf("failed to delete category: %v", err)
+	}
+	return nil
+}
+
+func (s *categoryService) CreateSubCategory(categoryID uint, input CategoryInput) (*models.SubCategory, error) {
+	category, err := s.getCategory(categoryID)
+	if err != nil {
+		return nil, err
+	}
+	sub := &models.SubCategory{CategoryID: category.ID, Active: true}
+	applyCategoryInput(input, &sub.Slug, &sub.Name, &sub.Description, &sub.Icon, &sub.Active, &sub.SortOrder, &sub.Translations)
+	if sub.Slug == "" || sub.Name == "" {
+		return nil, ErrInvalidCategory
+	}
+	if findSubCategory(category, sub.Slug) != nil {
+		return nil, ErrSubCategoryExists
+	}
+	if err := s.categoryRepo.CreateSubCategory(sub); err != nil {
+		return nil, fmt.Errorf("failed to create sub-category: %v", err)
+	}
+	return sub, nil
+}
+
+func (s *categoryService) UpdateSubCategory(categoryID, id uint, input CategoryInput) (*models.SubCategory, error) {
+	category, sub, err := s.getSubCategory(categoryID, id)
+	if err != nil {
+		return nil, err
+	}
+	oldSlug := sub.Slug
+	applyCategoryInput(input, &sub.Slug, &sub.Name, &sub.Description, &sub.Icon, &sub.Active, &sub.SortOrder, &sub.Translations)
+	if sub.Slug == "" || sub.Name == "" {
+		return nil, ErrInvalidCategory
+	}
+	if sub.Slug != oldSlug && findSubCategory(category, sub.Slug) != nil {
+		return nil, ErrSubCategoryExists
+	}
+	if err := s.categoryRepo.UpdateSubCategory(sub); err != nil {
+		return nil, fmt.Errorf("failed to update sub-category: %v", err)
+	}
+	return sub, nil
+}
+
+func (s *categoryService) DeleteSubCategory(categoryID, id uint) error {
+	if _, _, err := s.getSubCategory(categoryID, id); err != nil {
+		return err
+	}
+	if err := s.categoryRepo.DeleteSubCategory(id); err != nil {
+		return fmt.Errorf("failed to delete sub-category: %v", err)
+	}
+	return nil
+}
+
+// ResolveCategory looks up the category a report is submitted under by name or
+// slug, and the optional sub-type within it. Both must exist and be active.
+func (s *categoryService) ResolveCategory(category, subCategory string) (*models.Category, *models.SubCategory, error) {
+	slug := models.Slugify(category)
+	if slug == "" {
+		return nil, nil, ErrUnknownCategory
+	}
+	found, err := s.categoryRepo.GetCategoryBySlug(slug)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch category: %v", err)
+	}
+	if found == nil || !found.Active {
+		return nil, nil, ErrUnknownCategory
+	}
+	if subCategory == "" {
+		return found, nil, nil
+	}
+	sub := findSubCategory(found, models.Slugify(subCategory))
+	if sub == nil || !sub.Active {
+		return nil, nil, ErrUnknownSubCategory
+	}
+	return found, sub, nil
+}
+
+// ValidateAttributes checks the category specific fields of a submission
+// against the category's form schema.
+func (s *categoryService) ValidateAttributes(category *models.Category, input map[string]interface{}) (models.Attributes, error) {
+	attributes, err := category.FormSchema.Validate(input)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAttributes, err)
+	}
+	return attributes, nil
+}
+
+func (s *categoryService) getCategory(id uint) (*models.Category, error) {
+	category, err := s.categoryRepo.GetCategoryByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch category: %v", err)
+	}
+	if category == nil {
+		return nil, ErrCategoryNotFound
+	}
+	return category, nil
+}
+
+func (s *categoryService) getSubCategory(categoryID, id uint) (*models.Category, *models.SubCategory, error) {
+	category, err := s.getCategory(categoryID)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range category.SubTypes {
+		if category.SubTypes[i].ID == id {
+			return category, &category.SubTypes[i], nil
+		}
+	}
+	return nil, nil, ErrSubCategoryNotFound
+}
+
+func findSubCategory(category *models.Category, slug string) *models.SubCategory {
+	for i := range category.SubTypes {
+		if category.SubTypes[i].Slug == slug {
+			return &category.SubTypes[i]
+		}
+	}
+	return nil
+}
+
+// applyCategoryFields copies the fields only categories have.
+func applyCategoryFields(input CategoryInput, category *models.Category) error {
+	if input.FormSchema != nil {
+		if err := input.FormSchema.Check(); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidFormSchema, err)
+		}
+		category.FormSchema = *input.FormSchema
+	}
+	if input.AcknowledgeWithinHours != nil {
+		if *input.AcknowledgeWithinHours < 0 {
+			return ErrInvalidSLA
+		}
+		category.AcknowledgeWithinHours = *input.AcknowledgeWithinHours
+	}
+	if input.ResolveWithinHours != nil {
+		if *input.ResolveWithinHours < 0 {
+			return ErrInvalidSLA
+		}
+		category.ResolveWithinHours = *input.ResolveWithinHours
+	}
+	return nil
+}
+
+func localizeCategory(category *models.Category, lang string) {
+	if lang == "" {
+		return
+	}
+	category.Name, category.Description = category.Translations.Localize(lang, category.Name, category.Description)
+	for i := range category.SubTypes {
+		sub := &category.SubTypes[i]
+		sub.Name, sub.Description = sub.Translations.Localize(lang, sub.Name, sub.Description)
+	}
+}
+
+// applyCategoryInput copies the fields set in input. A missing slug is derived
+// from the name, and slugs are normalized the same way as submitted categories.
+func applyCategoryInput(input CategoryInput, slug, name, description, icon *string, active *bool, sortOrder *int, translations *models.Translations) {
+	if input.Name != nil {
+		*name = *input.Name
+	}
+	if input.Slug != nil {
+		*slug = models.Slugify(*input.Slug)
+	} else if *slug == "" {
+		*slug = models.Slugify(*name)
+	}
+	if input.Description != nil {
+		*description = *input.Description
+	}
+	if input.Icon != nil {
+		*icon = *input.Icon
+	}
+	if input.Active != nil {
+		*active = *input.Active
+	}
+	if input.SortOrder != nil {
+		*sortOrder = *input.SortOrder
+	}
+	if input.Translations != nil {
+		*translations = input.Translations
+	}
+}
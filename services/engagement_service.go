@@ -0,0 +1,317 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/techagentng/citizenx/config"
+	"github.com/techagentng/citizenx/db"
+	"github.com/techagentng/citizenx/models"
+)
+
+const (
+	// defaultViewDedupWindow is how long repeat views by one viewer count
+	// once when the window is not configured.
+	defaultViewDedupWindow = 30 * time.Minute
+	// defaultViewFlushInterval is how often buffered views are written to
+	// the database when the interval is not configured.
+	defaultViewFlushInterval = 30 * time.Second
+)
+
+// Redis keys buffering engagement until it is flushed. Each is moved to its
+// flushing key while being written so new views keep buffering, and a flush
+// that fails is retried from there.
+const (
+	pendingViewsKey   = "engagement:views"
+	pendingSharesKey  = "engagement:shares"
+	pendingViewersKey = "engagement:viewers"
+	flushingSuffix    = ":flushing"
+	// flushBatchKey names the batch held in the flushing keys; the database
+	// remembers applied batches so one is never counted twice
+	flushBatchKey = "engagement:flushing:batch"
+	// flushLockKey lets one instance flush at a time. The lock expires after
+	// flushLockTTL in case its holder dies mid flush.
+	flushLockKey = "engagement:flush:lock"
+	flushLockTTL = 5 * time.Minute
+)
+
+// releaseLockScript deletes a lock only while it still holds the caller's
+// token, so an expired lock taken over by another instance is left alone.
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+var ErrMetricsForbidden = errors.New("only the reporter and admins can see report metrics")
+
+// EngagementService counts report views and shares, deduplicated per viewer
+// and buffered in Redis, and reports engagement metrics.
+type EngagementService interface {
+	Start(ctx context.Context)
+	TrackView(reportID, viewerKey string, userID uint) error
+	TrackShare(reportID, viewerKey string) error
+	Flush() error
+	GetMetrics(reportID string, userID uint, role string) (*models.ReportMetrics, error)
+}
+
+type engagementService struct {
+	Config         *config.Config
+	engagementRepo db.EngagementRepository
+	redis          *redis.Client
+}
+
+func NewEngagementService(engagementRepo db.EngagementRepository, redisClient *redis.Client, conf *config.Config) EngagementService {
+	return &engagementService{
+		Config:         conf,
+		engagementRepo: engagementRepo,
+		redis:          redisClient,
+	}
+}
+
+func (s *engagementService) dedupWindow() time.Duration {
+	if s.Config != nil && s.Config.ViewDedupWindow > 0 {
+		return s.Config.ViewDedupWindow
+	}
+	return defaultViewDedupWindow
+}
+
+func (s *engagementService) flushInterval() time.Duration {
+	if s.Config != nil && s.Config.ViewFlushInterval > 0 {
+		return s.Config.ViewFlushInterval
+	}
+	return defaultViewFlushInterval
+}
+
+// Start flushes buffered engagement on every tick, and once more when ctx is
+// cancelled.
+func (s *engagementService) Start(ctx context.Context) {
+	ticker := time.NewTicker(s.flushInterval())
+	defer ticker.Stop()
+	log.Printf("Started engagement flusher, flushing every %s", s.flushInterval())
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := s.Flush(); err != nil {
+				log.Printf("Error flushing engagement: %v", err)
+			}
+			return
+		case <-ticker.C:
+			if err := s.Flush(); err != nil {
+				log.Printf("Error flushing engagement: %v", err)
+			}
+		}
+	}
+}
+
+// TrackView counts a view unless the viewer already viewed the report within
+// the dedup window.
+func (s *engagementService) TrackView(reportID, viewerKey string, userID uint) error {
+	if _, err := uuid.Parse(reportID); err != nil {
+		return ErrReportNotFound
+	}
+	if s.redis == nil {
+		return nil
+	}
+	ctx := context.Background()
+	first, err := s.redis.SetNX(ctx, "engagement:seen:view:"+reportID+":"+viewerKey, 1, s.dedupWindow()).Result()
+	if err != nil {
+		return fmt.Errorf("failed to track view: %v", err)
+	}
+	if !first {
+		return nil
+	}
+	pipe := s.redis.TxPipeline()
+	pipe.HIncrBy(ctx, pendingViewsKey, reportID, 1)
+	pipe.RPush(ctx, pendingViewersKey, fmt.Sprintf("%s|%s|%d", reportID, viewerKey, userID))
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to track view: %v", err)
+	}
+	return nil
+}
+
+// TrackShare counts a share link being opened, once per viewer within the
+// dedup window.
+func (s *engagementService) TrackShare(reportID, viewerKey string) error {
+	if _, err := uuid.Parse(reportID); err != nil {
+		return ErrReportNotFound
+	}
+	if s.redis == nil {
+		return nil
+	}
+	ctx := context.Background()
+	first, err := s.redis.SetNX(ctx, "engagement:seen:share:"+reportID+":"+viewerKey, 1, s.dedupWindow()).Result()
+	if err != nil {
+		return fmt.Errorf("failed to track share: %v", err)
+	}
+	if !first {
+		return nil
+	}
+	if err := s.redis.HIncrBy(ctx, pendingSharesKey, reportID, 1).Err(); err != nil {
+		return fmt.Errorf("failed to track share: %v", err)
+	}
+	return nil
+}
+
+// Flush writes the buffered views, shares and viewers to the database in one
+// transaction. Only one instance flushes at a time; the others skip the tick.
+func (s *engagementService) Flush() error {
+	if s.redis == nil {
+		return nil
+	}
+	ctx := context.Background()
+	token := uuid.New().String()
+	locked, err := s.redis.SetNX(ctx, flushLockKey, token, flushLockTTL).Result()
+	if err != nil {
+		return fmt.Errorf("failed to take the flush lock: %v", err)
+	}
+	if !locked {
+		return nil
+	}
+	defer func() {
+		if err := releaseLockScript.Run(ctx, s.redis, []string{flushLockKey}, token).Err(); err != nil && err != redis.Nil {
+			log.Printf("Error releasing engagement flush lock: %v", err)
+		}
+	}()
+
+	batchID, err := s.flushingBatch(ctx)
+	if err != nil {
+		return err
+	}
+
+	views, err := s.flushingCounts(ctx, pendingViewsKey)
+	if err != nil {
+		return err
+	}
+	shares, err := s.flushingCounts(ctx, pendingSharesKey)
+	if err != nil {
+		return err
+	}
+	entries, err := s.redis.LRange(ctx, pendingViewersKey+flushingSuffix, 0, -1).Result()
+	if err != nil {
+		return fmt.Errorf("failed to read buffered viewers: %v", err)
+	}
+
+	if len(views) > 0 || len(shares) > 0 || len(entries) > 0 {
+		now := time.Now().Unix()
+		viewers := make([]models.View, 0, len(entries))
+		for _, entry := range entries {
+			parts := strings.SplitN(entry, "|", 3)
+			if len(parts) != 3 {
+				continue
+			}
+			userID, _ := strconv.ParseUint(parts[2], 10, 64)
+			viewers = append(viewers, models.View{
+				ID:               parts[0] + ":" + parts[1],
+				IncidentReportID: parts[0],
+				UserID:           uint(userID),
+				CreatedAt:        now,
+			})
+		}
+
+		if err := s.engagementRepo.ApplyEngagement(batchID, views, shares, viewers); err != nil {
+			return fmt.Errorf("failed to save engagement: %v", err)
+		}
+	}
+	return s.redis.Del(ctx,
+		pendingViewsKey+flushingSuffix,
+		pendingSharesKey+flushingSuffix,
+		pendingViewersKey+flushingSuffix,
+		flushBatchKey,
+	).Err()
+}
+
+// flushingBatch returns the ID of the batch in the flushing keys. A batch a
+// failed flush left behind is retried as it is; otherwise the buffers are
+// moved aside as a new batch.
+func (s *engagementService) flushingBatch(ctx context.Context) (string, error) {
+	batchID, err := s.redis.Get(ctx, flushBatchKey).Result()
+	if err == nil {
+		return batchID, nil
+	}
+	if err != redis.Nil {
+		return "", fmt.Errorf("failed to read the flush batch: %v", err)
+	}
+	for _, key := range []string{pendingViewsKey, pendingSharesKey, pendingViewersKey} {
+		if err := s.moveAside(ctx, key); err != nil {
+			return "", err
+		}
+	}
+	batchID = uuid.New().String()
+	if err := s.redis.Set(ctx, flushBatchKey, batchID, 0).Err(); err != nil {
+		return "", fmt.Errorf("failed to save the flush batch: %v", err)
+	}
+	return batchID, nil
+}
+
+// moveAside renames a buffer to its flushing key, unless that key is still
+// there from a flush that never recorded its batch, in which case the buffer
+// waits for the next batch.
+func (s *engagementService) moveAside(ctx context.Context, key string) error {
+	exists, err := s.redis.Exists(ctx, key).Result()
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %v", key, err)
+	}
+	if exists == 0 {
+		return nil
+	}
+	if err := s.redis.RenameNX(ctx, key, key+flushingSuffix).Err(); err != nil {
+		return fmt.Errorf("failed to move %s aside: %v", key, err)
+	}
+	return nil
+}
+
+func (s *engagementService) flushingCounts(ctx context.Context, key string) (map[string]int, error) {
+	raw, err := s.redis.HGetAll(ctx, key+flushingSuffix).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", key, err)
+	}
+	counts := make(map[string]int, len(raw))
+	for reportID, value := range raw {
+		if count, err := strconv.Atoi(value); err == nil && count > 0 {
+			counts[reportID] = count
+		}
+	}
+	return counts, nil
+}
+
+// GetMetrics returns the report's engagement to its reporter or an admin.
+// Views and shares still buffered are included.
+func (s *engagementService) GetMetrics(reportID string, userID uint, role string) (*models.ReportMetrics, error) {
+	if _, err := uuid.Parse(reportID); err != nil {
+		return nil, ErrReportNotFound
+	}
+	metrics, err := s.engagementRepo.GetReportMetrics(reportID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch report metrics: %v", err)
+	}
+	if metrics == nil {
+		return nil, ErrReportNotFound
+	}
+	if metrics.OwnerID != userID && role != models.RoleAdmin {
+		return nil, ErrMetricsForbidden
+	}
+
+	if s.redis != nil {
+		ctx := context.Background()
+		for _, key := range []string{pendingViewsKey, pendingViewsKey + flushingSuffix} {
+			if n, err := s.redis.HGet(ctx, key, reportID).Int(); err == nil {
+				metrics.Views += n
+			}
+		}
+		for _, key := range []string{pendingSharesKey, pendingSharesKey + flushingSuffix} {
+			if n, err := s.redis.HGet(ctx, key, reportID).Int(); err == nil {
+				metrics.Shares += n
+			}
+		}
+	}
+	return metrics, nil
+}
@@ -0,0 +1,373 @@
+package services
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"mime/multipart"
+	"time"
+)
+
+// ExifData holds the EXIF fields the media pipeline cares about. Everything
+// else (camera model, serial numbers, thumbnails) is discarded when the image
+// is stripped.
+type ExifData struct {
+	HasGPS    bool
+	Latitude  float64
+	Longitude float64
+	TakenAt   *time.Time
+}
+
+const (
+	exifTagExifIFD          = 0x8769
+	exifTagGPSIFD           = 0x8825
+	exifTagDateTime         = 0x0132
+	exifTagDateTimeOriginal = 0x9003
+	gpsTagLatitudeRef       = 0x0001
+	gpsTagLatitude          = 0x0002
+	gpsTagLongitudeRef      = 0x0003
+	gpsTagLongitude         = 0x0004
+)
+
+var errNoExif = errors.New("no exif data")
+
+// ExtractExif reads GPS position and capture time from a JPEG's APP1 Exif segment.
+// It returns errNoExif when the image carries no EXIF block (PNGs, already
+// stripped images, screenshots).
+func ExtractExif(fileBytes []byte) (*ExifData, error) {
+	payload, err := findExifPayload(fileBytes)
+	if err != nil {
+		return nil, err
+	}
+	return parseTIFF(payload)
+}
+
+// findExifPayload walks the JPEG segments and returns the TIFF structure inside
+// the first "Exif\0\0" APP1 segment.
+func findExifPayload(b []byte) ([]byte, error) {
+	if len(b) < 4 || b[0] != 0xFF || b[1] != 0xD8 {
+		return nil, errNoExif
+	}
+	i := 2
+	for i+4 <= len(b) {
+		if b[i] != 0xFF {
+			return nil, errNoExif
+		}
+		marker := b[i+1]
+		if marker == 0xDA || marker == 0xD9 { // start of scan / end of image
+			break
+		}
+		length := int(binary.BigEndian.Uint16(b[i+2 : i+4]))
+		if length < 2 || i+2+length > len(b) {
+			return nil, errNoExif
+		}
+		segment := b[i+4 : i+2+length]
+		if marker == 0xE1 && len(segment) > 6 && string(segment[:6]) == "Exif\x00\x00" {
+			return segment[6:], nil
+		}
+		i += 2 + length
+	}
+	return nil, errNoExif
+}
+
+type tiffReader struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+func (t *tiffReader) u16(off int) (uint16, bool) {
+	if off < 0 || off+2 > len(t.data) {
+		return 0, false
+	}
+	return t.order.Uint16(t.data[off:]), true
+}
+
+func (t *tiffReader) u32(off int) (uint32, bool) {
+	if off < 0 || off+4 > len(t.data) {
+		return 0, false
+	}
+	return t.order.Uint32(t.data[off:]), true
+}
+
+type ifdEntry struct {
+	typ    uint16
+	count  uint32
+	offset int // offset of the value itself (inline or pointed to)
+}
+
+// readIFD returns the entries of the IFD at off, keyed by tag.
+func (t *tiffReader) readIFD(off int) map[uint16]ifdEntry {
+	entries := map[uint16]ifdEntry{}
+	n, ok := t.u16(off)
+	if !ok {
+		return entries
+	}
+	for i := 0; i < int(n); i++ {
+		base := off + 2 + i*12
+		tag, ok1 := t.u16(base)
+		typ, ok2 := t.u16(base + 2)
+		count, ok3 := t.u32(base + 4)
+		if !ok1 || !ok2 || !ok3 {
+			break
+		}
+		size := exifTypeSize(typ) * int(count)
+		valueOff := base + 8
+		if size > 4 {
+			ptr, ok := t.u32(base + 8)
+			if !ok {
+				continue
+			}
+			valueOff = int(ptr)
+		}
+		if size < 0 || valueOff+size > len(t.data) {
+			continue
+		}
+		entries[tag] = ifdEntry{typ: typ, count: count, offset: valueOff}
+	}
+	return entries
+}
+
+func exifTypeSize(typ uint16) int {
+	switch typ {
+	case 1, 2, 6, 7: // BYTE, ASCII, SBYTE, UNDEFINED
+		return 1
+	case 3, 8: // SHORT, SSHORT
+		return 2
+	case 4, 9, 11: // LONG, SLONG, FLOAT
+		return 4
+	case 5, 10, 12: // RATIONAL, SRATIONAL, DOUBLE
+		return 8
+	}
+	return -1
+}
+
+func (t *tiffReader) ascii(e ifdEntry) string {
+	s := t.data[e.offset : e.offset+int(e.count)]
+	return string(bytes.TrimRight(s, "\x00 "))
+}
+
+func (t *tiffReader) rationals(e ifdEntry) []float64 {
+	if e.typ != 5 {
+		return nil
+	}
+	values := make([]float64, 0, e.count)
+	for i := 0; i < int(e.count); i++ {
+		num, _ := t.u32(e.offset + i*8)
+		den, _ := t.u32(e.offset + i*8 + 4)
+		if den == 0 {
+			return nil
+		}
+		values = append(values, float64(num)/float64(den))
+	}
+	return values
+}
+
+func (t *tiffReader) pointer(e ifdEntry) (int, bool) {
+	if e.typ != 4 {
+		return 0, false
+	}
+	v, ok := t.u32(e.offset)
+	return int(v), ok
+}
+
+func parseTIFF(data []byte) (*ExifData, error) {
+	if len(data) < 8 {
+		return nil, errNoExif
+	}
+	t := &tiffReader{data: data}
+	switch string(data[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return nil, fmt.Errorf("invalid tiff header")
+	}
+	ifd0Off, _ := t.u32(4)
+	ifd0 := t.readIFD(int(ifd0Off))
+
+	result := &ExifData{}
+
+	// Prefer DateTimeOriginal from the Exif sub-IFD; fall back to IFD0 DateTime
+	var rawTime string
+	if e, ok := ifd0[exifTagExifIFD]; ok {
+		if off, ok := t.pointer(e); ok {
+			if dt, ok := t.readIFD(off)[exifTagDateTimeOriginal]; ok && dt.typ == 2 {
+				rawTime = t.ascii(dt)
+			}
+		}
+	}
+	if rawTime == "" {
+		if dt, ok := ifd0[exifTagDateTime]; ok && dt.typ == 2 {
+			rawTime = t.ascii(dt)
+		}
+	}
+	if rawTime != "" {
+		// EXIF timestamps carry no zone; devices record local time
+		if taken, err := time.ParseInLocation("2006:01:02 15:04:05", rawTime, lagosLocation()); err == nil {
+			result.TakenAt = &taken
+		}
+	}
+
+	if e, ok := ifd0[exifTagGPSIFD]; ok {
+		if off, ok := t.pointer(e); ok {
+			gps := t.readIFD(off)
+			lat, latOK := gpsCoordinate(t, gps[gpsTagLatitude], gps[gpsTagLatitudeRef], "S")
+			lng, lngOK := gpsCoordinate(t, gps[gpsTagLongitude], gps[gpsTagLongitudeRef], "W")
+			if latOK && lngOK {
+				result.HasGPS = true
+				result.Latitude = lat
+				result.Longitude = lng
+			}
+		}
+	}
+
+	return result, nil
+}
+
+func gpsCoordinate(t *tiffReader, value, ref ifdEntry, negativeRef string) (float64, bool) {
+	if value.count == 0 {
+		return 0, false
+	}
+	parts := t.rationals(value)
+	if len(parts) != 3 {
+		return 0, false
+	}
+	coord := parts[0] + parts[1]/60 + parts[2]/3600
+	if ref.count > 0 && ref.typ == 2 && t.ascii(ref) == negativeRef {
+		coord = -coord
+	}
+	return coord, true
+}
+
+func lagosLocation() *time.Location {
+	loc, err := time.LoadLocation("Africa/Lagos")
+	if err != nil {
+		return time.FixedZone("WAT", 60*60)
+	}
+	return loc
+}
+
+// StripImageMetadata removes EXIF, XMP, ICC comments and every other metadata
+// segment from a JPEG or PNG without re-encoding the pixels. Formats it does
+// not recognise are returned unchanged.
+func StripImageMetadata(fileBytes []byte) ([]byte, error) {
+	switch {
+	case len(fileBytes) > 2 && fileBytes[0] == 0xFF && fileBytes[1] == 0xD8:
+		return stripJPEGMetadata(fileBytes)
+	case bytes.HasPrefix(fileBytes, pngSignature):
+		return stripPNGMetadata(fileBytes)
+	}
+	return fileBytes, nil
+}
+
+func stripJPEGMetadata(b []byte) ([]byte, error) {
+	out := bytes.NewBuffer(make([]byte, 0, len(b)))
+	out.Write(b[:2])
+	i := 2
+	for i+4 <= len(b) {
+		if b[i] != 0xFF {
+			return nil, fmt.Errorf("malformed jpeg segment at offset %d", i)
+		}
+		marker := b[i+1]
+		if marker == 0xDA {
+			// Start of scan: the compressed image data runs to the end of the file
+			out.Write(b[i:])
+			return out.Bytes(), nil
+		}
+		length := int(binary.BigEndian.Uint16(b[i+2 : i+4]))
+		if length < 2 || i+2+length > len(b) {
+			return nil, fmt.Errorf("malformed jpeg segment length at offset %d", i)
+		}
+		// Drop APP1..APP15 (Exif, XMP, ICC, maker notes) and COM. APP0 (JFIF) and
+		// APP14 (Adobe colour transform) are kept because decoders need them.
+		isMetadata := (marker >= 0xE1 && marker <= 0xEF && marker != 0xEE) || marker == 0xFE
+		if !isMetadata {
+			out.Write(b[i : i+2+length])
+		}
+		i += 2 + length
+	}
+	return nil, fmt.Errorf("jpeg has no image data")
+}
+
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1A, '\n'}
+
+// pngMetadataChunks are ancillary chunks that can carry location, time or
+// device information.
+var pngMetadataChunks = map[string]bool{
+	"eXIf": true,
+	"tEXt": true,
+	"iTXt": true,
+	"zTXt": true,
+	"tIME": true,
+}
+
+func stripPNGMetadata(b []byte) ([]byte, error) {
+	out := bytes.NewBuffer(make([]byte, 0, len(b)))
+	out.Write(pngSignature)
+	i := len(pngSignature)
+	for i+8 <= len(b) {
+		length := int(binary.BigEndian.Uint32(b[i : i+4]))
+		chunkType := string(b[i+4 : i+8])
+		end := i + 12 + length // length + type + data + crc
+		if length < 0 || end > len(b) {
+			return nil, fmt.Errorf("malformed png chunk at offset %d", i)
+		}
+		if !pngMetadataChunks[chunkType] {
+			out.Write(b[i:end])
+		}
+		i = end
+		if chunkType == "IEND" {
+			break
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// LocationConsistency compares where and when a photo says it was taken with
+// the location and time on the report. The score is between 0 (no agreement)
+// and 1 (taken on the spot, at the time). ok is false when the photo carries no
+// GPS data, in which case nothing can be said either way.
+func LocationConsistency(exif *ExifData, reportLat, reportLng float64, reportedAt time.Time) (score float64, distanceMeters float64, ok bool) {
+	if exif == nil || !exif.HasGPS || (reportLat == 0 && reportLng == 0) {
+		return 0, 0, false
+	}
+
+	distanceMeters = haversineMeters(exif.Latitude, exif.Longitude, reportLat, reportLng)
+	// Within a few hundred metres is "on the spot"; a couple of km away is doubtful
+	distanceScore := math.Exp(-distanceMeters / 1000)
+
+	timeScore := 0.5 // unknown capture time counts as neither agreeing nor disagreeing
+	if exif.TakenAt != nil && !reportedAt.IsZero() {
+		hours := math.Abs(reportedAt.Sub(*exif.TakenAt).Hours())
+		timeScore = math.Exp(-hours / 24)
+	}
+
+	score = 0.7*distanceScore + 0.3*timeScore
+	return math.Round(score*1000) / 1000, math.Round(distanceMeters), true
+}
+
+func haversineMeters(lat1, lng1, lat2, lng2 float64) float64 {
+	const earthRadius = 6371000.0
+	toRad := func(d float64) float64 { return d * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadius * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// byteFile adapts an in-memory buffer to multipart.File so stripped bytes can
+// go through the same upload helpers as the original form file.
+type byteFile struct {
+	*bytes.Reader
+}
+
+func (byteFile) Close() error { return nil }
+
+// NewByteFile wraps b as a multipart.File.
+func NewByteFile(b []byte) multipart.File {
+	return byteFile{bytes.NewReader(b)}
+}
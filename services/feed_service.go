@@ -0,0 +1,285 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/techagentng/citizenx/config"
+	"github.com/techagentng/citizenx/db"
+	"github.com/techagentng/citizenx/models"
+)
+
+const (
+	// defaultFeedCacheTTL is how long a ranked feed is reused when the TTL
+	// is not configured.
+	defaultFeedCacheTTL = 2 * time.Minute
+	// defaultTrendingWindow is how far back trending counts activity when
+	// the window is not configured.
+	defaultTrendingWindow = 6 * time.Hour
+	// defaultNearMeRadius is how far, in metres, near me looks when the
+	// radius is not configured.
+	defaultNearMeRadius = 10000.0
+	// feedCandidates is how many reports a ranked feed holds. Reports past
+	// it are reached through the latest feed.
+	feedCandidates = 500
+	// feedHorizon is how old a report can be and still rank as hot or for
+	// you.
+	feedHorizon = 14 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidFeed   = errors.New("feed must be latest, hot, trending, near_me or for_you")
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrFeedLocation  = errors.New("near_me needs lat and lng, or a state on your profile")
+)
+
+// FeedQuery asks for a page of a feed. Latitude and Longitude are where the
+// user is, for near me; without them it falls back to the user's state and
+// LGA.
+type FeedQuery struct {
+	Mode      string
+	User      *models.User
+	Latitude  *float64
+	Longitude *float64
+	Cursor    string
+	Limit     int
+}
+
+// feedCursor is the position a page ends at. Ranked feeds point into the
+// ranking cached for a TTL window; the latest feed points at the last report
+// shown.
+type feedCursor struct {
+	Window    int64  `json:"w,omitempty"`
+	Offset    int    `json:"o,omitempty"`
+	CreatedAt int64  `json:"t,omitempty"`
+	ID        string `json:"id,omitempty"`
+}
+
+// FeedService serves the report feeds, cursor paginated.
+type FeedService interface {
+	GetFeed(query FeedQuery) (*models.FeedPage, error)
+}
+
+type feedService struct {
+	Config   *config.Config
+	feedRepo db.FeedRepository
+	redis    *redis.Client
+}
+
+func NewFeedService(feedRepo db.FeedRepository, redisClient *redis.Client, conf *config.Config) FeedService {
+	return &feedService{
+		Config:   conf,
+		feedRepo: feedRepo,
+		redis:    redisClient,
+	}
+}
+
+func (s *feedService) cacheTTL() time.Duration {
+	if s.Config != nil && s.Config.FeedCacheTTL > 0 {
+		return s.Config.FeedCacheTTL
+	}
+	return defaultFeedCacheTTL
+}
+
+func (s *feedService) trendingWindow() time.Duration {
+	if s.Config != nil && s.Config.TrendingWindow > 0 {
+		return s.Config.TrendingWindow
+	}
+	return defaultTrendingWindow
+}
+
+func (s *feedService) nearMeRadius() float64 {
+	if s.Config != nil && s.Config.NearMeRadius > 0 {
+		return s.Config.NearMeRadius
+	}
+	return defaultNearMeRadius
+}
+
+func (s *feedService) GetFeed(query FeedQuery) (*models.FeedPage, error) {
+	if query.Limit <= 0 {
+		query.Limit = db.DefaultPageSize
+	}
+	cursor, err := decodeFeedCursor(query.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	switch query.Mode {
+	case models.FeedLatest:
+		return s.latest(cursor, query.Limit)
+	case models.FeedNearMe:
+		if (query.Latitude == nil || query.Longitude == nil) && query.User.StateName == "" {
+			return nil, ErrFeedLocation
+		}
+		return s.ranked(query, cursor)
+	case models.FeedHot, models.FeedTrending, models.FeedForYou:
+		return s.ranked(query, cursor)
+	}
+	return nil, ErrInvalidFeed
+}
+
+// latest pages through reports newest first by their position, so reports
+// filed while the user scrolls do not shift the pages.
+func (s *feedService) latest(cursor *feedCursor, limit int) (*models.FeedPage, error) {
+	var after *models.FeedPosition
+	if cursor != nil {
+		if cursor.ID == "" {
+			return nil, ErrInvalidCursor
+		}
+		after = &models.FeedPosition{ID: cursor.ID, CreatedAt: cursor.CreatedAt}
+	}
+	positions, err := s.feedRepo.GetLatestPositions(after, limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch feed: %v", err)
+	}
+
+	page := &models.FeedPage{}
+	if len(positions) > limit {
+		positions = positions[:limit]
+		last := positions[len(positions)-1]
+		page.NextCursor = encodeFeedCursor(feedCursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	ids := make([]string, len(positions))
+	for i, position := range positions {
+		ids[i] = position.ID
+	}
+	if page.Reports, err = s.feedRepo.GetReportMaps(ids); err != nil {
+		return nil, fmt.Errorf("failed to fetch feed: %v", err)
+	}
+	return page, nil
+}
+
+// ranked pages through a ranking held in Redis for the cache TTL. The cursor
+// carries the window its ranking was made in so later pages stay consistent
+// with the first; if it has expired the feed is ranked again and continues
+// from the same offset.
+func (s *feedService) ranked(query FeedQuery, cursor *feedCursor) (*models.FeedPage, error) {
+	seconds := int64(s.cacheTTL() / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	window := time.Now().Unix() / seconds
+	offset := 0
+	if cursor != nil {
+		if cursor.Window <= 0 || cursor.Offset < 0 {
+			return nil, ErrInvalidCursor
+		}
+		window, offset = cursor.Window, cursor.Offset
+	}
+	key := rankingKey(query, window)
+
+	ids, err := s.ranking(key, query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &models.FeedPage{}
+	if offset >= len(ids) {
+		page.Reports = []map[string]interface{}{}
+		return page, nil
+	}
+	end := offset + query.Limit
+	if end < len(ids) {
+		page.NextCursor = encodeFeedCursor(feedCursor{Window: window, Offset: end})
+	} else {
+		end = len(ids)
+	}
+	if page.Reports, err = s.feedRepo.GetReportMaps(ids[offset:end]); err != nil {
+		return nil, fmt.Errorf("failed to fetch feed: %v", err)
+	}
+	return page, nil
+}
+
+// rankingKey names the ranking cached for a TTL window. Rankings are shared
+// by everyone asking in the window, per user for for you and per area for
+// near me.
+func rankingKey(query FeedQuery, window int64) string {
+	key := fmt.Sprintf("feed:%s:%d", query.Mode, window)
+	switch query.Mode {
+	case models.FeedForYou:
+		key += fmt.Sprintf(":user:%d", query.User.ID)
+	case models.FeedNearMe:
+		if query.Latitude != nil && query.Longitude != nil {
+			// About a kilometre either way shares a ranking
+			key += fmt.Sprintf(":at:%.2f,%.2f", *query.Latitude, *query.Longitude)
+		} else {
+			key += ":in:" + query.User.StateName + "/" + query.User.LGAName
+		}
+	}
+	return key
+}
+
+// ranking returns the cached ranking under key, ranking afresh when it is
+// missing. Redis being unavailable costs a query, not the feed.
+func (s *feedService) ranking(key string, query FeedQuery) ([]string, error) {
+	ctx := context.Background()
+	if s.redis != nil {
+		cached, err := s.redis.Get(ctx, key).Result()
+		if err == nil {
+			var ids []string
+			if json.Unmarshal([]byte(cached), &ids) == nil {
+				return ids, nil
+			}
+		} else if !errors.Is(err, redis.Nil) {
+			log.Printf("Error reading feed cache: %v", err)
+		}
+	}
+
+	ids, err := s.rank(query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rank feed: %v", err)
+	}
+	if s.redis != nil {
+		encoded, _ := json.Marshal(ids)
+		// Kept for two windows so cursors handed out late in one still work
+		if err := s.redis.Set(ctx, key, encoded, 2*s.cacheTTL()).Err(); err != nil {
+			log.Printf("Error caching feed: %v", err)
+		}
+	}
+	return ids, nil
+}
+
+func (s *feedService) rank(query FeedQuery) ([]string, error) {
+	now := time.Now()
+	switch query.Mode {
+	case models.FeedHot:
+		return s.feedRepo.RankHot(now.Add(-feedHorizon).Unix(), now.Unix(), feedCandidates)
+	case models.FeedTrending:
+		return s.feedRepo.RankTrending(now.Add(-s.trendingWindow()).Unix(), feedCandidates)
+	case models.FeedNearMe:
+		if query.Latitude != nil && query.Longitude != nil {
+			return s.feedRepo.RankNearby(*query.Latitude, *query.Longitude, s.nearMeRadius(), feedCandidates)
+		}
+		return s.feedRepo.RankLocal(query.User.StateName, query.User.LGAName, feedCandidates)
+	case models.FeedForYou:
+		return s.feedRepo.RankForUser(query.User, now.Add(-feedHorizon).Unix(), now.Unix(), feedCandidates)
+	}
+	return nil, ErrInvalidFeed
+}
+
+func encodeFeedCursor(cursor feedCursor) string {
+	encoded, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
+
+// decodeFeedCursor returns nil for the first page.
+func decodeFeedCursor(raw string) (*feedCursor, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor feedCursor
+	if err := json.Unmarshal(decoded, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
@@ -0,0 +1,273 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// maxVideoSeconds caps how much of an uploaded video is transcoded.
+const maxVideoSeconds = 60
+
+// hlsSegmentSeconds is the target HLS segment length. Short segments let the
+// player switch rendition quickly when the connection changes.
+const hlsSegmentSeconds = 4
+
+// hlsRendition is one rung of the adaptive bitrate ladder.
+type hlsRendition struct {
+	Name         string
+	Height       int
+	VideoBitrate int // kbit/s
+	AudioBitrate int // kbit/s
+}
+
+// hlsLadder is ordered from the lowest rendition up. Renditions taller than the
+// source are skipped, but the lowest one is always produced.
+var hlsLadder = []hlsRendition{
+	{Name: "240p", Height: 240, VideoBitrate: 400, AudioBitrate: 64},
+	{Name: "480p", Height: 480, VideoBitrate: 1000, AudioBitrate: 96},
+	{Name: "720p", Height: 720, VideoBitrate: 2500, AudioBitrate: 128},
+}
+
+// VideoProbe is what ffprobe reports about an uploaded video.
+type VideoProbe struct {
+	DurationSeconds float64
+	Width           int
+	Height          int
+	VideoCodec      string
+	AudioCodec      string
+}
+
+// storedVideo is the result of transcoding a video and storing its renditions.
+type storedVideo struct {
+	PlaylistURL string
+	PosterURL   string
+	Renditions  []string
+	Probe       *VideoProbe
+}
+
+// probeVideo runs ffprobe on the file and returns its duration, dimensions and
+// codecs.
+func probeVideo(path string) (*VideoProbe, error) {
+	cmd := exec.Command("ffprobe", "-v", "error", "-print_format", "json", "-show_format", "-show_streams", path)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("ffprobe error: %v, details: %s", err, stderr.String())
+	}
+
+	var out struct {
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+		Streams []struct {
+			CodecType string `json:"codec_type"`
+			CodecName string `json:"codec_name"`
+			Width     int    `json:"width"`
+			Height    int    `json:"height"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		return nil, fmt.Errorf("failed to parse ffprobe output: %v", err)
+	}
+
+	probe := &VideoProbe{}
+	probe.DurationSeconds, _ = strconv.ParseFloat(out.Format.Duration, 64)
+	for _, stream := range out.Streams {
+		switch stream.CodecType {
+		case "video":
+			if probe.VideoCodec == "" {
+				probe.VideoCodec = stream.CodecName
+				probe.Width = stream.Width
+				probe.Height = stream.Height
+			}
+		case "audio":
+			if probe.AudioCodec == "" {
+				probe.AudioCodec = stream.CodecName
+			}
+		}
+	}
+	if probe.VideoCodec == "" {
+		return nil, fmt.Errorf("file has no video stream")
+	}
+	return probe, nil
+}
+
+// shortSide is the smaller dimension of the source. Rendition heights apply to
+// it so portrait phone videos are not squeezed.
+func (p *VideoProbe) shortSide() int {
+	if p.Width < p.Height {
+		return p.Width
+	}
+	return p.Height
+}
+
+// transcodedSeconds is the length of the transcoded video, which is cut at
+// maxVideoSeconds.
+func (p *VideoProbe) transcodedSeconds() float64 {
+	if p.DurationSeconds > maxVideoSeconds {
+		return maxVideoSeconds
+	}
+	return p.DurationSeconds
+}
+
+// renditionsFor returns the part of the ladder that does not upscale the source.
+func renditionsFor(probe *VideoProbe) []hlsRendition {
+	renditions := []hlsRendition{hlsLadder[0]}
+	for _, rendition := range hlsLadder[1:] {
+		if rendition.Height <= probe.shortSide() {
+			renditions = append(renditions, rendition)
+		}
+	}
+	return renditions
+}
+
+// transcodeHLS writes one VOD playlist plus segments per rendition into outDir,
+// then the master playlist that lists them.
+func transcodeHLS(sourcePath, outDir string, probe *VideoProbe) ([]hlsRendition, error) {
+	renditions := renditionsFor(probe)
+	for _, rendition := range renditions {
+		args := []string{
+			"-y", "-i", sourcePath,
+			"-t", strconv.Itoa(maxVideoSeconds),
+			"-vf", fmt.Sprintf("scale='if(gt(iw,ih),-2,%[1]d)':'if(gt(iw,ih),%[1]d,-2)'", rendition.Height),
+			"-c:v", "libx264", "-profile:v", "main", "-preset", "veryfast",
+			"-b:v", fmt.Sprintf("%dk", rendition.VideoBitrate),
+			"-maxrate", fmt.Sprintf("%dk", rendition.VideoBitrate*107/100),
+			"-bufsize", fmt.Sprintf("%dk", rendition.VideoBitrate*3/2),
+			// Fixed GOPs aligned to segment boundaries so renditions switch cleanly
+			"-force_key_frames", fmt.Sprintf("expr:gte(t,n_forced*%d)", hlsSegmentSeconds),
+			"-sc_threshold", "0",
+		}
+		if probe.AudioCodec != "" {
+			args = append(args, "-c:a", "aac", "-ac", "2", "-b:a", fmt.Sprintf("%dk", rendition.AudioBitrate))
+		} else {
+			args = append(args, "-an")
+		}
+		args = append(args,
+			"-f", "hls",
+			"-hls_time", strconv.Itoa(hlsSegmentSeconds),
+			"-hls_playlist_type", "vod",
+			"-hls_segment_filename", filepath.Join(outDir, rendition.Name+"_%03d.ts"),
+			filepath.Join(outDir, rendition.Name+".m3u8"),
+		)
+
+		cmd := exec.Command("ffmpeg", args...)
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err != nil {
+			return nil, fmt.Errorf("ffmpeg %s error: %v, details: %s", rendition.Name, err, stderr.String())
+		}
+	}
+
+	if err := os.WriteFile(filepath.Join(outDir, "master.m3u8"), masterPlaylist(renditions, probe), 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write master playlist: %v", err)
+	}
+	return renditions, nil
+}
+
+func masterPlaylist(renditions []hlsRendition, probe *VideoProbe) []byte {
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+	for _, rendition := range renditions {
+		bandwidth := rendition.VideoBitrate * 1000
+		if probe.AudioCodec != "" {
+			bandwidth += rendition.AudioBitrate * 1000
+		}
+		width, height := rendition.Height*16/9, rendition.Height
+		if short := probe.shortSide(); short > 0 {
+			width = probe.Width * rendition.Height / short
+			height = probe.Height * rendition.Height / short
+		}
+		fmt.Fprintf(&b, "#EXT-X-STREAM-INF:BANDWIDTH=%d,RESOLUTION=%dx%d\n%s.m3u8\n", bandwidth, width+width%2, height+height%2, rendition.Name)
+	}
+	return []byte(b.String())
+}
+
+// extractPoster grabs a representative frame a little way into the video.
+func extractPoster(sourcePath, posterPath string, probe *VideoProbe) error {
+	offset := 1.0
+	if probe.DurationSeconds > 0 && probe.DurationSeconds < 2 {
+		offset = probe.DurationSeconds / 2
+	}
+	cmd := exec.Command("ffmpeg", "-y", "-ss", strconv.FormatFloat(offset, 'f', 2, 64), "-i", sourcePath,
+		"-frames:v", "1", "-vf", "scale=720:-2", posterPath)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("ffmpeg poster error: %v, details: %s", err, stderr.String())
+	}
+	return nil
+}
+
+// processAndStoreVideo transcodes the video to an HLS ladder, stores every
+// playlist and segment under one folder and returns the master playlist URL.
+func (m *mediaService) processAndStoreVideo(fileBytes []byte) (*storedVideo, error) {
+	workDir, err := os.MkdirTemp("", "video_*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	sourcePath := filepath.Join(workDir, "source")
+	if err := os.WriteFile(sourcePath, fileBytes, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to write temporary video file: %v", err)
+	}
+
+	probe, err := probeVideo(sourcePath)
+	if err != nil {
+		return nil, err
+	}
+
+	hlsDir := filepath.Join(workDir, "hls")
+	if err := os.Mkdir(hlsDir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create HLS directory: %v", err)
+	}
+	renditions, err := transcodeHLS(sourcePath, hlsDir, probe)
+	if err != nil {
+		return nil, err
+	}
+	posterPath := filepath.Join(workDir, "poster.jpg")
+	if err := extractPoster(sourcePath, posterPath, probe); err != nil {
+		return nil, err
+	}
+
+	// Segments are uploaded before the playlists that reference them
+	folder := "media/hls/" + uuid.New().String()
+	entries, err := os.ReadDir(hlsDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read HLS output: %v", err)
+	}
+	video := &storedVideo{Probe: probe}
+	for _, playlists := range []bool{false, true} {
+		for _, entry := range entries {
+			if strings.HasSuffix(entry.Name(), ".m3u8") != playlists {
+				continue
+			}
+			fileURL, err := m.storeLocalFile(filepath.Join(hlsDir, entry.Name()), entry.Name(), folder)
+			if err != nil {
+				return nil, err
+			}
+			if entry.Name() == "master.m3u8" {
+				video.PlaylistURL = fileURL
+			}
+		}
+	}
+
+	video.PosterURL, err = m.storeLocalFile(posterPath, generateUniqueFilename(".jpg"), "media/thumbnail")
+	if err != nil {
+		return nil, err
+	}
+	for _, rendition := range renditions {
+		video.Renditions = append(video.Renditions, rendition.Name)
+	}
+	return video, nil
+}
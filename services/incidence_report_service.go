@@ -1,7 +1,6 @@
 package services
 
 import (
-	"errors"
 	"fmt"
 	"log"
 	"math"
@@ -18,6 +17,7 @@ type IncidentReportService interface {
 	SaveReport(userID uint, lat float64, lng float64, report *models.IncidentReport, reportID string, totalPoints int) (*models.IncidentReport, error)
 	GetAllReports(filter string) ([]map[string]interface{}, error)
 	GetAllReportsByState(state string, page int) ([]models.IncidentReport, error)
+	SearchReports(query string, attributes map[string]string, page int) ([]models.IncidentReport, error)
 	GetAllReportsByLGA(lga string, page int) ([]models.IncidentReport, error)
 	GetAllReportsByReportType(reportType string, page int) ([]models.IncidentReport, error)
 	GetReportPercentageByState() ([]models.StateReportPercentage, error)
@@ -28,8 +28,6 @@ type IncidentReportService interface {
 	ListAllStatesWithReportCounts() ([]models.StateReportCount, error)
 	GetTotalReportCount() (int64, error)
 	GetNamesByCategory(stateName string, lgaID string, reportTypeCategory string) ([]string, error)
-	BookmarkReport(userID uint, reportID uuid.UUID) error
-	GetBookmarkedReports(userID uint) ([]models.IncidentReport, error)
 	GetUserReports(userID uint) ([]models.ReportType, error)
 	GetReportTypeCountsByLGA(lga string) (map[string]interface{}, error)
 	AddMediaToReport(reportTypeID string, feedURLs, thumbnailURLs, fullsizeURLs []string) error
@@ -43,16 +41,18 @@ type IncidentReportService interface {
 type IncidentService struct {
     Config       *config.Config
     incidentRepo db.IncidentReportRepository
-    rewardRepo   db.RewardRepository
+    rewards      RewardEngine
+    badges       BadgeService
     mediaRepo    db.MediaRepository
     DB           *gorm.DB
 }
 
-func NewIncidentReportService(incidentReportRepo db.IncidentReportRepository, rewardRepo db.RewardRepository, mediaRepo db.MediaRepository, conf *config.Config, db *gorm.DB) *IncidentService {
+func NewIncidentReportService(incidentReportRepo db.IncidentReportRepository, rewards RewardEngine, badges BadgeService, mediaRepo db.MediaRepository, conf *config.Config, db *gorm.DB) *IncidentService {
     return &IncidentService{
         Config:       conf,
         incidentRepo: incidentReportRepo,
-        rewardRepo:   rewardRepo,
+        rewards:      rewards,
+        badges:       badges,
         mediaRepo:    mediaRepo,
         DB:           db,
     }
@@ -66,46 +66,45 @@ func (s *IncidentService) GetTopStatesWithReportCount() ([]map[string]interface{
 func (s *IncidentService) SaveReport(userID uint, lat float64, lng float64, report *models.IncidentReport, reportID string, totalPoints int) (*models.IncidentReport, error) {
     fmt.Println("Report ID:", reportID)
 
-    var reward *models.Reward
-    mediaPoints := totalPoints * 10
-    var descPoint, locationPoint int
+    // The first report earns first report bonuses
+    hasPreviousReports, err := s.incidentRepo.HasPreviousReports(userID)
+    if err != nil {
+        return nil, fmt.Errorf("error checking previous reports: %v", err)
+    }
 
+    // Work out the points from the reward rules before saving so the report
+    // carries them
+    event := RewardEvent{
+        UserID:      userID,
+        ReportID:    reportID,
+        CategoryID:  report.CategoryID,
+        Verified:    report.IsVerified,
+        FirstReport: !hasPreviousReports,
+        Key:         ReportSubmittedKey(reportID),
+    }
+    quantities := map[string]int{
+        models.RewardActionReportSubmitted: 1,
+        models.RewardActionMediaItem:       totalPoints,
+    }
     if report.Description != "" {
-        descPoint += 10
+        quantities[models.RewardActionDescription] = 1
     }
     if !math.IsNaN(lat) && !math.IsNaN(lng) {
-        locationPoint += 10
+        quantities[models.RewardActionLocation] = 1
     }
 
-    reportPoints := locationPoint + descPoint + mediaPoints
-
-    // Check if user has previous reports
-    hasRewardPoints, err := s.incidentRepo.HasPreviousReports(userID)
-    if err != nil {
-        return nil, fmt.Errorf("error checking previous reports: %v", err)
-    }
-
-    // Set reward details based on the user's previous reports
-    if !hasRewardPoints {
-        reward = &models.Reward{
-            UserID:           userID,
-            RewardType:       "New entry",
-            Point:            reportPoints,
-            IncidentReportID: reportID,
-            Balance:          10, // Assuming new users get a balance of 10
+    reportPoints := 0
+    awards := map[string][]RewardAward{}
+    for action, quantity := range quantities {
+        event.Action, event.Quantity = action, quantity
+        actionAwards, err := s.rewards.Evaluate(event)
+        if err != nil {
+            return nil, fmt.Errorf("error evaluating rewards: %v", err)
         }
-    } else {
-        reward = &models.Reward{
-            UserID:           userID,
-            RewardType:       "Another entry",
-            Point:            reportPoints,
-            IncidentReportID: reportID,
+        for _, award := range actionAwards {
+            reportPoints += award.Points
         }
-    }
-
-    // Update the reward record in the repository
-    if err := s.incidentRepo.UpdateReward(userID, reward); err != nil {
-        return nil, fmt.Errorf("error creating reward: %v", err)
+        awards[action] = actionAwards
     }
 
     // Set the reward points on the report
@@ -146,33 +145,30 @@ func (s *IncidentService) SaveReport(userID uint, lat float64, lng float64, repo
         return nil, fmt.Errorf("error saving report: %v", err)
     }
 
+    // Credit the points once the report exists; the key stops a retried
+    // submission from crediting twice
+    for action, actionAwards := range awards {
+        event.Action = action
+        if _, err := s.rewards.Credit(event, actionAwards); err != nil {
+            return nil, fmt.Errorf("error crediting reward: %v", err)
+        }
+    }
+    s.badges.EvaluateAsync(userID, BadgeEventReportSubmitted)
+
     // Construct the response object with saved report data
     reportResponse := &models.IncidentReport{
         DateOfIncidence:      savedReport.DateOfIncidence,
         Description:          savedReport.Description,
-        FeedURLs:             savedReport.FeedURLs,
         RewardPoint:          savedReport.RewardPoint,
         ActionTypeName:       savedReport.ActionTypeName,
         Rating:               savedReport.Rating,
-        HospitalName:         savedReport.HospitalName,
-        Department:           savedReport.Department,
-        DepartmentHeadName:   savedReport.DepartmentHeadName,
-        AccidentCause:        savedReport.AccidentCause,
-        SchoolName:           savedReport.SchoolName,
-        VicePrincipal:        savedReport.VicePrincipal,
-        OutageLength:         savedReport.OutageLength,
-        AirportName:          savedReport.AirportName,
-        Country:              savedReport.Country,
-        StateEmbassyLocation: savedReport.StateEmbassyLocation,
-        NoWater:              savedReport.NoWater,
-        AmbassedorsName:      savedReport.AmbassedorsName,
-        HospitalAddress:      savedReport.HospitalAddress,
-        RoadName:             savedReport.RoadName,
-        AirlineName:          savedReport.AirlineName,
         Category:             savedReport.Category,
+        CategoryID:           savedReport.CategoryID,
+        SubCategoryID:        savedReport.SubCategoryID,
+        Attributes:           savedReport.Attributes,
         UserFullname:         savedReport.UserFullname,
         UserUsername:         savedReport.UserUsername,
-        ThumbnailURLs:        savedReport.ThumbnailURLs,
+        ReporterImage:        savedReport.ReporterImage,
         StateName:            savedReport.StateName,
         LGAName:              savedReport.LGAName,
 		IsAnonymous:    savedReport.IsAnonymous,
@@ -194,6 +190,10 @@ func (s *IncidentService) GetAllReportsByState(state string, page int) ([]models
 	return s.incidentRepo.GetAllReportsByState(state, page)
 }
 
+func (s *IncidentService) SearchReports(query string, attributes map[string]string, page int) ([]models.IncidentReport, error) {
+	return s.incidentRepo.SearchIncidentReports(strings.TrimSpace(query), attributes, page)
+}
+
 func (s *IncidentService) GetAllReportsByLGA(lga string, page int) ([]models.IncidentReport, error) {
 	return s.incidentRepo.GetAllReportsByLGA(lga, page)
 }
@@ -247,44 +247,6 @@ func (s *IncidentService) GetNamesByCategory(stateName string, lgaID string, rep
 	return names, nil
 }
 
-func (s *IncidentService) BookmarkReport(userID uint, reportID uuid.UUID) error {
-	// First check if the report exists
-	exists, err := s.incidentRepo.ReportExists(reportID)
-	if err != nil {
-		return err
-	}
-	if !exists {
-		return errors.New("report not found")
-	}
-
-	// Check if the report is already bookmarked by the user
-	var bookmark models.Bookmark
-	err = s.incidentRepo.IsBookmarked(userID, reportID, &bookmark)
-	if err == nil {
-		// Report is already bookmarked
-		return errors.New("report already bookmarked")
-	}
-
-	// Only proceed if the error was "record not found"
-	if err != gorm.ErrRecordNotFound {
-		return err // Return any other unexpected errors
-	}
-
-	// Create a new bookmark
-	newBookmark := models.Bookmark{
-		UserID:   userID,
-		ReportID: reportID,
-	}
-
-	// Save the bookmark
-	return s.incidentRepo.SaveBookmark(&newBookmark)
-}
-
-func (s *IncidentService) GetBookmarkedReports(userID uint) ([]models.IncidentReport, error) {
-	// Call the repository method to get the bookmarked reports
-	return s.incidentRepo.GetBookmarkedReports(userID)
-}
-
 func (s *IncidentService) GetUserReports(userID uint) ([]models.ReportType, error) {
 	return s.incidentRepo.GetReportsByUserID(userID)
 }
@@ -324,14 +286,6 @@ func (s *IncidentService) AddMediaToReport(reportTypeID string, feedURLs, thumbn
 	return nil
 }
 
-// appendURLs ensures that the new URLs are appended correctly to the existing string of URLs
-func appendURLs(existingURLs string, newURLs []string) string {
-	if existingURLs != "" {
-		return existingURLs + "," + strings.Join(newURLs, ",")
-	}
-	return strings.Join(newURLs, ",")
-}
-
 func (s *IncidentService) GetReportCountByLGA(lga string) (int, error) {
     return s.incidentRepo.GetReportCountByLGA(lga)
 }
@@ -0,0 +1,402 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/google/uuid"
+	"github.com/techagentng/citizenx/db"
+	"github.com/techagentng/citizenx/models"
+)
+
+var ErrInvalidLeaderboard = errors.New("invalid leaderboard")
+
+const (
+	leaderboardKeyPrefix = "leaderboard:"
+	// Rebuilt boards are written under this prefix, which leaderboardKeyPrefix
+	// scans don't match, until they replace the live ones.
+	leaderboardRebuildPrefix = "leaderboard-rebuild:"
+)
+
+// How far each signal moves a user's reputation score.
+const (
+	reputationPerApprovedReport = 10
+	reputationPerRejectedReport = -15
+	reputationPerUpvote         = 1
+	reputationPerDownvote       = -1
+	reputationVerifiedBonus     = 25
+	reputationQueriedPenalty    = -50
+)
+
+// Reputation weights stay within these bounds; a score of reputationScale
+// doubles a user's weight.
+const (
+	reputationScale     = 500
+	minReputationWeight = 0.5
+	maxReputationWeight = 2.0
+)
+
+// LeaderboardService ranks users by the points they earn, in Redis sorted
+// sets fed from the ledger, and scores their reputation.
+type LeaderboardService interface {
+	Record(txn *models.LedgerTransaction) error
+	GetLeaderboard(period, scope, name string, page int) ([]models.LeaderboardEntry, error)
+	GetStanding(userID uint, period, scope string) (*models.LeaderboardEntry, error)
+	Rebuild() error
+	GetReputation(userID uint) (*models.Reputation, error)
+}
+
+type leaderboardService struct {
+	repo  db.LeaderboardRepository
+	redis *redis.Client
+}
+
+func NewLeaderboardService(repo db.LeaderboardRepository, redisClient *redis.Client) LeaderboardService {
+	return &leaderboardService{repo: repo, redis: redisClient}
+}
+
+// Record adds the points of a newly posted ledger transaction to the boards
+// of the user's state and LGA.
+func (s *leaderboardService) Record(txn *models.LedgerTransaction) error {
+	if txn.Amount == 0 || !txn.EarnsPoints() {
+		return nil
+	}
+	user, err := s.repo.GetUserByID(txn.UserID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch user: %v", err)
+	}
+	if user == nil {
+		return nil
+	}
+	ctx := context.Background()
+	pipe := s.redis.Pipeline()
+	addEarnedPoints(ctx, pipe, "", models.EarnedPoints{
+		UserID:         txn.UserID,
+		StateName:      user.StateName,
+		LGAName:        user.LGAName,
+		Amount:         txn.Amount,
+		IdempotencyKey: txn.IdempotencyKey,
+		CreatedAt:      txn.CreatedAt,
+	})
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to update leaderboards: %v", err)
+	}
+	return nil
+}
+
+// Rebuild replaces every board with totals replayed from the ledger, for
+// when Redis has lost them. The boards are built under temporary keys and
+// renamed over the live ones in one transaction, so readers never see them
+// empty. Points recorded on the live boards while the replay runs would be
+// lost in the swap, so transactions posted after the replay started are
+// replayed onto the temporary boards until none are left, right before it.
+func (s *leaderboardService) Rebuild() error {
+	ctx := context.Background()
+	tmpPrefix := leaderboardRebuildPrefix + uuid.New().String() + ":"
+	built := make(map[string]bool)
+	lastID, _, err := s.replay(ctx, tmpPrefix, 0, built)
+	if err != nil {
+		s.dropRebuild(ctx, tmpPrefix, built)
+		return fmt.Errorf("failed to rebuild leaderboards: %v", err)
+	}
+
+	var stale []string
+	iter := s.redis.Scan(ctx, 0, leaderboardKeyPrefix+"*", 500).Iterator()
+	for iter.Next(ctx) {
+		if !built[iter.Val()] {
+			stale = append(stale, iter.Val())
+		}
+	}
+	if err := iter.Err(); err != nil {
+		s.dropRebuild(ctx, tmpPrefix, built)
+		return fmt.Errorf("failed to list leaderboards: %v", err)
+	}
+
+	for replayed := 1; replayed > 0; {
+		lastID, replayed, err = s.replay(ctx, tmpPrefix, lastID, built)
+		if err != nil {
+			s.dropRebuild(ctx, tmpPrefix, built)
+			return fmt.Errorf("failed to rebuild leaderboards: %v", err)
+		}
+	}
+
+	// Errors are checked per command below
+	cmds, _ := s.redis.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if len(stale) > 0 {
+			pipe.Del(ctx, stale...)
+		}
+		for key := range built {
+			pipe.Rename(ctx, tmpPrefix+key, key)
+		}
+		return nil
+	})
+	for _, cmd := range cmds {
+		// A weekly or monthly board may have expired while it was rebuilt
+		if err := cmd.Err(); err != nil && !strings.Contains(err.Error(), "no such key") {
+			return fmt.Errorf("failed to swap leaderboards: %v", err)
+		}
+	}
+	return nil
+}
+
+// replay adds the ledger transactions after afterID that earned points to
+// the boards under prefix, noting the boards it touched in built. It returns
+// the ID of the last transaction replayed and how many it replayed.
+func (s *leaderboardService) replay(ctx context.Context, prefix string, afterID uint, built map[string]bool) (uint, int, error) {
+	pipe := s.redis.Pipeline()
+	lastID, queued := afterID, 0
+	err := s.repo.EachEarnedPoints(afterID, func(earned models.EarnedPoints) error {
+		for _, key := range addEarnedPoints(ctx, pipe, prefix, earned) {
+			built[key] = true
+		}
+		lastID = earned.ID
+		queued++
+		if queued%500 != 0 {
+			return nil
+		}
+		_, err := pipe.Exec(ctx)
+		return err
+	})
+	if err != nil {
+		return lastID, queued, err
+	}
+	if queued%500 != 0 {
+		_, err = pipe.Exec(ctx)
+	}
+	return lastID, queued, err
+}
+
+// dropRebuild removes the temporary boards of a rebuild that failed.
+func (s *leaderboardService) dropRebuild(ctx context.Context, prefix string, built map[string]bool) {
+	keys := make([]string, 0, len(built))
+	for key := range built {
+		keys = append(keys, prefix+key)
+	}
+	if len(keys) == 0 {
+		return
+	}
+	if err := s.redis.Del(ctx, keys...).Err(); err != nil {
+		log.Printf("failed to remove temporary leaderboards: %v", err)
+	}
+}
+
+// addEarnedPoints queues the increments for every board the points count
+// towards, under prefix, and returns the names of those boards. Opening
+// balances carried over from the old rewards table were not earned in any one
+// week or month, so they only count all time.
+func addEarnedPoints(ctx context.Context, pipe redis.Pipeliner, prefix string, earned models.EarnedPoints) []string {
+	at := time.Unix(earned.CreatedAt, 0).In(lagosLocation())
+	periods := []string{models.LeaderboardAllTime}
+	if !strings.HasPrefix(earned.IdempotencyKey, "opening:") {
+		periods = append(periods, models.LeaderboardWeekly, models.LeaderboardMonthly)
+	}
+	scopes := map[string]string{models.LeaderboardGlobal: ""}
+	if earned.StateName != "" {
+		scopes[models.LeaderboardState] = earned.StateName
+	}
+	if earned.LGAName != "" {
+		scopes[models.LeaderboardLGA] = earned.LGAName
+	}
+
+	var keys []string
+	member := strconv.FormatUint(uint64(earned.UserID), 10)
+	for _, period := range periods {
+		ttl := leaderboardTTL(period)
+		if ttl > 0 && time.Since(at) > ttl {
+			continue
+		}
+		for scope, name := range scopes {
+			key := leaderboardKey(period, at, scope, name)
+			keys = append(keys, key)
+			pipe.ZIncrBy(ctx, prefix+key, float64(earned.Amount), member)
+			if ttl > 0 {
+				pipe.ExpireAt(ctx, prefix+key, at.Add(ttl))
+			}
+		}
+	}
+	return keys
+}
+
+// leaderboardKey names the sorted set of a board. Weekly and monthly boards
+// are keyed by the ISO week or month, in Lagos time, that contains at.
+func leaderboardKey(period string, at time.Time, scope, name string) string {
+	var window string
+	switch period {
+	case models.LeaderboardWeekly:
+		year, week := at.ISOWeek()
+		window = fmt.Sprintf("week:%d-%02d", year, week)
+	case models.LeaderboardMonthly:
+		window = at.Format("month:2006-01")
+	default:
+		window = "all"
+	}
+	key := leaderboardKeyPrefix + window + ":" + scope
+	if scope != models.LeaderboardGlobal {
+		key += ":" + strings.ToLower(strings.TrimSpace(name))
+	}
+	return key
+}
+
+// leaderboardTTL is how long a period's boards are kept after their last
+// update, long enough to look back at the previous period.
+func leaderboardTTL(period string) time.Duration {
+	switch period {
+	case models.LeaderboardWeekly:
+		return 3 * 7 * 24 * time.Hour
+	case models.LeaderboardMonthly:
+		return 62 * 24 * time.Hour
+	}
+	return 0
+}
+
+func validLeaderboard(period, scope string) bool {
+	switch period {
+	case models.LeaderboardWeekly, models.LeaderboardMonthly, models.LeaderboardAllTime:
+	default:
+		return false
+	}
+	switch scope {
+	case models.LeaderboardGlobal, models.LeaderboardState, models.LeaderboardLGA:
+		return true
+	}
+	return false
+}
+
+// GetLeaderboard returns a page of the current board. State and LGA boards
+// need the name of the state or LGA.
+func (s *leaderboardService) GetLeaderboard(period, scope, name string, page int) ([]models.LeaderboardEntry, error) {
+	if !validLeaderboard(period, scope) {
+		return nil, fmt.Errorf("%w: unknown period or scope", ErrInvalidLeaderboard)
+	}
+	if scope != models.LeaderboardGlobal && strings.TrimSpace(name) == "" {
+		return nil, fmt.Errorf("%w: %s name is required", ErrInvalidLeaderboard, scope)
+	}
+
+	ctx := context.Background()
+	key := leaderboardKey(period, time.Now().In(lagosLocation()), scope, name)
+	start := int64((page - 1) * db.DefaultPageSize)
+	scores, err := s.redis.ZRevRangeWithScores(ctx, key, start, start+db.DefaultPageSize-1).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch leaderboard: %v", err)
+	}
+
+	entries := make([]models.LeaderboardEntry, 0, len(scores))
+	ids := make([]uint, 0, len(scores))
+	for i, z := range scores {
+		// Users whose points were all reversed drop off the board
+		if z.Score <= 0 {
+			break
+		}
+		id, err := strconv.ParseUint(fmt.Sprint(z.Member), 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, uint(id))
+		entries = append(entries, models.LeaderboardEntry{
+			Rank:   start + int64(i) + 1,
+			UserID: uint(id),
+			Points: int64(z.Score),
+		})
+	}
+
+	users, err := s.repo.GetUsersByIDs(ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch users: %v", err)
+	}
+	byID := make(map[uint]models.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+	for i := range entries {
+		user := byID[entries[i].UserID]
+		if user.IsAnonymous {
+			entries[i].Username = "Anonymous"
+			continue
+		}
+		entries[i].Username = user.Username
+		entries[i].Fullname = user.Fullname
+		entries[i].ThumbNailURL = user.ThumbNailURL
+	}
+	return entries, nil
+}
+
+// GetStanding returns the user's rank and points on the current board of
+// their own state or LGA. Rank is 0 when they have not earned points on it.
+func (s *leaderboardService) GetStanding(userID uint, period, scope string) (*models.LeaderboardEntry, error) {
+	if !validLeaderboard(period, scope) {
+		return nil, fmt.Errorf("%w: unknown period or scope", ErrInvalidLeaderboard)
+	}
+	user, err := s.repo.GetUserByID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch user: %v", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	name := user.StateName
+	if scope == models.LeaderboardLGA {
+		name = user.LGAName
+	}
+
+	ctx := context.Background()
+	key := leaderboardKey(period, time.Now().In(lagosLocation()), scope, name)
+	member := strconv.FormatUint(uint64(userID), 10)
+	standing := &models.LeaderboardEntry{UserID: userID}
+	score, err := s.redis.ZScore(ctx, key, member).Result()
+	if errors.Is(err, redis.Nil) || (err == nil && score <= 0) {
+		return standing, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch standing: %v", err)
+	}
+	rank, err := s.redis.ZRevRank(ctx, key, member).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch standing: %v", err)
+	}
+	standing.Rank = rank + 1
+	standing.Points = int64(score)
+	return standing, nil
+}
+
+// GetReputation scores the user from their approved and rejected reports,
+// the votes their reports received, admin queries and verification.
+func (s *leaderboardService) GetReputation(userID uint) (*models.Reputation, error) {
+	stats, err := s.repo.GetReputationStats(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch reputation: %v", err)
+	}
+	if stats == nil {
+		return nil, ErrUserNotFound
+	}
+	score := stats.ApprovedReports*reputationPerApprovedReport +
+		stats.RejectedReports*reputationPerRejectedReport +
+		stats.UpvotesReceived*reputationPerUpvote +
+		stats.DownvotesReceived*reputationPerDownvote
+	if stats.IsVerified {
+		score += reputationVerifiedBonus
+	}
+	if stats.IsQueried {
+		score += reputationQueriedPenalty
+	}
+	return &models.Reputation{
+		UserID:          userID,
+		Score:           score,
+		Weight:          ReputationWeight(score),
+		ReputationStats: *stats,
+	}, nil
+}
+
+// ReputationWeight turns a reputation score into the factor a user's votes
+// and rewards can be scaled by.
+func ReputationWeight(score int) float64 {
+	weight := 1 + float64(score)/reputationScale
+	weight = math.Max(minReputationWeight, math.Min(maxReputationWeight, weight))
+	return math.Round(weight*100) / 100
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
+	"github.com/techagentng/citizenx/db"
+	"github.com/techagentng/citizenx/models"
+)
+
+var (
+	ErrInsufficientPoints  = errors.New("insufficient points")
+	ErrInvalidPoints       = errors.New("points must be a non-zero whole number")
+	ErrTransactionNotFound = errors.New("ledger transaction not found")
+	ErrAlreadyReversed     = errors.New("transaction is a reversal and cannot be reversed")
+)
+
+// Idempotency keys for the events that move points. A key names the event, so
+// posting it again, for example approving a report twice, credits nothing.
+func ReportSubmittedKey(reportID string) string { return "report:" + reportID }
+func MediaBonusKey(mediaRef string) string      { return "media:" + mediaRef }
+func ReversalKey(key string) string             { return "reversal:" + key }
+
+// ApprovalKey names the approval of a report in the given round. Rounds after
+// the first are keyed under the first, so reversing ApprovalKey(id, 0) as an
+// event undoes every round.
+func ApprovalKey(reportID string, round int) string {
+	if round == 0 {
+		return "approval:" + reportID
+	}
+	return fmt.Sprintf("approval:%s:%d", reportID, round)
+}
+
+// LedgerService posts points to the append-only ledger and reads balances
+// derived from it.
+type LedgerService interface {
+	Credit(userID uint, entryType string, amount int, reportID string, key, description string, ruleID *uint) (*models.LedgerTransaction, error)
+	Redeem(userID uint, amount int, key, description string) (*models.LedgerTransaction, error)
+	Adjust(adminID, userID uint, amount int, key, reason string) (*models.LedgerTransaction, error)
+	Reverse(key string, adminID uint, reason string) (*models.LedgerTransaction, error)
+	ReverseByID(id uint, adminID uint, reason string) (*models.LedgerTransaction, error)
+	ReverseEvent(key string, adminID uint, reason string) ([]models.LedgerTransaction, error)
+	GetBalance(userID uint) (int, error)
+	GetHistory(userID uint, page int) ([]models.LedgerTransaction, error)
+	GetTransactions(page int) ([]models.LedgerTransaction, error)
+	GetTotalBalance() (int, error)
+}
+
+type ledgerService struct {
+	ledgerRepo  db.LedgerRepository
+	leaderboard LeaderboardService
+}
+
+// NewLedgerService builds the ledger service. Posted transactions are fed to
+// leaderboard, which may be nil.
+func NewLedgerService(ledgerRepo db.LedgerRepository, leaderboard LeaderboardService) LedgerService {
+	return &ledgerService{ledgerRepo: ledgerRepo, leaderboard: leaderboard}
+}
+
+// Credit gives the user points for an event, recording the reward rule that
+// paid them. Zero amounts post nothing and return nil.
+func (s *ledgerService) Credit(userID uint, entryType string, amount int, reportID string, key, description string, ruleID *uint) (*models.LedgerTransaction, error) {
+	if amount == 0 {
+		return nil, nil
+	}
+	if amount < 0 {
+		return nil, ErrInvalidPoints
+	}
+	txn := &models.LedgerTransaction{
+		IdempotencyKey: key,
+		Type:           entryType,
+		UserID:         userID,
+		Amount:         amount,
+		Description:    description,
+		RuleID:         ruleID,
+	}
+	if id, err := uuid.Parse(reportID); err == nil {
+		txn.IncidentReportID = &id
+	}
+	return s.post(txn)
+}
+
+// Redeem takes points from the user, failing if they do not have enough.
+func (s *ledgerService) Redeem(userID uint, amount int, key, description string) (*models.LedgerTransaction, error) {
+	if amount <= 0 {
+		return nil, ErrInvalidPoints
+	}
+	return s.post(&models.LedgerTransaction{
+		IdempotencyKey: key,
+		Type:           models.LedgerRedemption,
+		UserID:         userID,
+		Amount:         -amount,
+		Description:    description,
+	})
+}
+
+// Adjust corrects a user's balance by hand. The key lets a retried request
+// from the admin apply once; a fresh one is used when it is empty.
+func (s *ledgerService) Adjust(adminID, userID uint, amount int, key, reason string) (*models.LedgerTransaction, error) {
+	if amount == 0 {
+		return nil, ErrInvalidPoints
+	}
+	if key == "" {
+		key = uuid.New().String()
+	}
+	return s.post(&models.LedgerTransaction{
+		IdempotencyKey: "adjustment:" + key,
+		Type:           models.LedgerAdjustment,
+		UserID:         userID,
+		Amount:         amount,
+		Description:    reason,
+		CreatedBy:      adminID,
+	})
+}
+
+// Reverse undoes the transaction posted for key. It returns nil when nothing
+// was posted for the key, and the existing reversal when it was already
+// reversed.
+func (s *ledgerService) Reverse(key string, adminID uint, reason string) (*models.LedgerTransaction, error) {
+	original, err := s.ledgerRepo.GetTransactionByKey(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transaction: %v", err)
+	}
+	if original == nil {
+		return nil, nil
+	}
+	return s.reverse(original, adminID, reason)
+}
+
+func (s *ledgerService) ReverseByID(id uint, adminID uint, reason string) (*models.LedgerTransaction, error) {
+	original, err := s.ledgerRepo.GetTransactionByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transaction: %v", err)
+	}
+	if original == nil {
+		return nil, ErrTransactionNotFound
+	}
+	return s.reverse(original, adminID, reason)
+}
+
+// ReverseEvent undoes every transaction posted for an event, including those
+// keyed per reward rule under it. Transactions already reversed are skipped.
+func (s *ledgerService) ReverseEvent(key string, adminID uint, reason string) ([]models.LedgerTransaction, error) {
+	txns, err := s.ledgerRepo.GetEventTransactions(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch transactions: %v", err)
+	}
+	var reversals []models.LedgerTransaction
+	for i := range txns {
+		reversal, err := s.reverse(&txns[i], adminID, reason)
+		if err != nil {
+			return nil, err
+		}
+		reversals = append(reversals, *reversal)
+	}
+	return reversals, nil
+}
+
+// reverse posts the mirror image of the original's entries.
+func (s *ledgerService) reverse(original *models.LedgerTransaction, adminID uint, reason string) (*models.LedgerTransaction, error) {
+	if original.Type == models.LedgerReversal {
+		return nil, ErrAlreadyReversed
+	}
+	entries := make([]models.LedgerEntry, 0, len(original.Entries))
+	for _, entry := range original.Entries {
+		entries = append(entries, models.LedgerEntry{
+			Account: entry.Account,
+			UserID:  entry.UserID,
+			Amount:  -entry.Amount,
+		})
+	}
+	if reason == "" {
+		reason = "Reversal of " + original.Type
+	}
+	return s.post(&models.LedgerTransaction{
+		IdempotencyKey:   ReversalKey(original.IdempotencyKey),
+		Type:             models.LedgerReversal,
+		UserID:           original.UserID,
+		Amount:           -original.Amount,
+		IncidentReportID: original.IncidentReportID,
+		ReversesID:       &original.ID,
+		RuleID:           original.RuleID,
+		Description:      reason,
+		CreatedBy:        adminID,
+		Entries:          entries,
+	})
+}
+
+func (s *ledgerService) post(txn *models.LedgerTransaction) (*models.LedgerTransaction, error) {
+	posted, err := s.ledgerRepo.Post(txn)
+	if err != nil {
+		if errors.Is(err, db.ErrInsufficientPoints) {
+			return nil, ErrInsufficientPoints
+		}
+		return nil, fmt.Errorf("failed to post %s: %v", txn.Type, err)
+	}
+	// The ledger is the source of truth; boards that miss an update can be
+	// rebuilt from it
+	if posted && s.leaderboard != nil {
+		if err := s.leaderboard.Record(txn); err != nil {
+			log.Printf("Error recording transaction %d on leaderboards: %v", txn.ID, err)
+		}
+	}
+	return txn, nil
+}
+
+func (s *ledgerService) GetBalance(userID uint) (int, error) {
+	balance, err := s.ledgerRepo.GetBalance(userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch balance: %v", err)
+	}
+	return balance, nil
+}
+
+func (s *ledgerService) GetHistory(userID uint, page int) ([]models.LedgerTransaction, error) {
+	txns, err := s.ledgerRepo.GetUserTransactions(userID, page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch points history: %v", err)
+	}
+	return txns, nil
+}
+
+func (s *ledgerService) GetTransactions(page int) ([]models.LedgerTransaction, error) {
+	txns, err := s.ledgerRepo.GetTransactions(page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch ledger: %v", err)
+	}
+	return txns, nil
+}
+
+func (s *ledgerService) GetTotalBalance() (int, error) {
+	total, err := s.ledgerRepo.SumBalances()
+	if err != nil {
+		return 0, fmt.Errorf("error getting total rewards balance: %w", err)
+	}
+	return total, nil
+}
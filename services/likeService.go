@@ -1,48 +1,111 @@
 package services
 
 import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/google/uuid"
 	"github.com/techagentng/citizenx/config"
 	"github.com/techagentng/citizenx/db"
 	"github.com/techagentng/citizenx/models"
+	"gorm.io/gorm"
 )
 
+var ErrInvalidVote = errors.New("vote must be upvote, downvote or none")
+
 // LikeService interface
 type LikeService interface {
-	LikeReport(userID uint, reportID string) error
-	DownVoteReport(userID uint, reportID string) error
-	GetVoteCounts(reportID string) (int, int, error)
+	LikeReport(userID uint, reportID string) (*models.VoteCounts, error)
+	DownVoteReport(userID uint, reportID string) (*models.VoteCounts, error)
+	Vote(userID uint, reportID string, vote string) (*models.VoteCounts, error)
+	GetVoteCounts(reportID string, userID uint) (*models.VoteCounts, error)
 }
 
 // likeService struct
 type likeService struct {
-	Config   *config.Config
-	likeRepo db.LikeRepository
+	Config      *config.Config
+	likeRepo    db.LikeRepository
+	leaderboard LeaderboardService
+	badges      BadgeService
 }
 
-// NewLikeService creates a new instance of LikeService
-func NewLikeService(likeRepo db.LikeRepository, conf *config.Config) LikeService {
+// NewLikeService creates a new instance of LikeService. Votes are weighted by
+// the voter's reputation from leaderboard, or count 1 when it is nil.
+func NewLikeService(likeRepo db.LikeRepository, leaderboard LeaderboardService, badges BadgeService, conf *config.Config) LikeService {
 	return &likeService{
-		likeRepo: likeRepo,
-		Config:   conf,
+		likeRepo:    likeRepo,
+		leaderboard: leaderboard,
+		badges:      badges,
+		Config:      conf,
 	}
 }
 
-// LikeReport handles the logic for liking a report
-func (lk *likeService) LikeReport(userID uint, reportID string) error {
-	var like models.Like
-	like.UserID = userID
-	return lk.likeRepo.UpvoteReport(userID, reportID)
+// LikeReport upvotes the report, switching a downvote, or retracts the
+// user's upvote if they already upvoted.
+func (lk *likeService) LikeReport(userID uint, reportID string) (*models.VoteCounts, error) {
+	return lk.setVote(userID, reportID, models.VoteUp, true)
 }
 
-// DownVoteReport handles the logic for disliking a report
-func (lk *likeService) DownVoteReport(userID uint, reportID string) error {
-	return lk.likeRepo.DownVoteReport(userID, reportID)
+// DownVoteReport downvotes the report, switching an upvote, or retracts the
+// user's downvote if they already downvoted.
+func (lk *likeService) DownVoteReport(userID uint, reportID string) (*models.VoteCounts, error) {
+	return lk.setVote(userID, reportID, models.VoteDown, true)
+}
+
+// Vote sets the user's vote on the report to upvote, downvote or none.
+func (lk *likeService) Vote(userID uint, reportID string, vote string) (*models.VoteCounts, error) {
+	switch vote {
+	case models.VoteUp, models.VoteDown, models.VoteNone:
+	default:
+		return nil, ErrInvalidVote
+	}
+	return lk.setVote(userID, reportID, vote, false)
 }
 
-func (lk *likeService) GetVoteCounts(reportID string) (int, int, error) {
-	upvotes, downvotes, err := lk.likeRepo.GetUpvoteAndDownvoteCounts(reportID)
+func (lk *likeService) setVote(userID uint, reportID string, vote string, toggle bool) (*models.VoteCounts, error) {
+	if _, err := uuid.Parse(reportID); err != nil {
+		return nil, ErrReportNotFound
+	}
+	result, err := lk.likeRepo.SetVote(userID, reportID, vote, toggle, lk.voteWeight(userID))
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrReportNotFound
+	}
 	if err != nil {
-		return 0, 0, err
+		return nil, fmt.Errorf("failed to record vote: %v", err)
+	}
+	if result == models.VoteUp {
+		lk.badges.EvaluateReportOwnerAsync(reportID, BadgeEventVoteReceived)
+	}
+	return lk.GetVoteCounts(reportID, userID)
+}
+
+// voteWeight is the voter's reputation weight. A failed lookup counts the
+// vote at the neutral weight rather than losing it.
+func (lk *likeService) voteWeight(userID uint) float64 {
+	if lk.leaderboard == nil {
+		return 1
+	}
+	reputation, err := lk.leaderboard.GetReputation(userID)
+	if err != nil {
+		log.Printf("Failed to fetch reputation of user %d: %v", userID, err)
+		return 1
+	}
+	return reputation.Weight
+}
+
+// GetVoteCounts returns the report's raw and weighted vote counts and the
+// user's own vote.
+func (lk *likeService) GetVoteCounts(reportID string, userID uint) (*models.VoteCounts, error) {
+	if _, err := uuid.Parse(reportID); err != nil {
+		return nil, ErrReportNotFound
+	}
+	counts, err := lk.likeRepo.GetVoteCounts(reportID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch vote counts: %v", err)
+	}
+	if counts == nil {
+		return nil, ErrReportNotFound
 	}
-	return upvotes, downvotes, nil
+	return counts, nil
 }
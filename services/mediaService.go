@@ -10,61 +10,55 @@ import (
 	"io"
 	"io/ioutil"
 	"log"
+	"mime"
 	"mime/multipart"
 	"net/http"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
 	"strconv"
 	"strings"
-	"sync"
 	"time"
 
-	// "github.com/aws/aws-sdk-go-v2/aws"
-	// "github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/disintegration/imaging"
-	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/nfnt/resize"
 	"github.com/techagentng/citizenx/config"
-	fig "github.com/aws/aws-sdk-go-v2/config"
 	"github.com/techagentng/citizenx/db"
 	"github.com/techagentng/citizenx/models"
+	"github.com/techagentng/citizenx/storage"
 )
 
 type MediaService interface {
-	ProcessMedia(c *gin.Context, formMedia []*multipart.FileHeader, userID uint, reportID string) ([]string, []string, []string, []string, error)
-	SaveMedia(media models.Media, reportID string, userID uint, imageCount int, videoCount int, audioCount int, totalPoints int) error
-	ProcessSingleMedia(mediaFile *multipart.FileHeader, userID uint, reportID string) (string, string, string, error)
+	ProcessMediaFile(fileHeader *multipart.FileHeader, reportID string) (*ProcessResult, models.Media)
 	downloadVideo(feedURL string) (string, error)
 	generateThumbnailFromVideo(videoFilePath string) (string, error)
-	UploadThumbnailToStorage(thumbnailPath, bucketName, folderName string) (string, error)
+	UploadThumbnailToStorage(thumbnailPath, folderName string) (string, error)
 	GenerateThumbnail(feedURL string) (string, error)
 	captureThumbnail(videoFilePath string) (string, error)
-	ProcessVideoFile(mediaFile *multipart.FileHeader, userID uint, reportIDStr string) (string, string, string, error)
-	SaveToStorage(file multipart.File, fileName, bucketName, folderName string) error
+	SaveToStorage(file io.Reader, size int64, fileName, folderName string) (string, error)
 	GenerateVideoThumbnail(file multipart.File, outputPath string) error
 	// GenerateImageThumbnail(file multipart.File, thumbnailPath string) error
 	ProcessImageFile(mediaFile *multipart.FileHeader, userID uint, reportIDStr string) (string, string, string, error)
 	GenerateImageThumbnail(mediaFile *multipart.FileHeader, thumbnailPath string) error
-	UploadFileToS3(mediaFile *multipart.FileHeader, userID uint, fileType string) (string, error)
+	UploadFile(mediaFile *multipart.FileHeader, userID uint, fileType string) (string, error)
 }
 
 type mediaService struct {
 	Config             *config.Config
 	mediaRepo          db.MediaRepository
-	rewardRepo         db.RewardRepository
+	store              storage.BlobStore
+	transcriber        Transcriber
 	IncidentReportRepo db.IncidentReportRepository
 }
 
-func NewMediaService(mediaRepo db.MediaRepository, rewardRepo db.RewardRepository, reportRepo db.IncidentReportRepository, conf *config.Config) MediaService {
+func NewMediaService(mediaRepo db.MediaRepository, reportRepo db.IncidentReportRepository, store storage.BlobStore, transcriber Transcriber, conf *config.Config) MediaService {
 	return &mediaService{
 		Config:             conf,
 		mediaRepo:          mediaRepo,
-		rewardRepo:         rewardRepo,
+		store:              store,
+		transcriber:        transcriber,
 		IncidentReportRepo: reportRepo,
 	}
 }
@@ -108,118 +102,127 @@ type ProcessResult struct {
 	Error        error
 }
 
-// Change the parameter type to []*multipart.FileHeader to handle multiple files
-func (m *mediaService) ProcessMedia(c *gin.Context, formMedia []*multipart.FileHeader, userID uint, reportID string) ([]string, []string, []string, []string, error) {
-	var (
-		feedURLs, thumbnailURLs, fullsizeURLs, fileTypes []string
-		mu                                               sync.Mutex
-		wg                                               sync.WaitGroup
-		bucketName                                       = os.Getenv("AWS_BUCKET")
-		results                                          = make(chan *ProcessResult, len(formMedia)) // len is valid now as formMedia is a slice
-	)
-
-	// Launch a goroutine for each file to process it concurrently
-	for _, fileHeader := range formMedia {
-		wg.Add(1)
-		go func(fileHeader *multipart.FileHeader) {
-			defer wg.Done()
-
-			// Open the file
-			file, err := fileHeader.Open()
-			if err != nil {
-				results <- &ProcessResult{Error: fmt.Errorf("failed to open file: %v", err)}
-				return
-			}
-			defer file.Close()
+// ProcessMediaFile runs one uploaded file through the processing pipeline and
+// uploads it. The returned media record is filled in but not saved, so callers
+// decide whether to create a new row or update a queued one.
+func (m *mediaService) ProcessMediaFile(fileHeader *multipart.FileHeader, reportID string) (*ProcessResult, models.Media) {
+	var media models.Media
 
-			// Read the file content
-			fileBytes, err := ioutil.ReadAll(file)
-			if err != nil {
-				results <- &ProcessResult{Error: fmt.Errorf("failed to read file: %v", err)}
-				return
-			}
+	// Open the file
+	file, err := fileHeader.Open()
+	if err != nil {
+		return &ProcessResult{Error: fmt.Errorf("failed to open file: %v", err)}, media
+	}
+	defer file.Close()
 
-			// Reset file pointer to the beginning after reading it
-			_, err = file.Seek(0, io.SeekStart)
-			if err != nil {
-				results <- &ProcessResult{Error: fmt.Errorf("failed to seek file: %v", err)}
-				return
-			}
+	// Read the file content
+	fileBytes, err := ioutil.ReadAll(file)
+	if err != nil {
+		return &ProcessResult{Error: fmt.Errorf("failed to read file: %v", err)}, media
+	}
 
-			fileType := getFileType(fileBytes)
-			var feedURL, thumbnailURL, fullsizeURL string
+	fileType := getFileType(fileBytes)
+	var feedURL, thumbnailURL, fullsizeURL string
+	media = models.Media{FileType: fileType, FileSize: int64(len(fileBytes))}
 
-			// Define the folder name based on the file type
-			folderName := ""
-			switch fileType {
-			case "image":
-				folderName = "images"
-				feedURL, thumbnailURL, fullsizeURL, err = processAndStoreImage(fileBytes)
-				if err != nil {
-					results <- &ProcessResult{Error: fmt.Errorf("failed to process and store image: %v", err)}
-					return
-				}
-			case "video":
-				folderName = "videos"
-				feedURL, thumbnailURL, fullsizeURL, err = processAndStoreVideo(fileBytes)
-				if err != nil {
-					results <- &ProcessResult{Error: fmt.Errorf("failed to process and store video: %v", err)}
-					return
-				}
-			case "audio":
-				folderName = "audio"
-				feedURL, thumbnailURL, err = processAndStoreAudio(fileBytes)
-				if err != nil {
-					results <- &ProcessResult{Error: fmt.Errorf("failed to process and store audio: %v", err)}
-					return
-				}
-			default:
-				results <- &ProcessResult{Error: fmt.Errorf("unsupported file type: %s", fileType)}
-				return
-			}
+	// Every rendition is written to storage by the processAndStore helpers
+	switch fileType {
+	case "image":
+		// Read EXIF before it is stripped; only the stripped bytes are stored
+		fileBytes, media, err = m.inspectImage(fileBytes, reportID)
+		if err != nil {
+			return &ProcessResult{Error: err}, media
+		}
+		feedURL, thumbnailURL, fullsizeURL, err = m.processAndStoreImage(fileBytes)
+		if err != nil {
+			return &ProcessResult{Error: fmt.Errorf("failed to process and store image: %v", err)}, media
+		}
+	case "video":
+		video, err := m.processAndStoreVideo(fileBytes)
+		if err != nil {
+			return &ProcessResult{Error: fmt.Errorf("failed to process and store video: %v", err)}, media
+		}
+		feedURL, thumbnailURL = video.PlaylistURL, video.PosterURL
+		media.Width = video.Probe.Width
+		media.Height = video.Probe.Height
+		media.DurationSeconds = video.Probe.transcodedSeconds()
+		media.VideoCodec = video.Probe.VideoCodec
+		media.AudioCodec = video.Probe.AudioCodec
+		media.Renditions = strings.Join(video.Renditions, ",")
+	case "audio":
+		feedURL, err = m.processAndStoreAudio(fileBytes)
+		if err != nil {
+			return &ProcessResult{Error: fmt.Errorf("failed to process and store audio: %v", err)}, media
+		}
+		m.transcribeAudio(fileBytes, &media)
+	default:
+		return &ProcessResult{Error: fmt.Errorf("unsupported file type: %s", fileType)}, media
+	}
 
-			// Upload the processed media to S3
-			feedURL, err = m.mediaRepo.UploadMediaToS3(file, fileHeader, bucketName, folderName)
-			if err != nil {
-				results <- &ProcessResult{Error: fmt.Errorf("failed to upload media to S3: %v", err)}
-				return
-			}
+	media.Filename = fileHeader.Filename
+	media.FeedURL = feedURL
+	media.ThumbnailURL = thumbnailURL
+	media.FullSizeURL = fullsizeURL
 
-			// Return the results through the channel
-			results <- &ProcessResult{
-				FeedURL:      feedURL,
-				ThumbnailURL: thumbnailURL,
-				FullSizeURL:  fullsizeURL,
-				FileType:     fileType,
-				Error:        nil,
-			}
-		}(fileHeader)
-	}
+	return &ProcessResult{
+		FeedURL:      feedURL,
+		ThumbnailURL: thumbnailURL,
+		FullSizeURL:  fullsizeURL,
+		FileType:     fileType,
+	}, media
+}
 
-	// Close the results channel when all goroutines are done
-	go func() {
-		wg.Wait()
-		close(results)
-	}()
+// inspectImage reads the EXIF position and capture time of an uploaded photo,
+// scores them against the report's lat/lng and time, and returns the image with
+// all metadata stripped. The returned media record carries the score and
+// dimensions but no URLs yet.
+func (m *mediaService) inspectImage(fileBytes []byte, reportID string) ([]byte, models.Media, error) {
+	media := models.Media{FileType: "image"}
+	if parsed, err := uuid.Parse(reportID); err == nil {
+		media.IncidentReportID = parsed
+	}
 
-	// Collect results from the channel
-	for result := range results {
-		if result.Error != nil {
-			return nil, nil, nil, nil, fmt.Errorf("error processing media: %v", result.Error)
-		}
-		mu.Lock()
-		feedURLs = append(feedURLs, result.FeedURL)
-		if result.ThumbnailURL != "" {
-			thumbnailURLs = append(thumbnailURLs, result.ThumbnailURL)
-		}
-		if result.FullSizeURL != "" {
-			fullsizeURLs = append(fullsizeURLs, result.FullSizeURL)
+	exif, err := ExtractExif(fileBytes)
+	if err != nil && !errors.Is(err, errNoExif) {
+		log.Printf("Ignoring unreadable EXIF data: %v", err)
+	}
+	if exif != nil {
+		media.CapturedAt = exif.TakenAt
+		report, err := m.IncidentReportRepo.GetIncidentReportByID(reportID)
+		if err != nil {
+			log.Printf("Unable to load report %s for location check: %v", reportID, err)
+		} else if score, distance, ok := LocationConsistency(exif, report.Latitude, report.Longitude, report.TimeofIncidence); ok {
+			media.LocationConsistencyScore = &score
+			media.LocationDistanceMeters = &distance
 		}
-		fileTypes = append(fileTypes, result.FileType)
-		mu.Unlock()
 	}
 
-	return feedURLs, thumbnailURLs, fullsizeURLs, fileTypes, nil
+	stripped, err := StripImageMetadata(fileBytes)
+	if err != nil {
+		return nil, media, fmt.Errorf("failed to strip image metadata: %v", err)
+	}
+	media.FileSize = int64(len(stripped))
+	if width, height, err := getImageDimensions(stripped); err == nil {
+		media.Width = width
+		media.Height = height
+	}
+
+	// Flag photos reused across reports so they do not earn points again
+	if hash, err := DifferenceHashBytes(stripped); err != nil {
+		log.Printf("Unable to hash image: %v", err)
+	} else {
+		signed := int64(hash)
+		media.PerceptualHash = &signed
+		original, err := m.mediaRepo.FindNearDuplicateMedia(signed, DuplicateHashDistance)
+		if err != nil {
+			log.Printf("Unable to look up duplicate images: %v", err)
+		} else if original != nil {
+			media.IsDuplicate = true
+			media.DuplicateOfID = original.ID
+			media.Points = 0
+		}
+	}
+	return stripped, media, nil
 }
 
 func getFileType(fileBytes []byte) string {
@@ -294,13 +297,17 @@ func (m *mediaService) processFile(fileCh <-chan *multipart.FileHeader, resultCh
 
 			var result ImageResult
 			if strings.HasPrefix(ext, ".jpg") || strings.HasPrefix(ext, ".jpeg") || strings.HasPrefix(ext, ".png") {
-				feedURL, thumbnailURL, fullsizeURL, err := processAndStoreImage(fileBytes)
+				feedURL, thumbnailURL, fullsizeURL, err := m.processAndStoreImage(fileBytes)
 				result = ImageResult{feedURL, thumbnailURL, fullsizeURL, err}
 			} else if strings.HasPrefix(ext, ".mp4") {
-				videoURL, thumbnailURL, _, err := processAndStoreVideo(fileBytes)
-				result = ImageResult{videoURL, thumbnailURL, "", err}
+				video, err := m.processAndStoreVideo(fileBytes)
+				if err != nil {
+					result = ImageResult{"", "", "", err}
+				} else {
+					result = ImageResult{video.PlaylistURL, video.PosterURL, "", nil}
+				}
 			} else if strings.HasPrefix(ext, ".mp3") || strings.HasPrefix(ext, ".wav") || strings.HasPrefix(ext, ".ogg") {
-				audioURL, _, err := processAndStoreAudio(fileBytes)
+				audioURL, err := m.processAndStoreAudio(fileBytes)
 				result = ImageResult{audioURL, "", "", err}
 			}
 
@@ -310,68 +317,36 @@ func (m *mediaService) processFile(fileCh <-chan *multipart.FileHeader, resultCh
 }
 
 // Image processing
-func processAndStoreImage(fileBytes []byte) (string, string, string, error) {
+func (m *mediaService) processAndStoreImage(fileBytes []byte) (string, string, string, error) {
 	img, _, err := image.Decode(bytes.NewReader(fileBytes))
 	if err != nil {
 		return "", "", "", fmt.Errorf("failed to decode image: %v", err)
 	}
 
-	feedImg := imaging.Fill(img, 1080, 1080, imaging.Center, imaging.Lanczos)
-	thumbnailImg := imaging.Resize(img, 161, 161, imaging.Lanczos)
-	fullSizeImg := img
-
-	feedFilename := generateUniqueFilename(".jpg")
-	thumbnailFilename := generateUniqueFilename(".jpg")
-	fullSizeFilename := generateUniqueFilename(".jpg")
-
-	feedDestPath := filepath.Join("media", "feed", feedFilename)
-	thumbnailDestPath := filepath.Join("media", "thumbnail", thumbnailFilename)
-	fullSizeDestPath := filepath.Join("media", "fullsize", fullSizeFilename)
-
-	log.Printf("Feed directory path: %s", filepath.Dir(feedDestPath))
-	log.Printf("Thumbnail directory path: %s", filepath.Dir(thumbnailDestPath))
-	log.Printf("Fullsize directory path: %s", filepath.Dir(fullSizeDestPath))
-
-	if err := os.MkdirAll(filepath.Dir(feedDestPath), 0755); err != nil {
-		return "", "", "", fmt.Errorf("error creating feed folder: %v", err)
-	}
-	if err := os.MkdirAll(filepath.Dir(thumbnailDestPath), 0755); err != nil {
-		return "", "", "", fmt.Errorf("error creating thumbnail folder: %v", err)
-	}
-	if err := os.MkdirAll(filepath.Dir(fullSizeDestPath), 0755); err != nil {
-		return "", "", "", fmt.Errorf("error creating fullsize folder: %v", err)
-	}
-
-	feedFile, err := os.Create(feedDestPath)
-	if err != nil {
-		return "", "", "", fmt.Errorf("failed to create feed file: %v", err)
-	}
-	defer feedFile.Close()
-
-	thumbnailFile, err := os.Create(thumbnailDestPath)
-	if err != nil {
-		return "", "", "", fmt.Errorf("failed to create thumbnail file: %v", err)
-	}
-	defer thumbnailFile.Close()
-
-	fullSizeFile, err := os.Create(fullSizeDestPath)
-	if err != nil {
-		return "", "", "", fmt.Errorf("failed to create fullsize file: %v", err)
+	renditions := []struct {
+		name string
+		img  image.Image
+	}{
+		{"feed", imaging.Fill(img, 1080, 1080, imaging.Center, imaging.Lanczos)},
+		{"thumbnail", imaging.Resize(img, 161, 161, imaging.Lanczos)},
+		{"fullsize", img},
 	}
-	defer fullSizeFile.Close()
 
-	if err := jpeg.Encode(feedFile, feedImg, nil); err != nil {
-		return "", "", "", fmt.Errorf("failed to encode feed image: %v", err)
-	}
-	if err := jpeg.Encode(thumbnailFile, thumbnailImg, nil); err != nil {
-		return "", "", "", fmt.Errorf("failed to encode thumbnail image: %v", err)
-	}
-	if err := jpeg.Encode(fullSizeFile, fullSizeImg, nil); err != nil {
-		return "", "", "", fmt.Errorf("failed to encode fullsize image: %v", err)
+	// Re-encoding also guarantees no metadata survives in the stored renditions
+	urls := make([]string, len(renditions))
+	for i, rendition := range renditions {
+		var buf bytes.Buffer
+		if err := jpeg.Encode(&buf, rendition.img, nil); err != nil {
+			return "", "", "", fmt.Errorf("failed to encode %s image: %v", rendition.name, err)
+		}
+		urls[i], err = m.SaveToStorage(&buf, int64(buf.Len()), generateUniqueFilename(".jpg"), path.Join("media", rendition.name))
+		if err != nil {
+			return "", "", "", fmt.Errorf("failed to store %s image: %v", rendition.name, err)
+		}
 	}
 
-	log.Printf("Successfully stored images: feed=%s, thumbnail=%s, fullsize=%s", feedDestPath, thumbnailDestPath, fullSizeDestPath)
-	return feedDestPath, thumbnailDestPath, fullSizeDestPath, nil
+	log.Printf("Successfully stored images: feed=%s, thumbnail=%s, fullsize=%s", urls[0], urls[1], urls[2])
+	return urls[0], urls[1], urls[2], nil
 }
 
 func isValidFileType(fileType string) bool {
@@ -457,224 +432,27 @@ func getImageDimensions(file []byte) (int, int, error) {
 	return img.Width, img.Height, nil
 }
 
-func (m *mediaService) SaveMedia(media models.Media, reportID string, userID uint, imageCount int, videoCount int, audioCount int, totalPoints int) error {
-	// Generate a new UUID for the media ID
-	ID := uuid.New()
-	media.ID = ID.String()
-	media.UserID = userID
-
-	// Multiply totalPoints by 10
-	rewardPoints := totalPoints * 10
-
-	// Set the points on the media (remove the redundant assignment)
-	media.Points = rewardPoints
-
-	// Save the media to the database
-	err := m.mediaRepo.SaveMedia(media, reportID, userID)
-	if err != nil {
-		return err
-	}
-
-	// Create and save the media count for the report
-	var mcount models.MediaCount
-	mcount.Images = imageCount
-	mcount.Videos = videoCount
-	mcount.Audios = audioCount
-	mcount.IncidentReportID = reportID
-	mcount.UserID = userID
-
-	// Get the user's reward record
-	reward, err := m.rewardRepo.GetRewardByUserID(userID)
-	if err != nil {
-		return err
-	}
-
-	// If no reward record exists, create a new one
-	if reward == nil {
-		reward = &models.Reward{
-			UserID:  userID,
-			Balance: 0,
-			Point:   0,
-		}
-	}
-
-	// Update the reward balance and points
-	reward.Balance += rewardPoints
-	reward.Point += rewardPoints
-
-	// Save the updated reward record
-	err = m.rewardRepo.SaveReward(reward)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
-func processAndStoreVideo(fileBytes []byte) (string, string, string, error) {
-	videoFilename := generateUniqueFilename(".mp4")
-	thumbnailFilename := generateUniqueFilename(".jpg")
-
-	videoDestPath := filepath.Join("media", "video", videoFilename)
-	thumbnailDestPath := filepath.Join("media", "thumbnail", thumbnailFilename)
-
-	log.Printf("Creating directories for video and thumbnail if they don't exist")
-	if err := os.MkdirAll(filepath.Dir(videoDestPath), 0755); err != nil {
-		return "", "", "", fmt.Errorf("error creating video folder: %v", err)
-	}
-	if err := os.MkdirAll(filepath.Dir(thumbnailDestPath), 0755); err != nil {
-		return "", "", "", fmt.Errorf("error creating thumbnail folder: %v", err)
-	}
-
-	log.Printf("Creating temporary file for the uploaded video")
-	tempFile, err := os.CreateTemp("", "uploaded_video_*.mp4")
+// storeLocalFile uploads a file produced on disk (e.g. by ffmpeg) to storage.
+func (m *mediaService) storeLocalFile(localPath, fileName, folderName string) (string, error) {
+	file, err := os.Open(localPath)
 	if err != nil {
-		return "", "", "", fmt.Errorf("failed to create temporary file: %v", err)
-	}
-	defer os.Remove(tempFile.Name())
-	log.Printf("Temporary file created: %s", tempFile.Name())
-
-	log.Printf("Writing video bytes to the temporary file")
-	if _, err := tempFile.Write(fileBytes); err != nil {
-		return "", "", "", fmt.Errorf("failed to write temporary video file: %v", err)
-	}
-	tempFile.Close()
-
-	log.Printf("Executing ffmpeg command to process the video")
-	ffmpegCmd := exec.Command("ffmpeg", "-i", tempFile.Name(), "-vf", "scale=1080:-2", "-t", "60", "-c:a", "copy", "-preset", "fast", "-crf", "23", videoDestPath)
-	var stderr bytes.Buffer
-	ffmpegCmd.Stderr = &stderr
-	if err := ffmpegCmd.Run(); err != nil {
-		return "", "", "", fmt.Errorf("ffmpeg error: %v, details: %s", err, stderr.String())
-	}
-	log.Printf("Video processed and saved to: %s", videoDestPath)
-
-	log.Printf("Generating thumbnail for the video")
-	ffmpegCmd = exec.Command("ffmpeg", "-i", tempFile.Name(), "-vf", "thumbnail", "-frames:v", "1", thumbnailDestPath)
-	stderr.Reset()
-	ffmpegCmd.Stderr = &stderr
-	if err := ffmpegCmd.Run(); err != nil {
-		return "", "", "", fmt.Errorf("ffmpeg thumbnail error: %v, details: %s", err, stderr.String())
-	}
-	log.Printf("Thumbnail generated and saved to: %s", thumbnailDestPath)
-
-	return videoDestPath, thumbnailDestPath, "video", nil
-}
-
-func processAndStoreAudio(fileBytes []byte) (string, string, error) {
-	audioFilename := generateUniqueFilename(".mp3")
-
-	audioDestPath := filepath.Join("media", "audio", audioFilename)
-
-	if err := os.MkdirAll(filepath.Dir(audioDestPath), 0755); err != nil {
-		return "", "", fmt.Errorf("error creating audio folder: %v", err)
-	}
-
-	audioFile, err := os.Create(audioDestPath)
-	if err != nil {
-		return "", "", fmt.Errorf("failed to create audio file: %v", err)
-	}
-	defer audioFile.Close()
-
-	if _, err := audioFile.Write(fileBytes); err != nil {
-		return "", "", fmt.Errorf("failed to write audio file: %v", err)
-	}
-
-	return audioDestPath, "audio", nil
-}
-
-// ProcessSingleMedia processes a single media file and returns URLs for different formats.
-func (m *mediaService) ProcessSingleMedia(mediaFile *multipart.FileHeader, userID uint, reportID string) (string, string, string, error) {
-	// Open the media file
-	file, err := mediaFile.Open()
-	if err != nil {
-		return "", "", "", fmt.Errorf("unable to open media file: %w", err)
+		return "", fmt.Errorf("failed to open %s: %v", localPath, err)
 	}
 	defer file.Close()
 
-	// Define buffer size for content type detection
-	const bufferSize = 512
-	buffer := make([]byte, bufferSize)
-
-	// Read the first 512 bytes of the file for MIME type detection
-	_, err = file.Read(buffer)
+	info, err := file.Stat()
 	if err != nil {
-		return "", "", "", fmt.Errorf("failed to read file for content type detection: %w", err)
+		return "", fmt.Errorf("failed to stat %s: %v", localPath, err)
 	}
+	return m.SaveToStorage(file, info.Size(), fileName, folderName)
+}
 
-	// Detect content type
-	fileType := http.DetectContentType(buffer)
-	fileSize := mediaFile.Size
-
-	// Reset file position to the beginning after reading
-	_, err = file.Seek(0, io.SeekStart)
+func (m *mediaService) processAndStoreAudio(fileBytes []byte) (string, error) {
+	audioURL, err := m.SaveToStorage(bytes.NewReader(fileBytes), int64(len(fileBytes)), generateUniqueFilename(".mp3"), "media/audio")
 	if err != nil {
-		return "", "", "", fmt.Errorf("failed to reset file read position: %w", err)
-	}
-
-	var feedURL, thumbnailURL, fullsizeURL string
-
-	// Image processing
-	if strings.HasPrefix(fileType, "image") {
-		if fileSize > 10*1024*1024 { // Example limit of 10MB for images
-			return "", "", "", fmt.Errorf("image file size exceeds limit")
-		}
-		// Retrieve the bucket name from environment variables
-		bucketName := os.Getenv("AWS_BUCKET")
-		if bucketName == "" {
-			return "", "", "", fmt.Errorf("AWS_BUCKET environment variable is not set")
-		}
-
-		folderName := "media"
-
-		feedURL, err = m.mediaRepo.UploadMediaToS3(file, mediaFile, bucketName, folderName)
-		if err != nil {
-			return "", "", "", fmt.Errorf("failed to upload feed image: %w", err)
-		}
-
-		// Generate thumbnail for the image
-		thumbnailURL, err = m.GenerateThumbnail(feedURL)
-		if err != nil {
-			return "", "", "", fmt.Errorf("failed to create thumbnail: %w", err)
-		}
-
-		fullsizeURL = feedURL // Assuming full-size is the original uploaded image URL
-
-		// Video processing
-	} else if strings.HasPrefix(fileType, "video") {
-		if fileSize > 80*1024*1024 { // Example limit of 80MB for videos
-			return "", "", "", fmt.Errorf("video file size exceeds limit")
-		}
-
-		// Retrieve the bucket name from environment variables
-		bucketName := os.Getenv("AWS_BUCKET")
-		if bucketName == "" {
-			return "", "", "", fmt.Errorf("AWS_BUCKET environment variable is not set")
-		}
-
-		// Define folder name for video upload (e.g., "videos")
-		folderName := "videos"
-
-		// Upload video to S3 and get feed URL
-		feedURL, err := m.mediaRepo.UploadMediaToS3(file, mediaFile, bucketName, folderName)
-		if err != nil {
-			return "", "", "", fmt.Errorf("failed to upload feed video: %w", err)
-		}
-
-		// Extract video thumbnail
-		thumbnailURL, err = m.ExtractVideoThumbnail(feedURL)
-		if err != nil {
-			return "", "", "", fmt.Errorf("failed to extract video thumbnail: %w", err)
-		}
-
-		fullsizeURL = feedURL // Use feed URL as full-size video URL
-
-	} else {
-		return "", "", "", fmt.Errorf("unsupported file type: %s", fileType)
+		return "", fmt.Errorf("failed to store audio file: %v", err)
 	}
-
-	// Return the generated URLs
-	return feedURL, thumbnailURL, fullsizeURL, nil
+	return audioURL, nil
 }
 
 func (m *mediaService) downloadVideo(feedURL string) (string, error) {
@@ -713,7 +491,7 @@ func (m *mediaService) generateThumbnailFromVideo(videoFilePath string) (string,
 	return thumbnailPath, nil
 }
 
-func (m *mediaService) UploadThumbnailToStorage(thumbnailPath, bucketName, folderName string) (string, error) {
+func (m *mediaService) UploadThumbnailToStorage(thumbnailPath, folderName string) (string, error) {
 	// Open the thumbnail file
 	file, err := os.Open(thumbnailPath)
 	if err != nil {
@@ -721,22 +499,15 @@ func (m *mediaService) UploadThumbnailToStorage(thumbnailPath, bucketName, folde
 	}
 	defer file.Close()
 
-	// Retrieve file info for creating FileHeader
+	// Retrieve file info for the size and name
 	fileInfo, err := file.Stat()
 	if err != nil {
 		return "", fmt.Errorf("unable to get file info: %v", err)
 	}
 
-	// Create a FileHeader using the file's information
-	fileHeader := &multipart.FileHeader{
-		Filename: fileInfo.Name(),
-		Size:     fileInfo.Size(),
-	}
-
-	// Use UploadMediaToS3 to upload the thumbnail
-	thumbnailURL, err := m.mediaRepo.UploadMediaToS3(file, fileHeader, bucketName, folderName)
+	thumbnailURL, err := m.SaveToStorage(file, fileInfo.Size(), fileInfo.Name(), folderName)
 	if err != nil {
-		return "", fmt.Errorf("failed to upload thumbnail to S3: %v", err)
+		return "", fmt.Errorf("failed to upload thumbnail: %v", err)
 	}
 
 	return thumbnailURL, nil
@@ -828,71 +599,18 @@ func (m *mediaService) captureThumbnail(videoFilePath string) (string, error) {
 	return thumbnailPath, nil
 }
 
-// ProcessVideoFile processes a video file, generates a feed URL, a thumbnail, and a full-size URL.
-func (m *mediaService) ProcessVideoFile(mediaFile *multipart.FileHeader, userID uint, reportIDStr string) (string, string, string, error) {
-    // Open the video file
-    file, err := mediaFile.Open()
-    if err != nil {
-        log.Printf("Error opening video file: %v", err)
-        return "", "", "", fmt.Errorf("error opening video file: %v", err)
-    }
-    defer file.Close()
-
-    // Sanitize and define file paths
-    sanitizedFilename := strings.ReplaceAll(mediaFile.Filename, " ", "_")
-    videoFileName := fmt.Sprintf("%d_%s", userID, sanitizedFilename)
-    thumbnailFileName := fmt.Sprintf("%d_%s_thumbnail.jpg", userID, reportIDStr)
-
-    bucketName := os.Getenv("AWS_BUCKET")
-    folderName := "media" // Folder name for S3
-
-    // URLs for S3 storage
-    feedURL := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s/%s", bucketName, os.Getenv("AWS_REGION"), folderName, videoFileName)
-    fullSizeURL := feedURL
-    thumbnailURL := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s/%s", bucketName, os.Getenv("AWS_REGION"), folderName, thumbnailFileName)
 
-    // Step 1: Save video to S3
-    if err := m.SaveToStorage(file, videoFileName, bucketName, folderName); err != nil {
-        log.Printf("Error saving video to S3: %v", err)
-        return "", "", "", fmt.Errorf("error saving video to S3: %v", err)
-    }
-
-    // // Step 2: Generate a thumbnail using FFmpeg
-    // thumbnailPath := fmt.Sprintf("/tmp/%s", thumbnailFileName) // Temporary path
-    // if err := m.GenerateVideoThumbnail(file, thumbnailPath); err != nil {
-    //     log.Printf("Error generating video thumbnail: %v", err)
-    //     return "", "", "", fmt.Errorf("error generating video thumbnail: %v", err)
-    // }
-
-    // Step 3: Upload the thumbnail to S3
-    // if err := m.UploadThumbnailToStorage(thumbnailPath, bucketName, folderName); err != nil {
-    //     log.Printf("Error uploading video thumbnail to S3: %v", err)
-    //     return "", "", "", fmt.Errorf("error uploading video thumbnail to S3: %v", err)
-    // }
-
-    // Step 4: Clean up temporary files
-    // if err := os.Remove(thumbnailPath); err != nil {
-    //     log.Printf("Error cleaning up thumbnail file: %v", err)
-    // }
-
-    log.Printf("Successfully processed video file: %s", mediaFile.Filename)
-
-    return feedURL, thumbnailURL, fullSizeURL, nil
-}
-
-
-func (m *mediaService) SaveToStorage(file multipart.File, fileName, bucketName, folderName string) error {
-	// Upload file to S3 using the repository method
-	fileURL, err := m.mediaRepo.UploadMediaToS3(file, &multipart.FileHeader{Filename: fileName}, bucketName, folderName)
+// SaveToStorage writes the file to folderName/fileName in the configured store
+// and returns its URL. Report media is private; readers get signed URLs.
+func (m *mediaService) SaveToStorage(file io.Reader, size int64, fileName, folderName string) (string, error) {
+	key := storage.Key(folderName, strings.ReplaceAll(fileName, " ", "_"))
+	fileURL, err := m.store.Put(context.TODO(), key, file, size, storage.PutOptions{ContentType: mime.TypeByExtension(filepath.Ext(fileName))})
 	if err != nil {
-		return fmt.Errorf("error uploading file to S3: %v", err)
+		return "", fmt.Errorf("error uploading file: %v", err)
 	}
 
-	// Log the file URL
-	log.Printf("File successfully uploaded to S3: %s", fileURL)
-
-	// You can return the file URL or save it to the database if needed
-	return nil
+	log.Printf("File successfully uploaded: %s", fileURL)
+	return fileURL, nil
 }
 
 func (m *mediaService) GenerateVideoThumbnail(file multipart.File, outputPath string) error {
@@ -907,36 +625,7 @@ func (m *mediaService) GenerateVideoThumbnail(file multipart.File, outputPath st
 }
 
 func (m *mediaService) uploadThumbnailToStorage(thumbnailFilePath string) (string, error) {
-	// Open the thumbnail file
-	file, err := os.Open(thumbnailFilePath)
-	if err != nil {
-		return "", fmt.Errorf("unable to open thumbnail file: %v", err)
-	}
-	defer file.Close()
-
-	// Retrieve file info for creating FileHeader
-	fileInfo, err := file.Stat()
-	if err != nil {
-		return "", fmt.Errorf("unable to get file info: %v", err)
-	}
-
-	// Create a FileHeader using the file's information
-	fileHeader := &multipart.FileHeader{
-		Filename: fileInfo.Name(),
-		Size:     fileInfo.Size(),
-	}
-
-	// Get bucket and folder names from environment variables
-	bucketName := os.Getenv("BUCKET_NAME")
-	folderName := os.Getenv("FOLDER_NAME")
-
-	// Use UploadMediaToS3 to upload the thumbnail
-	thumbnailURL, err := m.mediaRepo.UploadMediaToS3(file, fileHeader, bucketName, folderName)
-	if err != nil {
-		return "", fmt.Errorf("failed to upload thumbnail to S3: %v", err)
-	}
-
-	return thumbnailURL, nil
+	return m.UploadThumbnailToStorage(thumbnailFilePath, "media/thumbnail")
 }
 
 func (s *mediaService) ProcessImageFile(mediaFile *multipart.FileHeader, userID uint, reportIDStr string) (string, string, string, error) {
@@ -948,6 +637,18 @@ func (s *mediaService) ProcessImageFile(mediaFile *multipart.FileHeader, userID
     }
     defer file.Close()
 
+    fileBytes, err := io.ReadAll(file)
+    if err != nil {
+        return "", "", "", fmt.Errorf("error reading image file: %v", err)
+    }
+
+    // Score the EXIF location against the report, then drop all metadata
+    strippedBytes, media, err := s.inspectImage(fileBytes, reportIDStr)
+    if err != nil {
+        log.Printf("Error inspecting image: %v", err)
+        return "", "", "", err
+    }
+
     // Generate a unique identifier (using UUID and timestamp)
     uniqueID := fmt.Sprintf("%s_%d", uuid.New().String(), time.Now().UnixNano())
 
@@ -955,32 +656,18 @@ func (s *mediaService) ProcessImageFile(mediaFile *multipart.FileHeader, userID
     sanitizedFilename := strings.ReplaceAll(mediaFile.Filename, " ", "_")
     imageFileName := fmt.Sprintf("%d_%s_%s", userID, uniqueID, sanitizedFilename)
 
-    feedURL := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s/%s",
-        os.Getenv("AWS_BUCKET"),
-        os.Getenv("AWS_REGION"),
-        "media2", // Folder name in S3
-        imageFileName,
-    )
-    fullSizeURL := feedURL // Full-size image URL would be the same for now
-
     // Generate a unique filename for the thumbnail
     thumbnailFileName := fmt.Sprintf("%d_%s_thumbnail.jpg", userID, uniqueID)
-    thumbnailURL := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s/%s",
-        os.Getenv("AWS_BUCKET"),
-        os.Getenv("AWS_REGION"),
-        "media2", // Folder name in S3
-        thumbnailFileName,
-    )
-
-    // Define S3 bucket and folder name
-    bucketName := os.Getenv("AWS_BUCKET") // Use the AWS_BUCKET environment variable
-    folderName := "media2"               // Folder name where the file will be stored in S3
-
-    // Step 1: Save the original image file to S3 storage
-    if err := s.SaveToStorage(file, imageFileName, bucketName, folderName); err != nil {
+
+    folderName := "media2" // Folder name where the file will be stored
+
+    // Step 1: Save the stripped image file to storage
+    feedURL, err := s.SaveToStorage(bytes.NewReader(strippedBytes), int64(len(strippedBytes)), imageFileName, folderName)
+    if err != nil {
         log.Printf("Error saving image to storage: %v", err)
         return "", "", "", fmt.Errorf("error saving image to storage: %v", err)
     }
+    fullSizeURL := feedURL // Full-size image URL would be the same for now
 
     // Step 2: Generate a thumbnail for the image
     // Ensure the directory for the thumbnail exists
@@ -996,8 +683,8 @@ func (s *mediaService) ProcessImageFile(mediaFile *multipart.FileHeader, userID
         return "", "", "", fmt.Errorf("error generating image thumbnail: %v", err)
     }
 
-    // Step 3: Upload the thumbnail to S3 storage
-    thumbnailURL, err = s.UploadThumbnailToStorage(thumbnailPath, bucketName, folderName)
+    // Step 3: Upload the thumbnail to storage
+    thumbnailURL, err := s.UploadThumbnailToStorage(thumbnailPath, folderName)
     if err != nil {
         log.Printf("Error uploading thumbnail: %v", err)
         return "", "", "", fmt.Errorf("error uploading thumbnail: %v", err)
@@ -1008,6 +695,15 @@ func (s *mediaService) ProcessImageFile(mediaFile *multipart.FileHeader, userID
         log.Printf("Error deleting temporary thumbnail file: %v", err)
     }
 
+    media.Filename = mediaFile.Filename
+    media.FeedURL = feedURL
+    media.FullSizeURL = fullSizeURL
+    media.ThumbnailURL = thumbnailURL
+    if err := s.mediaRepo.SaveMedia(media, reportIDStr, userID); err != nil {
+        log.Printf("Error saving media record: %v", err)
+        return "", "", "", fmt.Errorf("error saving media record: %v", err)
+    }
+
     log.Printf("Processed image file successfully: %s", mediaFile.Filename)
     return feedURL, thumbnailURL, fullSizeURL, nil
 }
@@ -1048,7 +744,8 @@ func (s *mediaService) GenerateImageThumbnail(mediaFile *multipart.FileHeader, t
     return nil
 }
 
-func (s *mediaService) UploadFileToS3(mediaFile *multipart.FileHeader, userID uint, fileType string) (string, error) {
+// UploadFile streams an uploaded file to storage as-is and returns its URL.
+func (s *mediaService) UploadFile(mediaFile *multipart.FileHeader, userID uint, fileType string) (string, error) {
 	// Open the media file
 	file, err := mediaFile.Open()
 	if err != nil {
@@ -1056,47 +753,13 @@ func (s *mediaService) UploadFileToS3(mediaFile *multipart.FileHeader, userID ui
 	}
 	defer file.Close()
 
-	// Generate a unique file key for the S3 bucket
+	// Generate a unique key for the file
 	fileExtension := filepath.Ext(mediaFile.Filename)
 	fileKey := fmt.Sprintf("media/%d_%s_%s%s", userID, fileType, uuid.New().String(), fileExtension)
 
-	// Prepare the S3 bucket name from environment variables
-	bucketName := os.Getenv("AWS_BUCKET")
-	if bucketName == "" {
-		return "", fmt.Errorf("S3 bucket name is not configured")
-	}
-
-	// Load AWS config
-	cfg, err := fig.LoadDefaultConfig(context.TODO(),
-		fig.WithRegion(os.Getenv("AWS_REGION")),
-		fig.WithCredentialsProvider(
-			credentials.NewStaticCredentialsProvider(os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"), ""),
-		),
-	)
+	fileURL, err := s.store.Put(context.TODO(), fileKey, file, mediaFile.Size, storage.PutOptions{ContentType: mediaFile.Header.Get("Content-Type")})
 	if err != nil {
-		return "", fmt.Errorf("unable to load AWS config: %v", err)
+		return "", fmt.Errorf("failed to upload file: %v", err)
 	}
-
-	// Create S3 client
-	svc := s3.NewFromConfig(cfg)
-
-	// Stream file directly to S3
-	putObjectInput := &s3.PutObjectInput{
-		Bucket:      aws.String(bucketName),
-		Key:         aws.String(fileKey),
-		Body:        file,                       // Stream the file content
-		ACL:         "public-read",              // Make the file publicly accessible
-		ContentType: aws.String(mediaFile.Header.Get("Content-Type")), // Use the file's content type
-	}
-
-	// Upload the file
-	_, err = svc.PutObject(context.TODO(), putObjectInput)
-	if err != nil {
-		return "", fmt.Errorf("failed to upload file to S3: %v", err)
-	}
-
-	// Construct the file URL
-	fileURL := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucketName, os.Getenv("AWS_REGION"), fileKey)
-
 	return fileURL, nil
 }
@@ -0,0 +1,407 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/techagentng/citizenx/config"
+	"github.com/techagentng/citizenx/db"
+	"github.com/techagentng/citizenx/models"
+	"github.com/techagentng/citizenx/storage"
+)
+
+const (
+	defaultMediaWorkers  = 2
+	mediaJobMaxAttempts  = 5
+	mediaJobPollInterval = 2 * time.Second
+	mediaJobBaseBackoff  = 30 * time.Second
+	mediaJobMaxBackoff   = 30 * time.Minute
+	// A running job's lock is refreshed every mediaJobHeartbeat; a job whose
+	// lock is older than mediaJobLease lost its worker and is requeued.
+	mediaJobHeartbeat     = 30 * time.Second
+	mediaJobLease         = 2 * time.Minute
+	mediaJobsSpoolDirName = "media-jobs"
+)
+
+// MediaJobQueue runs media processing (transcodes, thumbnails, uploads) off the
+// request goroutine. Jobs live in Postgres; a pool of workers claims them,
+// retries failures with exponential backoff and moves jobs that keep failing
+// to the dead-letter table.
+type MediaJobQueue interface {
+	// EnqueueFile takes ownership of the file at path (it is moved into the
+	// blob store, where any instance's workers can fetch it) and returns the
+	// pending media record.
+	EnqueueFile(path, filename, contentType string, userID uint, reportID string) (*models.Media, error)
+	// EnqueueUpload spools a form upload and queues it like EnqueueFile.
+	EnqueueUpload(fileHeader *multipart.FileHeader, userID uint, reportID string) (*models.Media, error)
+	// Start runs the worker pool until ctx is cancelled.
+	Start(ctx context.Context)
+}
+
+type mediaJobQueue struct {
+	Config       *config.Config
+	jobRepo      db.MediaJobRepository
+	mediaRepo    db.MediaRepository
+	reportRepo   db.IncidentReportRepository
+	mediaService MediaService
+	store        storage.BlobStore
+	notifier     *NotificationService
+}
+
+func NewMediaJobQueue(jobRepo db.MediaJobRepository, mediaRepo db.MediaRepository, reportRepo db.IncidentReportRepository, mediaService MediaService, store storage.BlobStore, notifier *NotificationService, conf *config.Config) MediaJobQueue {
+	return &mediaJobQueue{
+		Config:       conf,
+		jobRepo:      jobRepo,
+		mediaRepo:    mediaRepo,
+		reportRepo:   reportRepo,
+		mediaService: mediaService,
+		store:        store,
+		notifier:     notifier,
+	}
+}
+
+func (q *mediaJobQueue) spoolDir() string {
+	base := filepath.Join(os.TempDir(), "citizenx-uploads")
+	if q.Config != nil && q.Config.UploadTempDir != "" {
+		base = q.Config.UploadTempDir
+	}
+	return filepath.Join(base, mediaJobsSpoolDirName)
+}
+
+func (q *mediaJobQueue) workers() int {
+	if q.Config != nil && q.Config.MediaWorkers > 0 {
+		return q.Config.MediaWorkers
+	}
+	return defaultMediaWorkers
+}
+
+func (q *mediaJobQueue) EnqueueFile(path, filename, contentType string, userID uint, reportID string) (*models.Media, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %v", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to read upload: %v", err)
+	}
+
+	ctx := context.Background()
+	mediaID := uuid.New().String()
+	sourceKey := storage.Key(mediaJobsSpoolDirName, mediaID+filepath.Ext(filename))
+	_, err = q.store.Put(ctx, sourceKey, file, info.Size(), storage.PutOptions{ContentType: contentType})
+	file.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to spool upload: %v", err)
+	}
+	os.Remove(path)
+
+	media := models.Media{
+		ID:               mediaID,
+		FileType:         strings.SplitN(contentType, "/", 2)[0],
+		FileSize:         info.Size(),
+		Filename:         filename,
+		ProcessingStatus: models.MediaStatusPending,
+	}
+	if parsed, err := uuid.Parse(reportID); err == nil {
+		media.IncidentReportID = parsed
+	}
+	if err := q.mediaRepo.SaveMedia(media, reportID, userID); err != nil {
+		q.store.Delete(ctx, sourceKey)
+		return nil, fmt.Errorf("failed to save media record: %v", err)
+	}
+	media.UserID = userID
+
+	job := &models.MediaJob{
+		MediaID:          mediaID,
+		IncidentReportID: reportID,
+		UserID:           userID,
+		SourceKey:        sourceKey,
+		Filename:         filename,
+		ContentType:      contentType,
+		MaxAttempts:      mediaJobMaxAttempts,
+	}
+	if err := q.jobRepo.EnqueueMediaJob(job); err != nil {
+		q.store.Delete(ctx, sourceKey)
+		return nil, fmt.Errorf("failed to queue media job: %v", err)
+	}
+	return &media, nil
+}
+
+func (q *mediaJobQueue) EnqueueUpload(fileHeader *multipart.FileHeader, userID uint, reportID string) (*models.Media, error) {
+	if err := os.MkdirAll(q.spoolDir(), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %v", err)
+	}
+	src, err := fileHeader.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open upload: %v", err)
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp(q.spoolDir(), "upload-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to spool upload: %v", err)
+	}
+	if _, err := io.Copy(tmp, src); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, fmt.Errorf("failed to spool upload: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return nil, fmt.Errorf("failed to spool upload: %v", err)
+	}
+
+	contentType := fileHeader.Header.Get("Content-Type")
+	if contentType == "" || strings.HasPrefix(contentType, "application/") {
+		contentType = mime.TypeByExtension(filepath.Ext(fileHeader.Filename))
+	}
+	media, err := q.EnqueueFile(tmp.Name(), filepath.Base(fileHeader.Filename), contentType, userID, reportID)
+	if err != nil {
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	return media, nil
+}
+
+func (q *mediaJobQueue) Start(ctx context.Context) {
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		q.reap(ctx)
+	}()
+	for i := 0; i < q.workers(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			q.work(ctx)
+		}()
+	}
+	log.Printf("Started %d media workers", q.workers())
+	wg.Wait()
+}
+
+// reap requeues jobs whose worker stopped refreshing their lease, whichever
+// instance the worker ran on, until ctx is cancelled.
+func (q *mediaJobQueue) reap(ctx context.Context) {
+	ticker := time.NewTicker(mediaJobHeartbeat)
+	defer ticker.Stop()
+	for {
+		if n, err := q.jobRepo.RequeueStaleMediaJobs(time.Now().Add(-mediaJobLease)); err != nil {
+			log.Printf("Error requeueing stale media jobs: %v", err)
+		} else if n > 0 {
+			log.Printf("Requeued %d stale media jobs", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// heartbeat refreshes the job's lease until done is closed.
+func (q *mediaJobQueue) heartbeat(job *models.MediaJob, done <-chan struct{}) {
+	ticker := time.NewTicker(mediaJobHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if err := q.jobRepo.HeartbeatMediaJob(job); err != nil {
+				log.Printf("Error refreshing media job %d lease: %v", job.ID, err)
+			}
+		}
+	}
+}
+
+func (q *mediaJobQueue) work(ctx context.Context) {
+	for {
+		job, err := q.jobRepo.ClaimMediaJob()
+		if err != nil {
+			log.Printf("Error claiming media job: %v", err)
+		}
+		if job != nil {
+			q.run(job)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(mediaJobPollInterval):
+		}
+	}
+}
+
+func (q *mediaJobQueue) run(job *models.MediaJob) {
+	if err := q.mediaRepo.UpdateMediaStatus(job.MediaID, models.MediaStatusProcessing); err != nil {
+		log.Printf("Error updating media %s status: %v", job.MediaID, err)
+	}
+
+	done := make(chan struct{})
+	go q.heartbeat(job, done)
+	media, err := q.process(job)
+	close(done)
+	if err != nil {
+		q.fail(job, err)
+		return
+	}
+
+	if err := q.jobRepo.CompleteMediaJob(job); err != nil {
+		// A lost claim means another worker holds the job and finishes it
+		log.Printf("Error completing media job %d: %v", job.ID, err)
+		return
+	}
+	q.removeSource(job)
+	q.notifyIfReportReady(job, media)
+}
+
+func (q *mediaJobQueue) process(job *models.MediaJob) (*models.Media, error) {
+	path, cleanup, err := q.fetchSource(job)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+
+	fileHeader, form, err := fileHeaderFromDisk(path, job.Filename, job.ContentType)
+	if err != nil {
+		return nil, err
+	}
+	defer form.RemoveAll()
+
+	result, media := q.mediaService.ProcessMediaFile(fileHeader, job.IncidentReportID)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	media.ID = job.MediaID
+	media.UserID = job.UserID
+	media.ProcessingStatus = models.MediaStatusReady
+	if parsed, err := uuid.Parse(job.IncidentReportID); err == nil {
+		media.IncidentReportID = parsed
+	}
+	if err := q.mediaRepo.UpdateMedia(&media); err != nil {
+		return nil, fmt.Errorf("failed to update media record: %v", err)
+	}
+	return &media, nil
+}
+
+// fetchSource returns a local path to the job's source file and a func that
+// removes any copy made for it.
+func (q *mediaJobQueue) fetchSource(job *models.MediaJob) (string, func(), error) {
+	if job.SourceKey == "" {
+		return job.SourcePath, func() {}, nil
+	}
+	if err := os.MkdirAll(q.spoolDir(), 0o700); err != nil {
+		return "", nil, fmt.Errorf("failed to create spool directory: %v", err)
+	}
+	body, err := q.store.Get(context.Background(), job.SourceKey)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to fetch %s: %v", job.SourceKey, err)
+	}
+	defer body.Close()
+
+	tmp, err := os.CreateTemp(q.spoolDir(), "job-*"+filepath.Ext(job.Filename))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to fetch %s: %v", job.SourceKey, err)
+	}
+	cleanup := func() { os.Remove(tmp.Name()) }
+	_, err = io.Copy(tmp, body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		cleanup()
+		return "", nil, fmt.Errorf("failed to fetch %s: %v", job.SourceKey, err)
+	}
+	return tmp.Name(), cleanup, nil
+}
+
+// removeSource deletes the source file of a finished job.
+func (q *mediaJobQueue) removeSource(job *models.MediaJob) {
+	if job.SourceKey == "" {
+		if err := os.Remove(job.SourcePath); err != nil {
+			log.Printf("Error removing spooled file %s: %v", job.SourcePath, err)
+		}
+		return
+	}
+	if err := q.store.Delete(context.Background(), job.SourceKey); err != nil {
+		log.Printf("Error removing spooled object %s: %v", job.SourceKey, err)
+	}
+}
+
+// fail retries the job or dead-letters it once it is out of attempts. A
+// worker whose claim was lost leaves the job and its media to the new one.
+func (q *mediaJobQueue) fail(job *models.MediaJob, cause error) {
+	log.Printf("Media job %d attempt %d failed: %v", job.ID, job.Attempts, cause)
+
+	if job.Attempts >= job.MaxAttempts {
+		if err := q.jobRepo.DeadLetterMediaJob(job, cause.Error()); err != nil {
+			log.Printf("Error dead-lettering media job %d: %v", job.ID, err)
+			return
+		}
+		if err := q.mediaRepo.UpdateMediaStatus(job.MediaID, models.MediaStatusFailed); err != nil {
+			log.Printf("Error updating media %s status: %v", job.MediaID, err)
+		}
+		return
+	}
+
+	if err := q.jobRepo.RetryMediaJob(job, time.Now().Add(mediaJobBackoff(job.Attempts)), cause.Error()); err != nil {
+		log.Printf("Error rescheduling media job %d: %v", job.ID, err)
+		return
+	}
+	if err := q.mediaRepo.UpdateMediaStatus(job.MediaID, models.MediaStatusPending); err != nil {
+		log.Printf("Error updating media %s status: %v", job.MediaID, err)
+	}
+}
+
+// mediaJobBackoff doubles the delay after each failed attempt.
+func mediaJobBackoff(attempts int) time.Duration {
+	delay := mediaJobBaseBackoff << uint(attempts-1)
+	if delay <= 0 || delay > mediaJobMaxBackoff {
+		return mediaJobMaxBackoff
+	}
+	return delay
+}
+
+// notifyIfReportReady pushes to the reporter once the last queued file for
+// their report has finished.
+func (q *mediaJobQueue) notifyIfReportReady(job *models.MediaJob, media *models.Media) {
+	open, err := q.jobRepo.CountOpenMediaJobs(job.IncidentReportID)
+	if err != nil || open > 0 || q.notifier == nil {
+		return
+	}
+
+	token, err := q.reportRepo.GetExpoPushToken(job.UserID)
+	if err != nil || token == "" {
+		return
+	}
+	err = q.notifier.SendPushNotification(
+		token,
+		"Media ready",
+		"Your report's media has finished processing.",
+		map[string]interface{}{
+			"reportId": job.IncidentReportID,
+			"mediaId":  media.ID,
+			"type":     "media-ready",
+			"deepLink": fmt.Sprintf("/reports/%s", job.IncidentReportID),
+		},
+	)
+	if err != nil {
+		log.Printf("Failed to send media ready notification: %v", err)
+	}
+}
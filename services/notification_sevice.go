@@ -2,6 +2,7 @@ package services
 
 import (
 	"fmt"
+	"strings"
 	"github.com/oliveroneill/exponent-server-sdk-golang/sdk"
 
 )
@@ -25,7 +26,7 @@ func convertToMapString(input map[string]interface{}) map[string]string {
 
 // isValidExpoPushToken validates if a given token is a valid Expo push token.
 func isValidExpoPushToken(token string) bool {
-	return len(token) > 0 && token[:2] == "ExponentPushToken"
+	return strings.HasPrefix(token, "ExponentPushToken[") || strings.HasPrefix(token, "ExpoPushToken[")
 }
 
 func NewNotificationService() *NotificationService {
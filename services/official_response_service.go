@@ -0,0 +1,218 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/techagentng/citizenx/db"
+	"github.com/techagentng/citizenx/models"
+)
+
+var (
+	ErrReportNotFound       = errors.New("report not found")
+	ErrEmptyResponse        = errors.New("response text is required")
+	ErrInvalidResolutionDue = errors.New("expected resolution date must be a date like 2006-01-02 that is not in the past")
+	ErrNotOfficial          = errors.New("only officials can respond to reports")
+	ErrReportNotAssigned    = errors.New("report is not assigned to your agency")
+)
+
+// OfficialResponseInput is what an official submits when responding to a
+// report. Status and ExpectedResolutionDate are optional.
+type OfficialResponseInput struct {
+	Text   string
+	Status string
+	// ExpectedResolutionDate is YYYY-MM-DD
+	ExpectedResolutionDate string
+	MediaURLs              []string
+}
+
+// OfficialResponseService lets admins and agency staff respond to reports and
+// tells the reporter and followers about it.
+type OfficialResponseService interface {
+	// CheckResponse validates a response without saving it, so attachments
+	// are only stored for a response that will be accepted.
+	CheckResponse(user *models.User, role string, reportID string, input OfficialResponseInput) error
+	PostResponse(user *models.User, role string, reportID string, input OfficialResponseInput) (*models.OfficialResponse, error)
+	GetResponses(reportID string) ([]models.OfficialResponse, error)
+}
+
+type officialResponseService struct {
+	responseRepo db.OfficialResponseRepository
+	reportRepo   db.IncidentReportRepository
+	agencyRepo   db.AgencyRepository
+	notifier     *NotificationService
+}
+
+func NewOfficialResponseService(responseRepo db.OfficialResponseRepository, reportRepo db.IncidentReportRepository, agencyRepo db.AgencyRepository, notifier *NotificationService) OfficialResponseService {
+	return &officialResponseService{
+		responseRepo: responseRepo,
+		reportRepo:   reportRepo,
+		agencyRepo:   agencyRepo,
+		notifier:     notifier,
+	}
+}
+
+func (s *officialResponseService) CheckResponse(user *models.User, role string, reportID string, input OfficialResponseInput) error {
+	_, _, _, err := s.prepare(user, role, reportID, input)
+	return err
+}
+
+// PostResponse records an official response. Agency staff may only respond to
+// reports assigned to their agency, and a status they set is applied to that
+// assignment as well, in the same transaction as the response.
+func (s *officialResponseService) PostResponse(user *models.User, role string, reportID string, input OfficialResponseInput) (*models.OfficialResponse, error) {
+	response, assignment, report, err := s.prepare(user, role, reportID, input)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.responseRepo.CreateOfficialResponse(response, assignment); err != nil {
+		return nil, fmt.Errorf("failed to save response: %v", err)
+	}
+
+	go s.notify(report, response)
+	return response, nil
+}
+
+// prepare validates a response and builds it, along with the agency
+// assignment it moves to a new status when an agency sets one.
+func (s *officialResponseService) prepare(user *models.User, role string, reportID string, input OfficialResponseInput) (*models.OfficialResponse, *models.ReportAssignment, *models.IncidentReport, error) {
+	if user == nil || (role != models.RoleAdmin && role != models.RoleAgency) {
+		return nil, nil, nil, ErrNotOfficial
+	}
+	input.Text = strings.TrimSpace(input.Text)
+	if input.Text == "" {
+		return nil, nil, nil, ErrEmptyResponse
+	}
+	if input.Status != "" && !isAssignmentStatus(input.Status) {
+		return nil, nil, nil, ErrInvalidStatus
+	}
+	var expected *time.Time
+	if input.ExpectedResolutionDate != "" {
+		t, err := time.Parse("2006-01-02", input.ExpectedResolutionDate)
+		if err != nil || t.Before(time.Now().Truncate(24*time.Hour)) {
+			return nil, nil, nil, ErrInvalidResolutionDue
+		}
+		expected = &t
+	}
+
+	report, err := s.getReport(reportID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	response := &models.OfficialResponse{
+		IncidentReportID:       report.ID,
+		ResponderID:            user.ID,
+		ResponderName:          user.Fullname,
+		Text:                   input.Text,
+		Status:                 input.Status,
+		ExpectedResolutionDate: expected,
+		MediaURLs:              input.MediaURLs,
+	}
+
+	if role != models.RoleAgency {
+		return response, nil, report, nil
+	}
+	assignment, err := s.agencyAssignment(user, report.ID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	response.AgencyID = &assignment.AgencyID
+	if assignment.Agency != nil {
+		response.AgencyName = assignment.Agency.Name
+	}
+	if input.Status == "" {
+		return response, nil, report, nil
+	}
+	applyAssignmentStatus(assignment, user.ID, input.Status, input.Text)
+	return response, assignment, report, nil
+}
+
+func (s *officialResponseService) GetResponses(reportID string) ([]models.OfficialResponse, error) {
+	report, err := s.getReport(reportID)
+	if err != nil {
+		return nil, err
+	}
+	responses, err := s.responseRepo.GetOfficialResponses(report.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch responses: %v", err)
+	}
+	return responses, nil
+}
+
+func (s *officialResponseService) getReport(reportID string) (*models.IncidentReport, error) {
+	if _, err := uuid.Parse(reportID); err != nil {
+		return nil, ErrReportNotFound
+	}
+	report, err := s.reportRepo.GetReportByID(reportID)
+	if err != nil || report == nil {
+		return nil, ErrReportNotFound
+	}
+	return report, nil
+}
+
+// agencyAssignment returns the user's agency assignment for the report.
+func (s *officialResponseService) agencyAssignment(user *models.User, reportID uuid.UUID) (*models.ReportAssignment, error) {
+	if user.AgencyID == nil {
+		return nil, ErrNotAgencyMember
+	}
+	assignments, err := s.agencyRepo.GetReportAssignments(reportID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch assignments: %v", err)
+	}
+	for i := range assignments {
+		if assignments[i].AgencyID == *user.AgencyID {
+			return &assignments[i], nil
+		}
+	}
+	return nil, ErrReportNotAssigned
+}
+
+// notify pushes the response to the reporter and everyone following the
+// report, once per device.
+func (s *officialResponseService) notify(report *models.IncidentReport, response *models.OfficialResponse) {
+	if s.notifier == nil {
+		return
+	}
+
+	tokens := map[string]bool{}
+	if token, err := s.reportRepo.GetExpoPushToken(report.UserID); err == nil && token != "" {
+		tokens[token] = true
+	}
+	followers, err := s.reportRepo.GetFollowersByReport(report.ID)
+	if err != nil {
+		log.Printf("Failed to get followers of report %s: %v", report.ID, err)
+	}
+	for _, follower := range followers {
+		if follower.ExpoPushToken != "" {
+			tokens[follower.ExpoPushToken] = true
+		}
+	}
+
+	from := response.AgencyName
+	if from == "" {
+		from = "An official"
+	}
+	body := fmt.Sprintf("%s responded: %s", from, response.Text)
+	for token := range tokens {
+		err := s.notifier.SendPushNotification(
+			token,
+			"Official response",
+			body,
+			map[string]interface{}{
+				"reportId":   report.ID.String(),
+				"responseId": fmt.Sprint(response.ID),
+				"status":     response.Status,
+				"type":       "official-response",
+				"deepLink":   fmt.Sprintf("/reports/%s", report.ID.String()),
+			},
+		)
+		if err != nil {
+			log.Printf("Failed to send official response notification: %v", err)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/techagentng/citizenx/config"
+	"github.com/techagentng/citizenx/models"
+)
+
+// PayoutProviderFake names the provider that pays without moving money.
+const PayoutProviderFake = "fake"
+
+// ErrPayoutNotFound is returned by Status when the provider has no payout
+// under the reference, so the payout never reached it.
+var ErrPayoutNotFound = errors.New("payout not found at provider")
+
+// PayoutResult is a provider's view of a payout. Status is one of
+// models.RedemptionProcessing, models.RedemptionPaid or
+// models.RedemptionFailed.
+type PayoutResult struct {
+	Reference     string
+	Status        string
+	FailureReason string
+}
+
+// PayoutProvider sends airtime, data or money for an approved redemption.
+// Providers that settle later return a processing result and report the
+// outcome through Status. The redemption's Reference is sent as the merchant
+// reference, and Status accepts it as well as the provider's own reference so
+// a payout whose response was lost can still be looked up.
+type PayoutProvider interface {
+	Name() string
+	Payout(redemption *models.Redemption) (*PayoutResult, error)
+	Status(reference string) (*PayoutResult, error)
+}
+
+// NewPayoutProvider returns the provider named in the config. There is no
+// default: a deploy that forgets to name one must not mark payouts paid
+// without moving money.
+func NewPayoutProvider(conf *config.Config) (PayoutProvider, error) {
+	name := strings.ToLower(conf.PayoutProvider)
+	switch name {
+	case "":
+		return nil, fmt.Errorf("no payout provider configured; set PAYOUT_PROVIDER (%q for local use)", PayoutProviderFake)
+	case PayoutProviderFake:
+		return NewFakePayoutProvider(), nil
+	}
+	return nil, fmt.Errorf("unknown payout provider %q", conf.PayoutProvider)
+}
+
+// fakePayoutProvider pays every redemption at once, except those to a phone
+// or account number ending in 000, which fail so the failure path can be
+// exercised locally.
+type fakePayoutProvider struct {
+	mu      sync.Mutex
+	results map[string]*PayoutResult
+}
+
+func NewFakePayoutProvider() PayoutProvider {
+	return &fakePayoutProvider{results: map[string]*PayoutResult{}}
+}
+
+func (p *fakePayoutProvider) Name() string {
+	return PayoutProviderFake
+}
+
+func (p *fakePayoutProvider) Payout(redemption *models.Redemption) (*PayoutResult, error) {
+	result := &PayoutResult{Reference: "fake-" + uuid.New().String(), Status: models.RedemptionPaid}
+	destination := redemption.PhoneNumber
+	if redemption.Type == models.RedemptionBankTransfer {
+		destination = redemption.AccountNumber
+	}
+	if strings.HasSuffix(destination, "000") {
+		result.Status = models.RedemptionFailed
+		result.FailureReason = "destination rejected by fake provider"
+	}
+
+	p.mu.Lock()
+	p.results[result.Reference] = result
+	p.results[redemption.Reference] = result
+	p.mu.Unlock()
+	return result, nil
+}
+
+func (p *fakePayoutProvider) Status(reference string) (*PayoutResult, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	result, ok := p.results[reference]
+	if !ok {
+		return nil, ErrPayoutNotFound
+	}
+	return result, nil
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+
+	"github.com/disintegration/imaging"
+)
+
+// DuplicateHashDistance is the largest Hamming distance between two dHashes
+// that still counts as the same photo. Re-encoding, resizing and light edits
+// usually stay well under it; unrelated photos land around 32.
+const DuplicateHashDistance = 10
+
+// DifferenceHash computes a 64 bit dHash of the image: it is shrunk to 9x8
+// grayscale and each bit records whether a pixel is brighter than its right
+// neighbour. Similar images produce hashes a small Hamming distance apart.
+func DifferenceHash(img image.Image) uint64 {
+	small := imaging.Grayscale(imaging.Resize(img, 9, 8, imaging.Box))
+
+	var hash uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			left := small.Pix[small.PixOffset(x, y)]
+			right := small.Pix[small.PixOffset(x+1, y)]
+			hash <<= 1
+			if left > right {
+				hash |= 1
+			}
+		}
+	}
+	return hash
+}
+
+// DifferenceHashBytes decodes an encoded image and returns its dHash.
+func DifferenceHashBytes(fileBytes []byte) (uint64, error) {
+	img, err := imaging.Decode(bytes.NewReader(fileBytes))
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode image for hashing: %v", err)
+	}
+	return DifferenceHash(img), nil
+}
@@ -0,0 +1,286 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/techagentng/citizenx/config"
+	"github.com/techagentng/citizenx/db"
+	"github.com/techagentng/citizenx/models"
+)
+
+// Minimum points for each kind of redemption when the config sets none.
+const (
+	defaultMinAirtimeRedemption      = 100
+	defaultMinDataRedemption         = 200
+	defaultMinBankTransferRedemption = 1000
+)
+
+var (
+	ErrRedemptionNotFound   = errors.New("redemption not found")
+	ErrInvalidRedemption    = errors.New("invalid redemption")
+	ErrBelowMinimum         = errors.New("points are below the minimum for this redemption")
+	ErrRedemptionNotPending = errors.New("redemption is no longer pending")
+	ErrRedemptionNotPayable = errors.New("redemption is not awaiting payout")
+)
+
+var (
+	phoneNumberPattern   = regexp.MustCompile(`^\+?[0-9]{10,14}$`)
+	accountNumberPattern = regexp.MustCompile(`^[0-9]{10}$`)
+)
+
+// RedemptionKey is the ledger idempotency key of a redemption's debit.
+func RedemptionKey(reference string) string { return "redemption:" + reference }
+
+// RedemptionInput is a user's request to cash out points.
+type RedemptionInput struct {
+	Type          string `json:"type" binding:"required"`
+	Points        int    `json:"points" binding:"required"`
+	PhoneNumber   string `json:"phone_number"`
+	Network       string `json:"network"`
+	BankCode      string `json:"bank_code"`
+	AccountNumber string `json:"account_number"`
+	AccountName   string `json:"account_name"`
+}
+
+// RedemptionService turns points into airtime, data or bank transfers. Points
+// are held by debiting the ledger when the request is made; an admin then
+// approves the payout or rejects the request.
+type RedemptionService interface {
+	RequestRedemption(userID uint, input RedemptionInput) (*models.Redemption, error)
+	GetUserRedemptions(userID uint, page int) ([]models.Redemption, error)
+	ListRedemptions(status string, page int) ([]models.Redemption, error)
+	ApproveRedemption(adminID, id uint) (*models.Redemption, error)
+	RejectRedemption(adminID, id uint, reason string) (*models.Redemption, error)
+	RefreshRedemption(id uint) (*models.Redemption, error)
+}
+
+type redemptionService struct {
+	Config         *config.Config
+	redemptionRepo db.RedemptionRepository
+	ledger         LedgerService
+	provider       PayoutProvider
+}
+
+func NewRedemptionService(redemptionRepo db.RedemptionRepository, ledger LedgerService, provider PayoutProvider, conf *config.Config) RedemptionService {
+	return &redemptionService{
+		Config:         conf,
+		redemptionRepo: redemptionRepo,
+		ledger:         ledger,
+		provider:       provider,
+	}
+}
+
+func (s *redemptionService) minimum(redemptionType string) int {
+	switch redemptionType {
+	case models.RedemptionAirtime:
+		if s.Config != nil && s.Config.MinAirtimeRedemption > 0 {
+			return s.Config.MinAirtimeRedemption
+		}
+		return defaultMinAirtimeRedemption
+	case models.RedemptionData:
+		if s.Config != nil && s.Config.MinDataRedemption > 0 {
+			return s.Config.MinDataRedemption
+		}
+		return defaultMinDataRedemption
+	}
+	if s.Config != nil && s.Config.MinBankTransferRedemption > 0 {
+		return s.Config.MinBankTransferRedemption
+	}
+	return defaultMinBankTransferRedemption
+}
+
+func (s *redemptionService) nairaPerPoint() float64 {
+	if s.Config != nil && s.Config.NairaPerPoint > 0 {
+		return s.Config.NairaPerPoint
+	}
+	return 1
+}
+
+// RequestRedemption validates the request and debits the points, failing
+// with ErrInsufficientPoints when the user does not have them.
+func (s *redemptionService) RequestRedemption(userID uint, input RedemptionInput) (*models.Redemption, error) {
+	redemption := &models.Redemption{
+		Reference: uuid.New().String(),
+		UserID:    userID,
+		Type:      input.Type,
+		Points:    input.Points,
+		Status:    models.RedemptionPending,
+	}
+	switch input.Type {
+	case models.RedemptionAirtime, models.RedemptionData:
+		redemption.PhoneNumber = strings.TrimSpace(input.PhoneNumber)
+		redemption.Network = strings.ToLower(strings.TrimSpace(input.Network))
+		if !phoneNumberPattern.MatchString(redemption.PhoneNumber) || redemption.Network == "" {
+			return nil, fmt.Errorf("%w: a phone number and network are required", ErrInvalidRedemption)
+		}
+	case models.RedemptionBankTransfer:
+		redemption.BankCode = strings.TrimSpace(input.BankCode)
+		redemption.AccountNumber = strings.TrimSpace(input.AccountNumber)
+		redemption.AccountName = strings.TrimSpace(input.AccountName)
+		if redemption.BankCode == "" || redemption.AccountName == "" || !accountNumberPattern.MatchString(redemption.AccountNumber) {
+			return nil, fmt.Errorf("%w: a bank code, 10 digit account number and account name are required", ErrInvalidRedemption)
+		}
+	default:
+		return nil, fmt.Errorf("%w: type must be airtime, data or bank_transfer", ErrInvalidRedemption)
+	}
+	if min := s.minimum(input.Type); input.Points < min {
+		return nil, fmt.Errorf("%w: at least %d points are needed", ErrBelowMinimum, min)
+	}
+	redemption.Amount = float64(input.Points) * s.nairaPerPoint()
+
+	txn, err := s.ledger.Redeem(userID, input.Points, RedemptionKey(redemption.Reference), "Redemption for "+input.Type)
+	if err != nil {
+		return nil, err
+	}
+	redemption.LedgerTransactionID = txn.ID
+
+	if err := s.redemptionRepo.CreateRedemption(redemption); err != nil {
+		if _, revErr := s.ledger.Reverse(RedemptionKey(redemption.Reference), 0, "Redemption could not be saved"); revErr != nil {
+			log.Printf("Unable to return points for redemption %s: %v", redemption.Reference, revErr)
+		}
+		return nil, fmt.Errorf("failed to save redemption: %v", err)
+	}
+	return redemption, nil
+}
+
+func (s *redemptionService) GetUserRedemptions(userID uint, page int) ([]models.Redemption, error) {
+	redemptions, err := s.redemptionRepo.GetUserRedemptions(userID, page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch redemptions: %v", err)
+	}
+	return redemptions, nil
+}
+
+func (s *redemptionService) ListRedemptions(status string, page int) ([]models.Redemption, error) {
+	redemptions, err := s.redemptionRepo.ListRedemptions(status, page)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch redemptions: %v", err)
+	}
+	return redemptions, nil
+}
+
+// ApproveRedemption sends the payout. A payout the provider refuses is marked
+// failed and its points returned. When the call itself fails the payout may
+// still have gone through, so the redemption stays processing until
+// RefreshRedemption learns the outcome.
+func (s *redemptionService) ApproveRedemption(adminID, id uint) (*models.Redemption, error) {
+	redemption, err := s.claim(id, models.RedemptionPending, models.RedemptionProcessing)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	redemption.ReviewedBy = adminID
+	redemption.ReviewedAt = &now
+	redemption.Provider = s.provider.Name()
+
+	result, err := s.provider.Payout(redemption)
+	if err != nil {
+		log.Printf("Payout of redemption %s has no outcome yet: %v", redemption.Reference, err)
+		result = &PayoutResult{Status: models.RedemptionProcessing}
+	}
+	return s.applyResult(redemption, result)
+}
+
+// RejectRedemption turns the request down and returns the points.
+func (s *redemptionService) RejectRedemption(adminID, id uint, reason string) (*models.Redemption, error) {
+	redemption, err := s.claim(id, models.RedemptionPending, models.RedemptionRejected)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	redemption.ReviewedBy = adminID
+	redemption.ReviewedAt = &now
+	redemption.RejectionReason = reason
+
+	if _, err := s.ledger.Reverse(RedemptionKey(redemption.Reference), adminID, "Redemption rejected"); err != nil {
+		return nil, err
+	}
+	if err := s.redemptionRepo.UpdateRedemption(redemption); err != nil {
+		return nil, fmt.Errorf("failed to update redemption: %v", err)
+	}
+	return redemption, nil
+}
+
+// RefreshRedemption asks the provider how a payout still processing ended.
+// A payout without a provider reference is looked up by our own reference,
+// and only one the provider never received is failed and refunded.
+func (s *redemptionService) RefreshRedemption(id uint) (*models.Redemption, error) {
+	redemption, err := s.getRedemption(id)
+	if err != nil {
+		return nil, err
+	}
+	if redemption.Status != models.RedemptionProcessing {
+		return nil, ErrRedemptionNotPayable
+	}
+	reference := redemption.ProviderReference
+	if reference == "" {
+		reference = redemption.Reference
+	}
+	result, err := s.provider.Status(reference)
+	if errors.Is(err, ErrPayoutNotFound) {
+		result = &PayoutResult{Status: models.RedemptionFailed, FailureReason: "payout never reached the provider"}
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to fetch payout status: %v", err)
+	}
+	return s.applyResult(redemption, result)
+}
+
+// applyResult records the provider's answer, returning the points of a
+// failed payout.
+func (s *redemptionService) applyResult(redemption *models.Redemption, result *PayoutResult) (*models.Redemption, error) {
+	if result.Reference != "" {
+		redemption.ProviderReference = result.Reference
+	}
+	switch result.Status {
+	case models.RedemptionPaid:
+		now := time.Now()
+		redemption.Status = models.RedemptionPaid
+		redemption.PaidAt = &now
+	case models.RedemptionFailed:
+		redemption.Status = models.RedemptionFailed
+		redemption.FailureReason = result.FailureReason
+		if _, err := s.ledger.Reverse(RedemptionKey(redemption.Reference), 0, "Payout failed"); err != nil {
+			return nil, err
+		}
+	default:
+		redemption.Status = models.RedemptionProcessing
+	}
+	if err := s.redemptionRepo.UpdateRedemption(redemption); err != nil {
+		return nil, fmt.Errorf("failed to update redemption: %v", err)
+	}
+	return redemption, nil
+}
+
+// claim moves a redemption between statuses so only one admin acts on it.
+func (s *redemptionService) claim(id uint, from, to string) (*models.Redemption, error) {
+	redemption, err := s.getRedemption(id)
+	if err != nil {
+		return nil, err
+	}
+	claimed, err := s.redemptionRepo.TransitionRedemption(id, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update redemption: %v", err)
+	}
+	if !claimed {
+		return nil, ErrRedemptionNotPending
+	}
+	redemption.Status = to
+	return redemption, nil
+}
+
+func (s *redemptionService) getRedemption(id uint) (*models.Redemption, error) {
+	redemption, err := s.redemptionRepo.GetRedemptionByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch redemption: %v", err)
+	}
+	if redemption == nil {
+		return nil, ErrRedemptionNotFound
+	}
+	return redemption, nil
+}
@@ -0,0 +1,165 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/techagentng/citizenx/config"
+	"github.com/techagentng/citizenx/db"
+	"github.com/techagentng/citizenx/models"
+)
+
+const (
+	// defaultReopenThreshold is how many "still broken" votes reopen a report
+	// when the threshold is not configured.
+	defaultReopenThreshold = 3
+	// defaultResolutionVoteRadius is how near, in metres, citizens other than
+	// the reporter must be to vote when the radius is not configured.
+	defaultResolutionVoteRadius = 2000.0
+)
+
+var (
+	ErrReportNotResolved = errors.New("report has not been resolved")
+	ErrLocationRequired  = errors.New("your location is required to confirm a report you did not file")
+	ErrTooFarFromReport  = errors.New("you must be near the report to confirm its resolution")
+)
+
+// ResolutionVoteInput is a citizen's verdict on a resolved report. Latitude
+// and Longitude are where the voter is and are not needed from the reporter.
+type ResolutionVoteInput struct {
+	Fixed     *bool    `json:"fixed" binding:"required"`
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+}
+
+// ResolutionService lets the reporter and nearby citizens confirm or dispute
+// a resolution, and reopens reports enough of them say are still broken.
+type ResolutionService interface {
+	Vote(userID uint, reportID string, input ResolutionVoteInput) (*models.ResolutionTally, bool, error)
+	GetTally(reportID string) (*models.ResolutionTally, error)
+	GetConfirmationRates(category, state string) (*models.ConfirmationReport, error)
+}
+
+type resolutionService struct {
+	Config       *config.Config
+	voteRepo     db.ResolutionVoteRepository
+	reportRepo   db.IncidentReportRepository
+	categoryRepo db.CategoryRepository
+}
+
+func NewResolutionService(voteRepo db.ResolutionVoteRepository, reportRepo db.IncidentReportRepository, categoryRepo db.CategoryRepository, conf *config.Config) ResolutionService {
+	return &resolutionService{
+		Config:       conf,
+		voteRepo:     voteRepo,
+		reportRepo:   reportRepo,
+		categoryRepo: categoryRepo,
+	}
+}
+
+func (s *resolutionService) reopenThreshold() int64 {
+	if s.Config != nil && s.Config.ReopenThreshold > 0 {
+		return int64(s.Config.ReopenThreshold)
+	}
+	return defaultReopenThreshold
+}
+
+func (s *resolutionService) voteRadius() float64 {
+	if s.Config != nil && s.Config.ResolutionVoteRadius > 0 {
+		return s.Config.ResolutionVoteRadius
+	}
+	return defaultResolutionVoteRadius
+}
+
+// Vote records the user's verdict on the report's current resolution and
+// returns the tally, reopening the report once enough citizens say it is
+// still broken. The second result reports whether this vote reopened it.
+func (s *resolutionService) Vote(userID uint, reportID string, input ResolutionVoteInput) (*models.ResolutionTally, bool, error) {
+	report, err := s.getReport(reportID)
+	if err != nil {
+		return nil, false, err
+	}
+	if report.ResolutionStatus != models.AssignmentStatusResolved {
+		return nil, false, ErrReportNotResolved
+	}
+
+	isReporter := report.UserID == userID
+	if !isReporter {
+		if input.Latitude == nil || input.Longitude == nil {
+			return nil, false, ErrLocationRequired
+		}
+		if haversineMeters(*input.Latitude, *input.Longitude, report.Latitude, report.Longitude) > s.voteRadius() {
+			return nil, false, ErrTooFarFromReport
+		}
+	}
+
+	vote := &models.ResolutionVote{
+		IncidentReportID: report.ID,
+		UserID:           userID,
+		Round:            report.ResolutionRound,
+		Fixed:            *input.Fixed,
+		IsReporter:       isReporter,
+	}
+	if err := s.voteRepo.SaveVote(vote); err != nil {
+		return nil, false, fmt.Errorf("failed to save vote: %v", err)
+	}
+
+	tally, err := s.voteRepo.GetTally(report.ID, report.ResolutionRound)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to count votes: %v", err)
+	}
+	if tally.StillBroken < s.reopenThreshold() {
+		return tally, false, nil
+	}
+
+	note := fmt.Sprintf("Reopened after %d citizens reported it still broken", tally.StillBroken)
+	reopened, err := s.voteRepo.ReopenReport(report.ID, report.ResolutionRound, s.resolveDue(report), note)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to reopen report: %v", err)
+	}
+	return tally, reopened, nil
+}
+
+func (s *resolutionService) GetTally(reportID string) (*models.ResolutionTally, error) {
+	report, err := s.getReport(reportID)
+	if err != nil {
+		return nil, err
+	}
+	tally, err := s.voteRepo.GetTally(report.ID, report.ResolutionRound)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count votes: %v", err)
+	}
+	return tally, nil
+}
+
+// GetConfirmationRates reports how often resolution votes confirmed the fix,
+// by agency and by state.
+func (s *resolutionService) GetConfirmationRates(category, state string) (*models.ConfirmationReport, error) {
+	byAgency, err := s.voteRepo.GetConfirmationMetrics("agency", category, state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch confirmation rates by agency: %v", err)
+	}
+	byState, err := s.voteRepo.GetConfirmationMetrics("state", category, state)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch confirmation rates by state: %v", err)
+	}
+	return &models.ConfirmationReport{ByAgency: byAgency, ByState: byState}, nil
+}
+
+func (s *resolutionService) getReport(reportID string) (*models.IncidentReport, error) {
+	report, err := s.reportRepo.GetReportByID(reportID)
+	if err != nil || report == nil {
+		return nil, ErrReportNotFound
+	}
+	return report, nil
+}
+
+// resolveDue restarts the resolve target of the report's category for a
+// reopened report. It is nil when the category has no target.
+func (s *resolutionService) resolveDue(report *models.IncidentReport) *time.Time {
+	category, err := s.categoryRepo.GetCategoryByID(report.CategoryID)
+	if err != nil || category == nil {
+		return nil
+	}
+	return slaDueAt(time.Now(), category.ResolveWithinHours)
+}
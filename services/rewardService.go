@@ -8,41 +8,40 @@ import (
 )
 
 type RewardService interface {
-	ApproveReportPoints(reportID string, userID uint) error
-	RejectReportPoints(reportID string, userID uint) error
-	AcceptReportPoints(reportID string, userID uint) error
-	SaveReward(reward *models.Reward) error
+	ApproveReportPoints(reportID string) error
+	RejectReportPoints(reportID string) error
+	AcceptReportPoints(reportID string) error
 	GetAllRewardsBalanceCount() (int, error)
-	GetAllRewards() ([]models.Reward, error)
+	GetAllRewards(page int) ([]models.LedgerTransaction, error)
 }
 
 type rewardService struct {
 	Config       *config.Config
-	rewardRepo   db.RewardRepository
 	incidentRepo db.IncidentReportRepository
+	ledger       LedgerService
+	rewards      RewardEngine
+	badges       BadgeService
 }
 
-func NewRewardService(rewardRepo db.RewardRepository, incidentRepo db.IncidentReportRepository, conf *config.Config) RewardService {
+func NewRewardService(incidentRepo db.IncidentReportRepository, ledger LedgerService, rewards RewardEngine, badges BadgeService, conf *config.Config) RewardService {
 	return &rewardService{
 		Config:       conf,
-		rewardRepo:   rewardRepo,
 		incidentRepo: incidentRepo,
+		ledger:       ledger,
+		rewards:      rewards,
+		badges:       badges,
 	}
 }
 
-func (s *rewardService) ApproveReportPoints(reportID string, userID uint) error {
+// ApproveReportPoints approves the report and pays its reporter the approval
+// rules, which count the points the report earned when submitted. Approving
+// twice credits once; approving again after a rejection pays again.
+func (s *rewardService) ApproveReportPoints(reportID string) error {
 	report, err := s.incidentRepo.GetReportByID(reportID)
-	var reward models.Reward
 	if err != nil {
 		return fmt.Errorf("error fetching report: %v", err)
 	}
 
-	//get user reward points
-	points, err := s.rewardRepo.GetRewardPointByReportID(reportID)
-	if err != nil {
-		return err
-	}
-	// Update reward balance with the points value
 	report.ReportStatus = "approved"
 
 	// Call UpdateIncidentReport and handle the error
@@ -50,42 +49,47 @@ func (s *rewardService) ApproveReportPoints(reportID string, userID uint) error
 		return fmt.Errorf("error updating report status: %v", err)
 	}
 
-	newBalance := reward.Balance + points
-	reward = models.Reward{
-		Model:            models.Model{},
-		IncidentReportID: reportID,
-		UserID:           userID,
-		RewardType:       "Another Entry",
-		Point:            points,
-		Balance:          newBalance,
-		AccountNumber:    "",
-	}
-
-	if err := s.rewardRepo.SaveReward(&reward); err != nil {
+	_, err = s.rewards.Award(RewardEvent{
+		Action:     models.RewardActionReportApproved,
+		UserID:     report.UserID,
+		ReportID:   reportID,
+		CategoryID: report.CategoryID,
+		Quantity:   report.RewardPoint,
+		Verified:   report.IsVerified,
+		Key:        ApprovalKey(reportID, report.ApprovalRound),
+	})
+	if err != nil {
 		return fmt.Errorf("error saving reward: %v", err)
 	}
+	s.badges.EvaluateAsync(report.UserID, BadgeEventReportApproved)
 
 	return nil
 }
 
-func (s *rewardService) RejectReportPoints(reportID string, userID uint) error {
+// RejectReportPoints rejects the report and reverses its approval bonus if it
+// had been approved. The next approval starts a new round.
+func (s *rewardService) RejectReportPoints(reportID string) error {
 	report, err := s.incidentRepo.GetReportByID(reportID)
 	if err != nil {
 		return fmt.Errorf("error fetching report: %v", err)
 	}
 
-	// Update reward balance with the points value
 	report.ReportStatus = "rejected"
+	report.ApprovalRound++
 
 	// Call UpdateIncidentReport and check for errors
 	if err := s.incidentRepo.UpdateIncidentReport(report); err != nil {
 		return fmt.Errorf("error updating report status: %v", err)
 	}
 
+	if _, err := s.ledger.ReverseEvent(ApprovalKey(reportID, 0), 0, "Report rejected"); err != nil {
+		return fmt.Errorf("error reversing reward: %v", err)
+	}
+
 	return nil
 }
 
-func (s *rewardService) AcceptReportPoints(reportID string, userID uint) error {
+func (s *rewardService) AcceptReportPoints(reportID string) error {
 	report, err := s.incidentRepo.GetReportByID(reportID)
 	if err != nil {
 		return fmt.Errorf("error fetching report: %v", err)
@@ -102,20 +106,13 @@ func (s *rewardService) AcceptReportPoints(reportID string, userID uint) error {
 	return nil
 }
 
-func (s *rewardService) SaveReward(reward *models.Reward) error {
-	return s.rewardRepo.SaveReward(reward)
-}
-
 func (s *rewardService) GetAllRewardsBalanceCount() (int, error) {
-	totalBalance, err := s.rewardRepo.SumAllRewardsBalance()
-	if err != nil {
-		return 0, fmt.Errorf("error getting total rewards balance: %w", err)
-	}
-	return totalBalance, nil
+	return s.ledger.GetTotalBalance()
 }
 
-func (s *rewardService) GetAllRewards() ([]models.Reward, error) {
-	rewards, err := s.rewardRepo.GetAllRewards()
+// GetAllRewards returns a page of the points ledger, newest first.
+func (s *rewardService) GetAllRewards(page int) ([]models.LedgerTransaction, error) {
+	rewards, err := s.ledger.GetTransactions(page)
 	if err != nil {
 		return nil, fmt.Errorf("error getting all rewards: %w", err)
 	}
@@ -0,0 +1,286 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/techagentng/citizenx/db"
+	"github.com/techagentng/citizenx/models"
+)
+
+var (
+	ErrRewardRuleNotFound = errors.New("reward rule not found")
+	ErrInvalidRewardRule  = errors.New("invalid reward rule")
+)
+
+// RewardRuleInput carries the fields an admin sets on a reward rule. Fields
+// left nil keep their current value on update. Category is a category slug;
+// an empty string makes the rule apply to every category.
+type RewardRuleInput struct {
+	Name               *string    `json:"name"`
+	Action             *string    `json:"action"`
+	Category           *string    `json:"category"`
+	Points             *int       `json:"points"`
+	VerifiedMultiplier *float64   `json:"verified_multiplier"`
+	DailyCap           *int       `json:"daily_cap"`
+	FirstReportOnly    *bool      `json:"first_report_only"`
+	StartsAt           *time.Time `json:"starts_at"`
+	EndsAt             *time.Time `json:"ends_at"`
+	Active             *bool      `json:"active"`
+}
+
+// RewardEvent is something a user did that reward rules may pay for.
+type RewardEvent struct {
+	Action     string
+	UserID     uint
+	ReportID   string
+	CategoryID uint
+	// Quantity is how many units of the action happened, e.g. media items
+	Quantity    int
+	Verified    bool
+	FirstReport bool
+	// Key identifies the event; each rule's credit is keyed under it so the
+	// event pays out once
+	Key string
+	At  time.Time
+}
+
+// RewardAward is what one rule pays for an event.
+type RewardAward struct {
+	RuleID uint   `json:"rule_id"`
+	Rule   string `json:"rule"`
+	Points int    `json:"points"`
+}
+
+// RewardEngine holds the admin managed reward rules and works out, and
+// credits, the points every action earns.
+type RewardEngine interface {
+	ListRules() ([]models.RewardRule, error)
+	CreateRule(input RewardRuleInput) (*models.RewardRule, error)
+	UpdateRule(id uint, input RewardRuleInput) (*models.RewardRule, error)
+	DeleteRule(id uint) error
+	Evaluate(event RewardEvent) ([]RewardAward, error)
+	Credit(event RewardEvent, awards []RewardAward) (int, error)
+	Award(event RewardEvent) (int, error)
+}
+
+type rewardEngine struct {
+	ruleRepo     db.RewardRuleRepository
+	categoryRepo db.CategoryRepository
+	ledgerRepo   db.LedgerRepository
+	ledger       LedgerService
+}
+
+func NewRewardEngine(ruleRepo db.RewardRuleRepository, categoryRepo db.CategoryRepository, ledgerRepo db.LedgerRepository, ledger LedgerService) RewardEngine {
+	return &rewardEngine{
+		ruleRepo:     ruleRepo,
+		categoryRepo: categoryRepo,
+		ledgerRepo:   ledgerRepo,
+		ledger:       ledger,
+	}
+}
+
+func (e *rewardEngine) ListRules() ([]models.RewardRule, error) {
+	rules, err := e.ruleRepo.ListRules()
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch reward rules: %v", err)
+	}
+	return rules, nil
+}
+
+func (e *rewardEngine) CreateRule(input RewardRuleInput) (*models.RewardRule, error) {
+	rule := &models.RewardRule{Active: true}
+	if err := e.applyRuleInput(input, rule); err != nil {
+		return nil, err
+	}
+	if err := e.ruleRepo.CreateRule(rule); err != nil {
+		return nil, fmt.Errorf("failed to create reward rule: %v", err)
+	}
+	return rule, nil
+}
+
+func (e *rewardEngine) UpdateRule(id uint, input RewardRuleInput) (*models.RewardRule, error) {
+	rule, err := e.getRule(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.applyRuleInput(input, rule); err != nil {
+		return nil, err
+	}
+	if err := e.ruleRepo.UpdateRule(rule); err != nil {
+		return nil, fmt.Errorf("failed to update reward rule: %v", err)
+	}
+	return rule, nil
+}
+
+func (e *rewardEngine) DeleteRule(id uint) error {
+	if _, err := e.getRule(id); err != nil {
+		return err
+	}
+	if err := e.ruleRepo.DeleteRule(id); err != nil {
+		return fmt.Errorf("failed to delete reward rule: %v", err)
+	}
+	return nil
+}
+
+// Evaluate works out what every matching rule pays for the event without
+// crediting anything.
+func (e *rewardEngine) Evaluate(event RewardEvent) ([]RewardAward, error) {
+	if event.At.IsZero() {
+		event.At = time.Now()
+	}
+	quantity := event.Quantity
+	if quantity <= 0 {
+		return nil, nil
+	}
+
+	rules, err := e.ruleRepo.GetRulesForAction(event.Action, event.CategoryID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch reward rules: %v", err)
+	}
+
+	var awards []RewardAward
+	for i := range rules {
+		rule := &rules[i]
+		if !rule.Applies(event.At) || (rule.FirstReportOnly && !event.FirstReport) {
+			continue
+		}
+		points := rule.Points * quantity
+		if event.Verified && rule.VerifiedMultiplier > 0 {
+			points = int(math.Round(float64(points) * rule.VerifiedMultiplier))
+		}
+		if rule.DailyCap > 0 {
+			dayStart := event.At.UTC().Truncate(24 * time.Hour)
+			earned, err := e.ledgerRepo.SumRuleCredits(event.UserID, rule.ID, dayStart)
+			if err != nil {
+				return nil, fmt.Errorf("failed to check daily cap: %v", err)
+			}
+			if remaining := rule.DailyCap - earned; points > remaining {
+				points = remaining
+			}
+		}
+		if points <= 0 {
+			continue
+		}
+		awards = append(awards, RewardAward{RuleID: rule.ID, Rule: rule.Name, Points: points})
+	}
+	return awards, nil
+}
+
+// Credit posts the awards to the ledger, each keyed by the event and rule,
+// and returns the points credited.
+func (e *rewardEngine) Credit(event RewardEvent, awards []RewardAward) (int, error) {
+	total := 0
+	for _, award := range awards {
+		ruleID := award.RuleID
+		key := fmt.Sprintf("%s:rule:%d", event.Key, ruleID)
+		txn, err := e.ledger.Credit(event.UserID, ledgerTypeForAction(event.Action), award.Points, event.ReportID, key, award.Rule, &ruleID)
+		if err != nil {
+			return total, err
+		}
+		if txn != nil {
+			total += txn.Amount
+		}
+	}
+	return total, nil
+}
+
+// Award evaluates the event and credits what it earns.
+func (e *rewardEngine) Award(event RewardEvent) (int, error) {
+	awards, err := e.Evaluate(event)
+	if err != nil {
+		return 0, err
+	}
+	return e.Credit(event, awards)
+}
+
+func (e *rewardEngine) getRule(id uint) (*models.RewardRule, error) {
+	rule, err := e.ruleRepo.GetRuleByID(id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch reward rule: %v", err)
+	}
+	if rule == nil {
+		return nil, ErrRewardRuleNotFound
+	}
+	return rule, nil
+}
+
+func (e *rewardEngine) applyRuleInput(input RewardRuleInput, rule *models.RewardRule) error {
+	if input.Name != nil {
+		rule.Name = strings.TrimSpace(*input.Name)
+	}
+	if input.Action != nil {
+		rule.Action = *input.Action
+	}
+	if input.Category != nil {
+		rule.CategoryID = nil
+		if slug := models.Slugify(*input.Category); slug != "" {
+			category, err := e.categoryRepo.GetCategoryBySlug(slug)
+			if err != nil {
+				return fmt.Errorf("failed to fetch category: %v", err)
+			}
+			if category == nil {
+				return ErrCategoryNotFound
+			}
+			rule.CategoryID = &category.ID
+		}
+	}
+	if input.Points != nil {
+		rule.Points = *input.Points
+	}
+	if input.VerifiedMultiplier != nil {
+		rule.VerifiedMultiplier = *input.VerifiedMultiplier
+	}
+	if input.DailyCap != nil {
+		rule.DailyCap = *input.DailyCap
+	}
+	if input.FirstReportOnly != nil {
+		rule.FirstReportOnly = *input.FirstReportOnly
+	}
+	if input.StartsAt != nil {
+		rule.StartsAt = input.StartsAt
+	}
+	if input.EndsAt != nil {
+		rule.EndsAt = input.EndsAt
+	}
+	if input.Active != nil {
+		rule.Active = *input.Active
+	}
+
+	switch {
+	case rule.Name == "":
+		return fmt.Errorf("%w: name is required", ErrInvalidRewardRule)
+	case !containsAction(rule.Action):
+		return fmt.Errorf("%w: action must be one of %s", ErrInvalidRewardRule, strings.Join(models.RewardActions, ", "))
+	case rule.Points <= 0:
+		return fmt.Errorf("%w: points must be positive", ErrInvalidRewardRule)
+	case rule.VerifiedMultiplier < 0 || rule.DailyCap < 0:
+		return fmt.Errorf("%w: multiplier and daily cap cannot be negative", ErrInvalidRewardRule)
+	case rule.StartsAt != nil && rule.EndsAt != nil && !rule.EndsAt.After(*rule.StartsAt):
+		return fmt.Errorf("%w: ends_at must be after starts_at", ErrInvalidRewardRule)
+	}
+	return nil
+}
+
+func containsAction(action string) bool {
+	for _, a := range models.RewardActions {
+		if a == action {
+			return true
+		}
+	}
+	return false
+}
+
+// ledgerTypeForAction files a rule credit under the matching ledger type.
+func ledgerTypeForAction(action string) string {
+	switch action {
+	case models.RewardActionMediaItem:
+		return models.LedgerMediaBonus
+	case models.RewardActionReportApproved:
+		return models.LedgerApproval
+	}
+	return models.LedgerReportSubmitted
+}
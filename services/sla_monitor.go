@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/techagentng/citizenx/config"
+	"github.com/techagentng/citizenx/db"
+	"github.com/techagentng/citizenx/mailingservices"
+	"github.com/techagentng/citizenx/models"
+)
+
+// defaultSLACheckInterval is how often assignments are checked for missed SLA
+// targets when the interval is not configured.
+const defaultSLACheckInterval = 5 * time.Minute
+
+// SLAMonitor periodically finds assignments that missed the acknowledge or
+// resolve target of their category and escalates them to the agency's
+// escalation contacts.
+type SLAMonitor interface {
+	Start(ctx context.Context)
+	CheckBreaches(now time.Time) (int, error)
+}
+
+type slaMonitor struct {
+	Config     *config.Config
+	agencyRepo db.AgencyRepository
+	mailer     mailingservices.Mailer
+}
+
+func NewSLAMonitor(agencyRepo db.AgencyRepository, mailer mailingservices.Mailer, conf *config.Config) SLAMonitor {
+	return &slaMonitor{
+		Config:     conf,
+		agencyRepo: agencyRepo,
+		mailer:     mailer,
+	}
+}
+
+func (m *slaMonitor) interval() time.Duration {
+	if m.Config != nil && m.Config.SLACheckInterval > 0 {
+		return m.Config.SLACheckInterval
+	}
+	return defaultSLACheckInterval
+}
+
+// Start checks for breaches on every tick until ctx is cancelled.
+func (m *slaMonitor) Start(ctx context.Context) {
+	ticker := time.NewTicker(m.interval())
+	defer ticker.Stop()
+	log.Printf("Started SLA monitor, checking every %s", m.interval())
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if n, err := m.CheckBreaches(now); err != nil {
+				log.Printf("Error checking SLA breaches: %v", err)
+			} else if n > 0 {
+				log.Printf("Escalated %d SLA breaches", n)
+			}
+		}
+	}
+}
+
+// CheckBreaches escalates every overdue stage of every assignment once and
+// returns how many it escalated.
+func (m *slaMonitor) CheckBreaches(now time.Time) (int, error) {
+	assignments, err := m.agencyRepo.GetOverdueAssignments(now)
+	if err != nil {
+		return 0, err
+	}
+
+	escalated := 0
+	for i := range assignments {
+		assignment := &assignments[i]
+		if assignment.AcknowledgedAt == nil && assignment.AcknowledgeEscalatedAt == nil &&
+			assignment.AcknowledgeDueAt != nil && assignment.AcknowledgeDueAt.Before(now) {
+			if m.escalate(assignment, models.SLAStageAcknowledge, now) {
+				escalated++
+			}
+		}
+		if assignment.ResolvedAt == nil && assignment.ResolveEscalatedAt == nil &&
+			assignment.ResolveDueAt != nil && assignment.ResolveDueAt.Before(now) {
+			if m.escalate(assignment, models.SLAStageResolve, now) {
+				escalated++
+			}
+		}
+	}
+	return escalated, nil
+}
+
+// escalate claims the breach so it is only escalated once, even with several
+// instances running, and emails the agency's escalation contacts.
+func (m *slaMonitor) escalate(assignment *models.ReportAssignment, stage string, now time.Time) bool {
+	claimed, err := m.agencyRepo.MarkAssignmentEscalated(assignment.ID, stage, now)
+	if err != nil {
+		log.Printf("Unable to mark assignment %d escalated: %v", assignment.ID, err)
+		return false
+	}
+	if !claimed {
+		return false
+	}
+
+	agency := assignment.Agency
+	if agency == nil || len(agency.EscalationContacts) == 0 {
+		log.Printf("Assignment %d missed its %s target but its agency has no escalation contacts", assignment.ID, stage)
+		return true
+	}
+	link := reportPortalLink(m.Config, assignment.IncidentReportID)
+	for _, email := range agency.EscalationContacts {
+		if m.mailer == nil {
+			break
+		}
+		if _, err := m.mailer.SendSLAEscalation(email, agency.Name, stage, link); err != nil {
+			log.Printf("Unable to send SLA escalation for assignment %d to %s: %v", assignment.ID, email, err)
+		}
+	}
+	return true
+}
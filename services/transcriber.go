@@ -0,0 +1,178 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/techagentng/citizenx/config"
+	"github.com/techagentng/citizenx/models"
+)
+
+// transcribeTimeout bounds a single transcription run.
+const transcribeTimeout = 10 * time.Minute
+
+// Transcript is the text recognised in an audio file.
+type Transcript struct {
+	Text string
+	// Language is the detected ISO 639-1 code, e.g. "en" or "yo"
+	Language string
+}
+
+// Transcriber turns recorded speech into text. It is called from the media
+// pipeline for every audio upload; implementations return a nil transcript
+// when they have nothing to offer.
+type Transcriber interface {
+	Transcribe(ctx context.Context, audioPath string) (*Transcript, error)
+}
+
+// NewTranscriber returns a whisper.cpp transcriber when a model is configured
+// and the binary can be found, and a no-op transcriber otherwise.
+func NewTranscriber(conf *config.Config) Transcriber {
+	if conf == nil || conf.WhisperModel == "" {
+		return noopTranscriber{}
+	}
+	binary := conf.WhisperBinary
+	if binary == "" {
+		binary = "whisper-cli"
+	}
+	path, err := exec.LookPath(binary)
+	if err != nil {
+		log.Printf("Transcription disabled: %s not found", binary)
+		return noopTranscriber{}
+	}
+	return &whisperTranscriber{binary: path, model: conf.WhisperModel}
+}
+
+type noopTranscriber struct{}
+
+func (noopTranscriber) Transcribe(ctx context.Context, audioPath string) (*Transcript, error) {
+	return nil, nil
+}
+
+// whisperTranscriber runs the whisper.cpp command line tool locally.
+type whisperTranscriber struct {
+	binary string
+	model  string
+}
+
+func (w *whisperTranscriber) Transcribe(ctx context.Context, audioPath string) (*Transcript, error) {
+	workDir, err := os.MkdirTemp("", "transcribe_*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(workDir)
+
+	// whisper.cpp only reads 16 kHz mono WAV
+	wavPath := filepath.Join(workDir, "audio.wav")
+	if err := runCommand(ctx, "ffmpeg", "-y", "-i", audioPath, "-ar", "16000", "-ac", "1", "-c:a", "pcm_s16le", wavPath); err != nil {
+		return nil, err
+	}
+
+	outPrefix := filepath.Join(workDir, "transcript")
+	if err := runCommand(ctx, w.binary, "-m", w.model, "-f", wavPath, "-l", "auto", "-np", "-oj", "-of", outPrefix); err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(outPrefix + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transcript: %v", err)
+	}
+	var out struct {
+		Result struct {
+			Language string `json:"language"`
+		} `json:"result"`
+		Transcription []struct {
+			Text string `json:"text"`
+		} `json:"transcription"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("failed to parse transcript: %v", err)
+	}
+
+	segments := make([]string, 0, len(out.Transcription))
+	for _, segment := range out.Transcription {
+		if text := strings.TrimSpace(segment.Text); text != "" {
+			segments = append(segments, text)
+		}
+	}
+	return &Transcript{Text: strings.Join(segments, " "), Language: out.Result.Language}, nil
+}
+
+func runCommand(ctx context.Context, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s error: %v, details: %s", filepath.Base(name), err, stderr.String())
+	}
+	return nil
+}
+
+// ModerationFlags returns the configured moderation terms that occur in text
+// as whole words or phrases, ignoring case and punctuation.
+func ModerationFlags(text string, terms []string) []string {
+	normalize := func(s string) string {
+		fields := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+		})
+		return " " + strings.Join(fields, " ") + " "
+	}
+
+	normalized := normalize(text)
+	var flags []string
+	for _, term := range terms {
+		if phrase := normalize(term); phrase != "  " && strings.Contains(normalized, phrase) {
+			flags = append(flags, strings.TrimSpace(phrase))
+		}
+	}
+	return flags
+}
+
+// transcribeAudio runs the transcriber over an audio upload and records the
+// result on the media record. Failures are logged and leave the media without
+// a transcript rather than failing the upload.
+func (m *mediaService) transcribeAudio(fileBytes []byte, media *models.Media) {
+	if m.transcriber == nil {
+		return
+	}
+	tmp, err := os.CreateTemp("", "audio_*")
+	if err != nil {
+		log.Printf("Unable to transcribe audio: %v", err)
+		return
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(fileBytes)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		log.Printf("Unable to transcribe audio: %v", err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), transcribeTimeout)
+	defer cancel()
+	transcript, err := m.transcriber.Transcribe(ctx, tmp.Name())
+	if err != nil {
+		log.Printf("Transcription failed: %v", err)
+		return
+	}
+	if transcript == nil {
+		return
+	}
+
+	media.Transcript = transcript.Text
+	media.TranscriptLanguage = transcript.Language
+	if m.Config != nil {
+		media.ModerationFlags = strings.Join(ModerationFlags(transcript.Text, m.Config.ModerationKeywords), ",")
+	}
+}
@@ -0,0 +1,400 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/techagentng/citizenx/config"
+	"github.com/techagentng/citizenx/db"
+	"github.com/techagentng/citizenx/models"
+	"github.com/techagentng/citizenx/storage"
+)
+
+const (
+	// UploadChunkSize is the size of every chunk except the last. It matches the
+	// S3 minimum part size so chunks map cleanly onto multipart parts.
+	UploadChunkSize = 5 << 20
+	// MaxResumableUploadSize caps a single resumable upload.
+	MaxResumableUploadSize = 500 << 20
+	// uploadSessionTTL is how long a client has to finish an upload.
+	uploadSessionTTL = 24 * time.Hour
+	// maxOpenUploadSessions caps the unfinished uploads a user can hold.
+	maxOpenUploadSessions = 10
+	// uploadSweepInterval is how often expired sessions and their temp
+	// files are removed, uploadSweepBatch how many sessions a sweep takes
+	// at a time.
+	uploadSweepInterval = time.Hour
+	uploadSweepBatch    = 500
+	uploadChunksDirName = "uploads"
+)
+
+var (
+	ErrUploadNotFound    = errors.New("upload session not found")
+	ErrUploadExpired     = errors.New("upload session has expired")
+	ErrUploadCompleted   = errors.New("upload session is already completed")
+	ErrUploadTooLarge    = fmt.Errorf("upload exceeds the %d MB limit", MaxResumableUploadSize>>20)
+	ErrChunkOutOfOrder   = errors.New("chunk out of order")
+	ErrChunkSizeMismatch = errors.New("chunk has the wrong size")
+	ErrUploadIncomplete  = errors.New("upload is not complete")
+	ErrUnsupportedUpload = errors.New("unsupported media type")
+	ErrTooManyUploads    = fmt.Errorf("at most %d uploads can be in progress at once", maxOpenUploadSessions)
+)
+
+// UploadService implements resumable uploads in the spirit of tus: the client
+// creates a session, PUTs numbered chunks (asking for the current offset after
+// a dropped connection) and then completes it, at which point the assembled
+// file goes through ProcessMedia like any other upload.
+type UploadService interface {
+	CreateSession(userID uint, reportID, filename, contentType string, totalSize int64) (*models.UploadSession, error)
+	GetSession(userID uint, sessionID string) (*models.UploadSession, error)
+	WriteChunk(userID uint, sessionID string, chunk int, body io.Reader) (*models.UploadSession, error)
+	Complete(userID uint, sessionID string) (*models.Media, error)
+	// Start removes expired sessions and their temp files until ctx is
+	// cancelled.
+	Start(ctx context.Context)
+}
+
+type uploadService struct {
+	Config     *config.Config
+	uploadRepo db.UploadRepository
+	reportRepo db.IncidentReportRepository
+	jobs       MediaJobQueue
+	store      storage.BlobStore
+}
+
+func NewUploadService(uploadRepo db.UploadRepository, reportRepo db.IncidentReportRepository, jobs MediaJobQueue, store storage.BlobStore, conf *config.Config) UploadService {
+	return &uploadService{
+		Config:     conf,
+		uploadRepo: uploadRepo,
+		reportRepo: reportRepo,
+		jobs:       jobs,
+		store:      store,
+	}
+}
+
+// chunkKey names the blob holding chunk n of a session. Keys sort in chunk
+// order.
+func chunkKey(sessionID string, n int) string {
+	return storage.Key(uploadChunksDirName, sessionID, fmt.Sprintf("%05d", n))
+}
+
+func (u *uploadService) tempDir() string {
+	if u.Config != nil && u.Config.UploadTempDir != "" {
+		return u.Config.UploadTempDir
+	}
+	return filepath.Join(os.TempDir(), "citizenx-uploads")
+}
+
+func (u *uploadService) CreateSession(userID uint, reportID, filename, contentType string, totalSize int64) (*models.UploadSession, error) {
+	if totalSize <= 0 {
+		return nil, errors.New("total size must be greater than zero")
+	}
+	if totalSize > MaxResumableUploadSize {
+		return nil, ErrUploadTooLarge
+	}
+	if !strings.HasPrefix(contentType, "image/") && !strings.HasPrefix(contentType, "video/") && !strings.HasPrefix(contentType, "audio/") {
+		return nil, ErrUnsupportedUpload
+	}
+
+	report, err := u.reportRepo.GetIncidentReportByID(reportID)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving report: %v", err)
+	}
+	if report.UserID != userID {
+		return nil, errors.New("media can only be added to your own reports")
+	}
+
+	pending, err := u.uploadRepo.CountOpenUploadSessions(userID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to count upload sessions: %v", err)
+	}
+	if pending >= maxOpenUploadSessions {
+		return nil, ErrTooManyUploads
+	}
+
+	session := &models.UploadSession{
+		ID:               uuid.New().String(),
+		UserID:           userID,
+		IncidentReportID: reportID,
+		Filename:         filepath.Base(filename),
+		ContentType:      contentType,
+		TotalSize:        totalSize,
+		ChunkSize:        UploadChunkSize,
+		Status:           models.UploadStatusPending,
+		ExpiresAt:        time.Now().Add(uploadSessionTTL),
+	}
+	if err := u.uploadRepo.CreateUploadSession(session); err != nil {
+		return nil, fmt.Errorf("failed to create upload session: %v", err)
+	}
+	return session, nil
+}
+
+func (u *uploadService) GetSession(userID uint, sessionID string) (*models.UploadSession, error) {
+	session, err := u.uploadRepo.GetUploadSession(sessionID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load upload session: %v", err)
+	}
+	if session == nil {
+		return nil, ErrUploadNotFound
+	}
+	// Sessions from before chunks moved to the blob store have to start over
+	if session.Status == models.UploadStatusPending && (time.Now().After(session.ExpiresAt) || session.TempPath != "") {
+		return nil, ErrUploadExpired
+	}
+	return session, nil
+}
+
+// WriteChunk stores chunk n in the blob store. Re-sending a chunk that was
+// already stored is acknowledged without rewriting it, so clients can safely
+// retry after losing the response.
+func (u *uploadService) WriteChunk(userID uint, sessionID string, chunk int, body io.Reader) (*models.UploadSession, error) {
+	session, err := u.GetSession(userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != models.UploadStatusPending {
+		return nil, ErrUploadCompleted
+	}
+	if chunk < session.NextChunk {
+		return session, nil
+	}
+	if chunk > session.NextChunk {
+		return nil, ErrChunkOutOfOrder
+	}
+
+	start := int64(chunk) * session.ChunkSize
+	expected := session.ChunkSize
+	if remaining := session.TotalSize - start; remaining < expected {
+		expected = remaining
+	}
+
+	data, err := io.ReadAll(io.LimitReader(body, expected+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chunk: %v", err)
+	}
+	if int64(len(data)) != expected {
+		return nil, ErrChunkSizeMismatch
+	}
+
+	_, err = u.store.Put(context.Background(), chunkKey(session.ID, chunk), bytes.NewReader(data), int64(len(data)), storage.PutOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to write chunk: %v", err)
+	}
+
+	advanced, err := u.uploadRepo.AdvanceUploadSession(session.ID, chunk, start+expected)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update upload session: %v", err)
+	}
+	if !advanced {
+		// A concurrent retry of the same chunk won; its bytes are identical
+		log.Printf("Chunk %d of upload %s was already recorded", chunk, session.ID)
+	}
+	return u.GetSession(userID, sessionID)
+}
+
+// Complete queues the assembled file for background processing. The returned
+// media record is pending; the reporter gets a push notification when it is ready.
+// The session is completed before the file is queued so concurrent calls
+// cannot queue it twice, and reopened if queueing fails so the client can retry.
+func (u *uploadService) Complete(userID uint, sessionID string) (*models.Media, error) {
+	session, err := u.GetSession(userID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != models.UploadStatusPending {
+		return nil, ErrUploadCompleted
+	}
+	if session.Offset != session.TotalSize {
+		return nil, ErrUploadIncomplete
+	}
+
+	completed, err := u.uploadRepo.MarkUploadSessionCompleted(session.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to complete upload session: %v", err)
+	}
+	if !completed {
+		return nil, ErrUploadCompleted
+	}
+
+	media, err := u.enqueue(session)
+	if err != nil {
+		if reopenErr := u.uploadRepo.ReopenUploadSession(session.ID); reopenErr != nil {
+			log.Printf("Error reopening upload session %s: %v", session.ID, reopenErr)
+		}
+		return nil, err
+	}
+	u.deleteChunks(session.ID)
+	return media, nil
+}
+
+// enqueue assembles the session's chunks into a temp file and hands it to the
+// media job queue.
+func (u *uploadService) enqueue(session *models.UploadSession) (*models.Media, error) {
+	if err := os.MkdirAll(u.tempDir(), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create upload directory: %v", err)
+	}
+	file, err := os.CreateTemp(u.tempDir(), session.ID+"-*.part")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create upload file: %v", err)
+	}
+	path := file.Name()
+
+	ctx := context.Background()
+	var written int64
+	for n := 0; n < session.NextChunk && err == nil; n++ {
+		var chunk io.ReadCloser
+		chunk, err = u.store.Get(ctx, chunkKey(session.ID, n))
+		if err != nil {
+			err = fmt.Errorf("failed to read chunk %d: %v", n, err)
+			break
+		}
+		var copied int64
+		copied, err = io.Copy(file, chunk)
+		chunk.Close()
+		written += copied
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil && written != session.TotalSize {
+		err = ErrUploadIncomplete
+	}
+	if err != nil {
+		os.Remove(path)
+		return nil, fmt.Errorf("failed to assemble upload: %v", err)
+	}
+
+	media, err := u.jobs.EnqueueFile(path, session.Filename, session.ContentType, session.UserID, session.IncidentReportID)
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	return media, nil
+}
+
+// deleteChunks removes the stored chunks of a session.
+func (u *uploadService) deleteChunks(sessionID string) {
+	ctx := context.Background()
+	keys, err := u.store.List(ctx, storage.Key(uploadChunksDirName, sessionID)+"/")
+	if err != nil {
+		log.Printf("Error listing chunks of upload %s: %v", sessionID, err)
+		return
+	}
+	for _, key := range keys {
+		if err := u.store.Delete(ctx, key); err != nil {
+			log.Printf("Error removing upload chunk %s: %v", key, err)
+		}
+	}
+}
+
+func (u *uploadService) Start(ctx context.Context) {
+	ticker := time.NewTicker(uploadSweepInterval)
+	defer ticker.Stop()
+	for {
+		u.sweep()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sweep deletes expired sessions and their chunks. Sessions from before
+// chunks moved to the blob store wrote them to the instance that received
+// them, so temp files older than the session TTL are also removed from this
+// instance's directory whether or not their session is still on record.
+func (u *uploadService) sweep() {
+	now := time.Now()
+	for {
+		sessions, err := u.uploadRepo.GetExpiredUploadSessions(now, uploadSweepBatch)
+		if err != nil {
+			log.Printf("Error fetching expired upload sessions: %v", err)
+			break
+		}
+		for _, session := range sessions {
+			if session.TempPath == "" {
+				u.deleteChunks(session.ID)
+			} else if err := os.Remove(session.TempPath); err != nil && !os.IsNotExist(err) {
+				log.Printf("Error removing upload file %s: %v", session.TempPath, err)
+			}
+			if err := u.uploadRepo.DeleteUploadSession(session.ID); err != nil {
+				log.Printf("Error deleting upload session %s: %v", session.ID, err)
+				return
+			}
+		}
+		if len(sessions) < uploadSweepBatch {
+			break
+		}
+	}
+
+	paths, err := filepath.Glob(filepath.Join(u.tempDir(), "*.part"))
+	if err != nil {
+		log.Printf("Error listing upload files: %v", err)
+		return
+	}
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil || now.Sub(info.ModTime()) < uploadSessionTTL {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			log.Printf("Error removing upload file %s: %v", path, err)
+		}
+	}
+}
+
+// fileHeaderFromDisk wraps a file on disk in a multipart.FileHeader so it can be
+// passed to the same pipeline as form uploads. The file is streamed through a
+// multipart reader, which spools anything over 1 MB to its own temp file; the
+// caller must call RemoveAll on the returned form.
+func fileHeaderFromDisk(path, filename, contentType string) (*multipart.FileHeader, *multipart.Form, error) {
+	pr, pw := io.Pipe()
+	writer := multipart.NewWriter(pw)
+
+	go func() {
+		file, err := os.Open(path)
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		defer file.Close()
+
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="mediaFiles"; filename=%q`, filename))
+		header.Set("Content-Type", contentType)
+		part, err := writer.CreatePart(header)
+		if err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		if _, err := io.Copy(part, file); err != nil {
+			pw.CloseWithError(err)
+			return
+		}
+		pw.CloseWithError(writer.Close())
+	}()
+
+	form, err := multipart.NewReader(pr, writer.Boundary()).ReadForm(1 << 20)
+	pr.Close()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read assembled upload: %v", err)
+	}
+	files := form.File["mediaFiles"]
+	if len(files) == 0 {
+		form.RemoveAll()
+		return nil, nil, errors.New("assembled upload is empty")
+	}
+	return files[0], form, nil
+}
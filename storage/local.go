@@ -0,0 +1,278 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultLocalDir = "./media"
+	defaultLocalURL = "/media"
+
+	publicDir  = "public"
+	privateDir = "private"
+)
+
+// LocalStore keeps objects on the local disk. It is meant for development and
+// tests; the server mounts Handler at the URL prefix so stored files can be
+// fetched the same way S3 URLs are. Public and private objects live in
+// separate directories and private ones are only served with a valid signature.
+type LocalStore struct {
+	root    string
+	baseURL string
+	secret  []byte
+}
+
+func NewLocalStore(root, baseURL, secret string) (*LocalStore, error) {
+	if root == "" {
+		root = defaultLocalDir
+	}
+	if baseURL == "" {
+		baseURL = defaultLocalURL
+	}
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %v", err)
+	}
+	return &LocalStore{root: root, baseURL: strings.TrimRight(baseURL, "/"), secret: []byte(secret)}, nil
+}
+
+// BaseURL is the URL prefix objects are served under.
+func (l *LocalStore) BaseURL() string {
+	return l.baseURL
+}
+
+// Root is the directory objects are stored in.
+func (l *LocalStore) Root() string {
+	return l.root
+}
+
+// path maps a key to a file under the public or private directory, rejecting
+// keys that would escape it.
+func (l *LocalStore) path(key string, public bool) (string, error) {
+	cleaned := filepath.Clean("/" + key)
+	if cleaned == "/" {
+		return "", errors.New("empty object key")
+	}
+	dir := privateDir
+	if public {
+		dir = publicDir
+	}
+	return filepath.Join(l.root, dir, filepath.FromSlash(cleaned)), nil
+}
+
+// find returns the file holding key and whether it is public.
+func (l *LocalStore) find(key string) (string, bool, error) {
+	for _, public := range []bool{true, false} {
+		path, err := l.path(key, public)
+		if err != nil {
+			return "", false, err
+		}
+		if _, err := os.Stat(path); err == nil {
+			return path, public, nil
+		}
+	}
+	return "", false, ErrNotFound
+}
+
+func (l *LocalStore) url(key string) string {
+	return l.baseURL + "/" + strings.TrimLeft(key, "/")
+}
+
+func (l *LocalStore) KeyForURL(rawURL string) (string, bool) {
+	prefix := l.baseURL + "/"
+	if !strings.HasPrefix(rawURL, prefix) {
+		return "", false
+	}
+	key := strings.TrimPrefix(rawURL, prefix)
+	if i := strings.IndexByte(key, '?'); i >= 0 {
+		key = key[:i]
+	}
+	return key, key != ""
+}
+
+func (l *LocalStore) Put(ctx context.Context, key string, body io.Reader, size int64, opts PutOptions) (string, error) {
+	path, err := l.path(key, opts.Public)
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %v", err)
+	}
+
+	// Write to a temp file first so readers never see a partial object
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create file: %v", err)
+	}
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to write file: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to write file: %v", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return "", fmt.Errorf("failed to store file: %v", err)
+	}
+	// Drop any copy stored with the other visibility
+	if other, err := l.path(key, !opts.Public); err == nil {
+		os.Remove(other)
+	}
+	return l.url(key), nil
+}
+
+func (l *LocalStore) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, _, err := l.find(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return file, nil
+}
+
+func (l *LocalStore) Delete(ctx context.Context, key string) error {
+	for _, public := range []bool{true, false} {
+		path, err := l.path(key, public)
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// MakePrivate moves a public object into the private directory.
+func (l *LocalStore) MakePrivate(ctx context.Context, key string) error {
+	path, public, err := l.find(key)
+	if err != nil {
+		return err
+	}
+	if !public {
+		return nil
+	}
+	private, err := l.path(key, false)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(private), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory: %v", err)
+	}
+	return os.Rename(path, private)
+}
+
+// Handler serves stored objects under the path of the base URL. Public objects
+// are served to anyone; private ones need the query parameters added by
+// SignedURL.
+func (l *LocalStore) Handler() http.Handler {
+	prefix := l.baseURL
+	if parsed, err := url.Parse(l.baseURL); err == nil {
+		prefix = parsed.Path
+	}
+	return http.StripPrefix(prefix, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimLeft(r.URL.Path, "/")
+		path, public, err := l.find(key)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		if !public {
+			query := r.URL.Query()
+			if !l.VerifySignature(key, query.Get("expires"), query.Get("signature")) {
+				http.Error(w, "invalid or expired signature", http.StatusForbidden)
+				return
+			}
+			w.Header().Set("Cache-Control", "private, max-age=300")
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		defer file.Close()
+		info, err := file.Stat()
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+	}))
+}
+
+// SignedURL appends an expiry and an HMAC of the key to the object URL.
+func (l *LocalStore) SignedURL(ctx context.Context, key string, expires time.Duration) (string, error) {
+	expiry := strconv.FormatInt(time.Now().Add(expires).Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", expiry)
+	query.Set("signature", l.sign(key, expiry))
+	return l.url(key) + "?" + query.Encode(), nil
+}
+
+// VerifySignature checks a signature produced by SignedURL.
+func (l *LocalStore) VerifySignature(key, expires, signature string) bool {
+	expiry, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiry {
+		return false
+	}
+	return hmac.Equal([]byte(signature), []byte(l.sign(key, expires)))
+}
+
+func (l *LocalStore) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, l.secret)
+	mac.Write([]byte(strings.TrimLeft(key, "/") + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (l *LocalStore) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	for _, dir := range []string{publicDir, privateDir} {
+		base := filepath.Join(l.root, dir)
+		err := filepath.WalkDir(base, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if d.IsDir() || strings.HasPrefix(d.Name(), ".upload-") {
+				return nil
+			}
+			rel, err := filepath.Rel(base, path)
+			if err != nil {
+				return err
+			}
+			key := filepath.ToSlash(rel)
+			if strings.HasPrefix(key, prefix) {
+				keys = append(keys, key)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects: %v", err)
+		}
+	}
+	return keys, nil
+}
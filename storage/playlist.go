@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	playlistExt = ".m3u8"
+	// maxPlaylistSize bounds how much of a stored playlist is read.
+	maxPlaylistSize = 1 << 20
+)
+
+// ErrInvalidSignature is returned by ServePlaylist for a missing, wrong or
+// expired signature.
+var ErrInvalidSignature = errors.New("invalid or expired signature")
+
+// WithPlaylistEndpoint makes Sign route HLS playlists through the endpoint at
+// baseURL instead of signing them for direct download. A playlist fetched
+// straight from storage would reference segments by relative URLs, which are
+// private too and would not carry a signature; the endpoint rewrites those
+// references with ServePlaylist.
+func (s *URLSigner) WithPlaylistEndpoint(baseURL, secret string) *URLSigner {
+	s.playlistBase = strings.TrimRight(baseURL, "/")
+	s.playlistSecret = []byte(secret)
+	return s
+}
+
+// playlistURL signs the folder holding the playlist, so the variant playlists
+// next to it can be requested with the same query string.
+func (s *URLSigner) playlistURL(key string, expiresAt time.Time) string {
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", s.playlistSignature(path.Dir(key), expires))
+	return s.playlistBase + "/" + key + "?" + query.Encode()
+}
+
+func (s *URLSigner) playlistSignature(folder, expires string) string {
+	mac := hmac.New(sha256.New, s.playlistSecret)
+	mac.Write([]byte("playlist\n" + folder + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ServePlaylist checks the signature of a playlist URL produced by Sign and
+// returns the stored playlist with every segment replaced by a signed URL and
+// every nested playlist carrying the same signature.
+func (s *URLSigner) ServePlaylist(ctx context.Context, key, expires, signature string) ([]byte, error) {
+	if len(s.playlistSecret) == 0 || !strings.HasSuffix(key, playlistExt) {
+		return nil, ErrInvalidSignature
+	}
+	expiry, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiry {
+		return nil, ErrInvalidSignature
+	}
+	folder := path.Dir(key)
+	if !hmac.Equal([]byte(signature), []byte(s.playlistSignature(folder, expires))) {
+		return nil, ErrInvalidSignature
+	}
+
+	body, err := s.store.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	query := url.Values{}
+	query.Set("expires", expires)
+	query.Set("signature", signature)
+
+	var out bytes.Buffer
+	scanner := bufio.NewScanner(io.LimitReader(body, maxPlaylistSize))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.Contains(line, "://"):
+		case strings.HasSuffix(line, playlistExt):
+			line = line + "?" + query.Encode()
+		default:
+			signed, err := s.signKey(ctx, path.Join(folder, line))
+			if err != nil {
+				return nil, fmt.Errorf("failed to sign segment %s: %v", line, err)
+			}
+			line = signed
+		}
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read playlist: %v", err)
+	}
+	return out.Bytes(), nil
+}
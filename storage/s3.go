@@ -0,0 +1,244 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+const (
+	// multipartUploadThreshold is the size above which objects are sent with
+	// S3 multipart upload instead of a single PutObject held in memory.
+	multipartUploadThreshold = 16 << 20
+	// multipartPartSize is the size of each uploaded part (S3 minimum is 5 MB).
+	multipartPartSize = 8 << 20
+)
+
+type s3Store struct {
+	client  *s3.Client
+	presign *s3.PresignClient
+	bucket  string
+	region  string
+}
+
+func NewS3Store(bucket, region, accessKeyID, secretAccessKey string) (BlobStore, error) {
+	if bucket == "" {
+		return nil, errors.New("s3 storage requires a bucket")
+	}
+	cfg, err := awsconfig.LoadDefaultConfig(context.TODO(),
+		awsconfig.WithRegion(region),
+		awsconfig.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(accessKeyID, secretAccessKey, "")),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load SDK config, %v", err)
+	}
+	client := s3.NewFromConfig(cfg)
+	return &s3Store{
+		client:  client,
+		presign: s3.NewPresignClient(client),
+		bucket:  bucket,
+		region:  region,
+	}, nil
+}
+
+func (s *s3Store) url(key string) string {
+	return s.urlPrefix() + key
+}
+
+func (s *s3Store) urlPrefix() string {
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/", s.bucket, s.region)
+}
+
+func (s *s3Store) KeyForURL(rawURL string) (string, bool) {
+	if !strings.HasPrefix(rawURL, s.urlPrefix()) {
+		return "", false
+	}
+	key := strings.TrimPrefix(rawURL, s.urlPrefix())
+	if i := strings.IndexByte(key, '?'); i >= 0 {
+		key = key[:i]
+	}
+	return key, key != ""
+}
+
+func (s *s3Store) acl(opts PutOptions) types.ObjectCannedACL {
+	if opts.Public {
+		return types.ObjectCannedACLPublicRead
+	}
+	return types.ObjectCannedACLPrivate
+}
+
+func (s *s3Store) Put(ctx context.Context, key string, body io.Reader, size int64, opts PutOptions) (string, error) {
+	if size < 0 || size > multipartUploadThreshold {
+		if err := s.putMultipart(ctx, key, body, opts); err != nil {
+			return "", err
+		}
+		return s.url(key), nil
+	}
+
+	content, err := io.ReadAll(body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file content: %v", err)
+	}
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(content),
+		ACL:    s.acl(opts),
+	}
+	if opts.ContentType != "" {
+		input.ContentType = aws.String(opts.ContentType)
+	}
+	if _, err := s.client.PutObject(ctx, input); err != nil {
+		return "", fmt.Errorf("failed to upload file to S3: %v", err)
+	}
+	return s.url(key), nil
+}
+
+// putMultipart streams the body to S3 in parts so only one part is held in
+// memory at a time. The multipart upload is aborted if any part fails.
+func (s *s3Store) putMultipart(ctx context.Context, key string, body io.Reader, opts PutOptions) error {
+	input := &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		ACL:    s.acl(opts),
+	}
+	if opts.ContentType != "" {
+		input.ContentType = aws.String(opts.ContentType)
+	}
+	created, err := s.client.CreateMultipartUpload(ctx, input)
+	if err != nil {
+		return fmt.Errorf("failed to start multipart upload: %v", err)
+	}
+
+	abort := func(cause error) error {
+		_, abortErr := s.client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+			Bucket:   aws.String(s.bucket),
+			Key:      aws.String(key),
+			UploadId: created.UploadId,
+		})
+		if abortErr != nil {
+			return fmt.Errorf("%v (abort also failed: %v)", cause, abortErr)
+		}
+		return cause
+	}
+
+	var parts []types.CompletedPart
+	buf := make([]byte, multipartPartSize)
+	for partNumber := int32(1); ; partNumber++ {
+		n, readErr := io.ReadFull(body, buf)
+		if n > 0 {
+			uploaded, err := s.client.UploadPart(ctx, &s3.UploadPartInput{
+				Bucket:     aws.String(s.bucket),
+				Key:        aws.String(key),
+				UploadId:   created.UploadId,
+				PartNumber: aws.Int32(partNumber),
+				Body:       bytes.NewReader(buf[:n]),
+			})
+			if err != nil {
+				return abort(fmt.Errorf("failed to upload part %d: %v", partNumber, err))
+			}
+			parts = append(parts, types.CompletedPart{
+				ETag:       uploaded.ETag,
+				PartNumber: aws.Int32(partNumber),
+			})
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return abort(fmt.Errorf("failed to read file content: %v", readErr))
+		}
+	}
+
+	_, err = s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s.bucket),
+		Key:             aws.String(key),
+		UploadId:        created.UploadId,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		return abort(fmt.Errorf("failed to complete multipart upload: %v", err))
+	}
+	return nil
+}
+
+func (s *s3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, ErrNotFound
+		}
+		return nil, fmt.Errorf("failed to get object from S3: %v", err)
+	}
+	return out.Body, nil
+}
+
+func (s *s3Store) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete object from S3: %v", err)
+	}
+	return nil
+}
+
+func (s *s3Store) MakePrivate(ctx context.Context, key string) error {
+	_, err := s.client.PutObjectAcl(ctx, &s3.PutObjectAclInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		ACL:    types.ObjectCannedACLPrivate,
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) || strings.Contains(err.Error(), "NoSuchKey") {
+			return ErrNotFound
+		}
+		return fmt.Errorf("failed to update object ACL: %v", err)
+	}
+	return nil
+}
+
+func (s *s3Store) SignedURL(ctx context.Context, key string, expires time.Duration) (string, error) {
+	req, err := s.presign.PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(expires))
+	if err != nil {
+		return "", fmt.Errorf("failed to presign object: %v", err)
+	}
+	return req.URL, nil
+}
+
+func (s *s3Store) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	paginator := s3.NewListObjectsV2Paginator(s.client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list objects: %v", err)
+		}
+		for _, object := range page.Contents {
+			keys = append(keys, aws.ToString(object.Key))
+		}
+	}
+	return keys, nil
+}
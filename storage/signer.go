@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultSignedURLTTL is how long signed media URLs stay valid.
+	DefaultSignedURLTTL = time.Hour
+	// signedURLMinRemaining is the least validity a cached URL must have left to
+	// be handed out again, so clients never receive a URL about to expire.
+	signedURLMinRemaining = 15 * time.Minute
+	signerPurgeInterval   = 5 * time.Minute
+)
+
+type signedURL struct {
+	url       string
+	expiresAt time.Time
+}
+
+// URLSigner turns the canonical URLs stored on records into short-lived signed
+// URLs at read time. Signed URLs are cached per key until they get close to
+// expiring, so list endpoints do not presign every object on every call.
+// URLs that do not belong to the store are returned unchanged.
+type URLSigner struct {
+	store BlobStore
+	ttl   time.Duration
+
+	// playlistBase and playlistSecret are set by WithPlaylistEndpoint
+	playlistBase   string
+	playlistSecret []byte
+
+	mu         sync.Mutex
+	cache      map[string]signedURL
+	lastPurged time.Time
+}
+
+func NewURLSigner(store BlobStore, ttl time.Duration) *URLSigner {
+	if ttl <= signedURLMinRemaining {
+		ttl = DefaultSignedURLTTL
+	}
+	return &URLSigner{
+		store: store,
+		ttl:   ttl,
+		cache: make(map[string]signedURL),
+	}
+}
+
+// MinValidity is the shortest time a URL returned by Sign stays valid.
+func (s *URLSigner) MinValidity() time.Duration {
+	return signedURLMinRemaining
+}
+
+// Sign returns a signed URL for rawURL. On error an empty string is returned
+// so callers never fall back to exposing the unsigned object URL.
+func (s *URLSigner) Sign(ctx context.Context, rawURL string) string {
+	if rawURL == "" {
+		return ""
+	}
+	key, ok := s.store.KeyForURL(rawURL)
+	if !ok {
+		return rawURL
+	}
+
+	signed, err := s.signKey(ctx, key)
+	if err != nil {
+		log.Printf("Error signing %s: %v", key, err)
+		return ""
+	}
+	return signed
+}
+
+// signKey returns a cached signed URL for key, signing it again once the cached
+// one gets close to expiring.
+func (s *URLSigner) signKey(ctx context.Context, key string) (string, error) {
+	now := time.Now()
+	s.mu.Lock()
+	s.purge(now)
+	cached, found := s.cache[key]
+	s.mu.Unlock()
+	if found && cached.expiresAt.Sub(now) > signedURLMinRemaining {
+		return cached.url, nil
+	}
+
+	expiresAt := now.Add(s.ttl)
+	var signed string
+	if s.playlistBase != "" && strings.HasSuffix(key, playlistExt) {
+		signed = s.playlistURL(key, expiresAt)
+	} else {
+		var err error
+		if signed, err = s.store.SignedURL(ctx, key, s.ttl); err != nil {
+			return "", err
+		}
+	}
+	s.mu.Lock()
+	s.cache[key] = signedURL{url: signed, expiresAt: expiresAt}
+	s.mu.Unlock()
+	return signed, nil
+}
+
+// Forget drops the cached signed URLs of the given canonical URLs, so media
+// that was just hidden is not handed out again from the cache.
+func (s *URLSigner) Forget(rawURLs ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, rawURL := range rawURLs {
+		if key, ok := s.store.KeyForURL(rawURL); ok {
+			delete(s.cache, key)
+		}
+	}
+}
+
+// SignList signs every URL in a comma separated list, the format report media
+// URLs are stored in.
+func (s *URLSigner) SignList(ctx context.Context, urls string) string {
+	if urls == "" {
+		return ""
+	}
+	parts := strings.Split(urls, ",")
+	signed := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if url := s.Sign(ctx, strings.TrimSpace(part)); url != "" {
+			signed = append(signed, url)
+		}
+	}
+	return strings.Join(signed, ",")
+}
+
+// purge removes entries that can no longer be handed out. The caller holds mu.
+func (s *URLSigner) purge(now time.Time) {
+	if now.Sub(s.lastPurged) < signerPurgeInterval {
+		return
+	}
+	s.lastPurged = now
+	for key, entry := range s.cache {
+		if entry.expiresAt.Sub(now) <= signedURLMinRemaining {
+			delete(s.cache, key)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Package storage is the single place media and other uploaded files are
+// written to and read from. The backend is chosen by config: S3 in production,
+// a local directory for development and tests.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/techagentng/citizenx/config"
+)
+
+const (
+	BackendS3    = "s3"
+	BackendLocal = "local"
+)
+
+// ErrNotFound is returned by Get when the key does not exist.
+var ErrNotFound = errors.New("object not found")
+
+// PutOptions controls how an object is stored.
+type PutOptions struct {
+	ContentType string
+	// Public objects can be fetched by anyone with the URL. Everything else is
+	// private and only reachable through SignedURL.
+	Public bool
+}
+
+// BlobStore stores objects under slash separated keys such as
+// "media/feed/abc.jpg".
+type BlobStore interface {
+	// Put writes the object and returns its canonical URL. size may be -1 when
+	// unknown.
+	Put(ctx context.Context, key string, body io.Reader, size int64, opts PutOptions) (string, error)
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// SignedURL returns a URL that grants read access to the object until it expires.
+	SignedURL(ctx context.Context, key string, expires time.Duration) (string, error)
+	// KeyForURL maps a canonical URL returned by Put back to its key. It
+	// reports false for URLs that do not belong to this store.
+	KeyForURL(rawURL string) (string, bool)
+	// List returns the keys that start with prefix.
+	List(ctx context.Context, prefix string) ([]string, error)
+	// MakePrivate withdraws public access to an object stored as public.
+	// It returns ErrNotFound when the key does not exist.
+	MakePrivate(ctx context.Context, key string) error
+}
+
+// New returns the backend selected by conf.StorageBackend. When it is unset,
+// S3 is used if a bucket is configured and the local disk otherwise, so a fresh
+// checkout runs without AWS credentials.
+func New(conf *config.Config) (BlobStore, error) {
+	backend := strings.ToLower(conf.StorageBackend)
+	if backend == "" {
+		backend = BackendLocal
+		if conf.AWS_BUCKET != "" {
+			backend = BackendS3
+		}
+	}
+
+	switch backend {
+	case BackendS3:
+		return NewS3Store(conf.AWS_BUCKET, conf.AWS_REGION, conf.AWS_ACCESS_KEY_ID, conf.AWS_SECRET_ACCESS_KEY)
+	case BackendLocal:
+		return NewLocalStore(conf.LocalStorageDir, conf.LocalStorageURL, conf.JWTSecret)
+	}
+	return nil, fmt.Errorf("unknown storage backend %q", conf.StorageBackend)
+}
+
+// Key joins path segments into an object key.
+func Key(parts ...string) string {
+	cleaned := make([]string, 0, len(parts))
+	for _, part := range parts {
+		part = strings.Trim(part, "/")
+		if part != "" {
+			cleaned = append(cleaned, part)
+		}
+	}
+	return strings.Join(cleaned, "/")
+}